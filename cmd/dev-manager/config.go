@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"time"
 
+	"dev-manager/internal/textdiff"
+	"dev-manager/pkg/app"
 	"dev-manager/pkg/config"
-	"dev-manager/pkg/deps"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -20,40 +23,325 @@ var configCmd = &cobra.Command{
 	Long:  `Commands for managing dev-manager configuration.`,
 }
 
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how the effective configuration differs from the file",
+	Long: `Show a unified diff between the configuration file as written and the
+configuration dev-manager actually uses, after schema migrations, ${env:...}
+and ${file:...} references and the defaults block have been applied.
+
+Example:
+  dev-manager config diff
+  dev-manager config diff -U 10`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		context, _ := cmd.Flags().GetInt("context")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		// Read the file before Load, which may rewrite it when migrating
+		raw, err := mgr.Raw()
+		if errors.Is(err, config.ErrConfigNotFound) {
+			fmt.Printf("No configuration file at %s; nothing to compare.\n", mgr.Path())
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		// Both sides go through the same marshalling so only values differ
+		rawYAML, err := yaml.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		effectiveYAML, err := yaml.Marshal(mgr.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		diff := textdiff.Unified(mgr.Path(), "effective", string(rawYAML), string(effectiveYAML), context)
+		if diff == "" {
+			fmt.Println("The effective configuration matches the file.")
+			return nil
+		}
+		fmt.Print(diff)
+		return nil
+	},
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write a shareable subset of the configuration",
+	Long: `Write the repositories, dependencies and tools of the configuration to a file
+that can be shared with teammates and merged into their configuration with
+config import. Select sections with --repos, --deps and --tools; without any,
+all three are exported.
+
+Local details are left out: repository paths and sync times, dependency install
+paths and headers, and credentials in http(s) URLs. ${env:...} and ${file:...}
+references are exported as written, never with their values, and tool paths
+under your home directory are written with ~.
+
+Example:
+  dev-manager config export --repos --out shared.yaml
+  dev-manager config export > everything.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		out, _ := cmd.Flags().GetString("out")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		data, err := yaml.Marshal(mgr.Export(shareSections(cmd)))
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+
+		if out == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if _, err := os.Stat(out); err == nil && !confirm(cmd, fmt.Sprintf("Overwrite %s?", out), false) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		fmt.Printf("Exported configuration to %s\n", out)
+		return nil
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Merge a shared configuration into yours",
+	Long: `Merge the repositories, dependencies and tools of a file written by config
+export into the configuration. Select sections with --repos, --deps and
+--tools; without any, all three are imported.
+
+Imported repositories are placed in your workspace. Entries whose name you
+already use are never overwritten: identical ones are skipped, and ones that
+differ are reported as conflicts for you to resolve by hand. Hooks of the
+imported repositories run shell commands, so they are listed and the import
+asks for confirmation before saving them. Without a configuration yet, one is
+created with the default workspace, as init would. The previous
+configuration can be restored with config undo.
+
+Example:
+  dev-manager config import shared.yaml
+  dev-manager config import --repos shared.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		var shared config.Config
+		if err := yaml.Unmarshal(data, &shared); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", args[0], err)
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+		if err := mgr.Load(); err != nil {
+			if !errors.Is(err, config.ErrConfigNotFound) {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			workspace, err := defaultWorkspace()
+			if err != nil {
+				return err
+			}
+			cfg := mgr.GetConfig()
+			cfg.WorkspacePath = workspace
+			cfg.UpdateFrequency = config.DefaultUpdateFrequency
+		}
+
+		cfg := mgr.GetConfig()
+		report, err := cfg.Import(&shared, shareSections(cmd))
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", args[0], err)
+		}
+
+		for _, item := range report.Added {
+			fmt.Printf("Added %s\n", item)
+		}
+		for _, item := range report.Skipped {
+			fmt.Printf("Skipped %s (already configured)\n", item)
+		}
+		for _, item := range report.Conflicts {
+			fmt.Printf("Conflict: %s is configured differently; keeping yours\n", item)
+		}
+
+		if len(report.Added) > 0 {
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("imported configuration is invalid: %w", err)
+			}
+			if len(report.Hooks) > 0 {
+				fmt.Println("\nImported repositories have hooks that run shell commands:")
+				for _, hook := range report.Hooks {
+					fmt.Printf("  %s\n", hook)
+				}
+				if !confirm(cmd, "Import them?", false) {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+			if err := mgr.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+		} else {
+			fmt.Println("Nothing to import.")
+		}
+		if len(report.Conflicts) > 0 {
+			return fmt.Errorf("%d conflicting entries were not imported", len(report.Conflicts))
+		}
+		return nil
+	},
+}
+
+// shareSections returns the sections selected with --repos, --deps and
+// --tools, or all of them when none is given
+func shareSections(cmd *cobra.Command) config.Sections {
+	var s config.Sections
+	s.Repositories, _ = cmd.Flags().GetBool("repos")
+	s.Dependencies, _ = cmd.Flags().GetBool("deps")
+	s.Tools, _ = cmd.Flags().GetBool("tools")
+	if s == (config.Sections{}) {
+		return config.AllSections
+	}
+	return s
+}
+
+var configUndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restore the configuration from before the last change",
+	Long: fmt.Sprintf(`Every command that changes the configuration first keeps the previous file
+as config.yaml.1, shifting older copies up to config.yaml.%d. Undo shows what
+restoring the most recent copy would change and, once confirmed, restores it.
+Run it again to step further back.
+
+Example:
+  dev-manager config undo`, config.MaxBackups),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		backup, err := os.ReadFile(mgr.BackupPath(1))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return config.ErrNoBackup
+			}
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		current, err := os.ReadFile(mgr.Path())
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+
+		if diff := textdiff.Unified(mgr.Path(), mgr.BackupPath(1), string(current), string(backup), 3); diff != "" {
+			fmt.Print(diff)
+		}
+		if !confirm(cmd, fmt.Sprintf("Restore %s from %s?", mgr.Path(), mgr.BackupPath(1)), false) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		if err := mgr.Undo(); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s\n", mgr.Path())
+		return nil
+	},
+}
+
 var configValidateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate the configuration",
 	Long: `Validate the current configuration for required fields and structure.
 Shows a detailed report of any validation errors found.
 
+With --strict the configuration is also checked against the filesystem and
+network: the workspace must exist and be writable, repositories must not
+share a path and should live inside the workspace, and dependency sources
+must answer a HEAD request, or a ranged GET where HEAD is refused. Problems
+that may be intentional or temporary, such as an unreachable source while
+offline, are reported as warnings and don't fail the command.
+
 Example:
   dev-manager config validate --file config.yaml
-  dev-manager config validate -f config.yaml`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager config validate -f config.yaml
+  dev-manager config validate --strict`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
+		fmt.Printf("Validating configuration at %s...\n\n", mgr.Path())
+
 		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+			switch {
+			case errors.Is(err, config.ErrConfigNotFound):
+				return fmt.Errorf("no configuration file found; run \"dev-manager init\" to create one")
+			case errors.Is(err, config.ErrConfigParse):
+				return fmt.Errorf("the configuration is not valid YAML: %w", err)
+			default:
+				return fmt.Errorf("failed to load config: %w", err)
+			}
 		}
 
 		cfg := mgr.GetConfig()
 
-		fmt.Printf("Validating configuration at %s...\n\n", mgr.Path())
-
 		if err := cfg.Validate(); err != nil {
 			if validationErr, ok := err.(*config.ValidationError); ok {
 				fmt.Println(validationErr.Error())
-				os.Exit(1)
+				return fmt.Errorf("configuration is invalid")
+			}
+			return fmt.Errorf("validation failed: %w", err)
+		}
+
+		if strict, _ := cmd.Flags().GetBool("strict"); strict {
+			findings := app.CheckConfig(context.Background(), cfg, app.DepsManager(cfg))
+			errorCount := 0
+			for _, f := range findings {
+				if f.Severity == app.SeverityError {
+					errorCount++
+				}
+				fmt.Printf("  %s: %s\n", f.Severity, f.Message)
+			}
+			if errorCount > 0 {
+				return fmt.Errorf("configuration has %d error(s)", errorCount)
+			}
+			if len(findings) > 0 {
+				fmt.Printf("\nConfiguration is valid with %d warning(s).\n", len(findings))
+				return nil
 			}
-			log.Fatalf("validation failed: %v", err)
 		}
 
 		fmt.Println("Configuration is valid!")
+		return nil
 	},
 }
 
@@ -62,33 +350,47 @@ var configShowCmd = &cobra.Command{
 	Short: "Show the current configuration",
 	Long: `Show the current configuration in a readable format.
 Shows workspace path and all managed repositories with their details.
+Use --output json to get the whole configuration as JSON.
 
 Example:
   dev-manager config show
-  dev-manager config show --raw`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager config show --raw
+  dev-manager config show -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 		raw, _ := cmd.Flags().GetBool("raw")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		cfg := mgr.GetConfig()
+		// References are shown as written rather than the secrets they resolve to
+		cfg := mgr.Unresolved()
+
+		if format == outputJSON {
+			if err := printJSON(cfg); err != nil {
+				return fmt.Errorf("failed to encode config: %w", err)
+			}
+			return nil
+		}
 
 		if raw {
 			// Print raw YAML content
 			data, err := yaml.Marshal(cfg)
 			if err != nil {
-				log.Fatalf("failed to marshal config: %v", err)
+				return fmt.Errorf("failed to marshal config: %w", err)
 			}
 			fmt.Println(string(data))
-			return
+			return nil
 		}
 
 		fmt.Printf("Configuration file: %s\n\n", mgr.Path())
@@ -96,7 +398,7 @@ Example:
 
 		if len(cfg.Repositories) == 0 {
 			fmt.Println("No repositories configured.")
-			return
+			return nil
 		}
 
 		fmt.Printf("Managed repositories (%d):\n\n", len(cfg.Repositories))
@@ -108,9 +410,142 @@ Example:
 			fmt.Printf("  Last Sync: %s\n", repo.LastSync.Format(time.RFC3339))
 			fmt.Println()
 		}
+		return nil
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the configuration in your editor",
+	Long: `Open the configuration file in $VISUAL or $EDITOR (vi, or notepad on
+Windows, if neither is set). When the editor exits the configuration is
+loaded and validated; if it has errors you can reopen the editor to fix them.
+Your edits are never discarded.
+
+Example:
+  dev-manager config edit
+  EDITOR="code --wait" dev-manager config edit`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		for {
+			if err := openEditor(mgr.Path()); err != nil {
+				return err
+			}
+
+			err := checkConfig(mgr.Path())
+			if err == nil {
+				fmt.Println("Configuration is valid!")
+				return nil
+			}
+
+			fmt.Println(err)
+			if assumeYes(cmd) || !confirm(cmd, "Reopen the editor to fix it?", true) {
+				return fmt.Errorf("configuration at %s has errors", mgr.Path())
+			}
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a configuration value",
+	Long: `Print the value of a top-level configuration field such as
+workspacePath or updateFrequency.
+
+Example:
+  dev-manager config get updateFrequency`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		value, err := mgr.GetConfig().Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration value",
+	Long: `Set a top-level configuration field such as workspacePath or
+updateFrequency. Durations use Go syntax (e.g. 2h, 30m). The configuration is
+validated before it is saved.
+
+Example:
+  dev-manager config set workspacePath ~/code
+  dev-manager config set updateFrequency 4h`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+		if err := cfg.Set(args[0], args[1]); err != nil {
+			return err
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("not saving invalid configuration: %w", err)
+		}
+
+		if err := mgr.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		value, _ := cfg.Get(args[0])
+		fmt.Printf("%s set to %s\n", args[0], value)
+		return nil
 	},
 }
 
+// checkConfig loads the configuration at path and validates it
+func checkConfig(path string) error {
+	mgr, err := config.NewManager(path)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	return mgr.GetConfig().Validate()
+}
+
+// defaultWorkspace returns the workspace a new configuration starts with,
+// $HOME/dev
+func defaultWorkspace() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "dev"), nil
+}
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize dev-manager configuration",
@@ -120,44 +555,50 @@ This will:
 2. Set up the workspace directory
 3. Install default dependencies
 
+Running init again leaves an existing configuration untouched. Use --force to
+reset its workspace path and update frequency; repositories, dependencies and
+tools are kept.
+
 Example:
   dev-manager init
-  dev-manager init --workspace ~/dev`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager init --workspace ~/dev
+  dev-manager init --force --workspace ~/src`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 		workspace, _ := cmd.Flags().GetString("workspace")
 		installDeps, _ := cmd.Flags().GetBool("install-deps")
+		force, _ := cmd.Flags().GetBool("force")
 
-		// Default workspace: $HOME/dev
 		if workspace == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				log.Fatalf("failed to get home directory: %v", err)
+			var err error
+			if workspace, err = defaultWorkspace(); err != nil {
+				return err
 			}
-			workspace = filepath.Join(home, "dev")
 		}
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
 		// Attempt to load existing config (fail if parsing error, ignore if not exists)
-		if err := mgr.Load(); err != nil {
-			if !os.IsNotExist(err) {
-				log.Fatalf("failed to load config: %v", err)
-			}
+		err = mgr.Load()
+		if err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
+		exists := err == nil
 
 		cfg := mgr.GetConfig()
-		if err := cfg.Validate(); err != nil {
-			log.Fatalf("invalid configuration: %v", err)
+		if exists && !force {
+			fmt.Printf("Configuration already initialized at %s (use --force to reset it)\n", mgr.Path())
+			return installDefaultDeps(cfg, installDeps)
 		}
-		if cfg.WorkspacePath == "" {
+
+		if force || cfg.WorkspacePath == "" {
 			cfg.WorkspacePath = workspace
 		}
-		if cfg.UpdateFrequency == 0 {
-			cfg.UpdateFrequency = 2 * time.Hour
+		if force || cfg.UpdateFrequency == 0 {
+			cfg.UpdateFrequency = config.DefaultUpdateFrequency
 		}
 
 		// Add default dependencies if none exist
@@ -176,39 +617,74 @@ Example:
 			}
 		}
 
+		// Validate only once the defaults are in place
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
 		// Save configuration
 		if err := mgr.Save(); err != nil {
-			log.Fatalf("failed to save configuration: %v", err)
+			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
 		fmt.Printf("Configuration initialized at %s\n", mgr.Path())
 		fmt.Printf("Workspace directory: %s\n", cfg.WorkspacePath)
 
-		// Install dependencies if requested
-		if installDeps {
-			fmt.Println("\nInstalling dependencies...")
-			depMgr := deps.New(filepath.Join(cfg.WorkspacePath, "deps"))
-			for _, dep := range cfg.Dependencies {
-				if err := depMgr.Install(dep, false); err != nil {
-					log.Printf("failed to install %s: %v", dep.Name, err)
-					continue
-				}
-				fmt.Printf("Installed %s\n", dep.Name)
-			}
-		}
+		return installDefaultDeps(cfg, installDeps)
 	},
 }
 
+// installDefaultDeps installs the configured dependencies when init is run
+// with --install-deps
+func installDefaultDeps(cfg *config.Config, installDeps bool) error {
+	if !installDeps {
+		return nil
+	}
+
+	fmt.Println("\nInstalling dependencies...")
+	ctx, stop := app.InterruptContext(context.Background())
+	defer stop()
+	depMgr := app.DepsManager(cfg)
+	for _, dep := range cfg.Dependencies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := depMgr.InstallContext(ctx, dep, false); err != nil {
+			log.Printf("failed to install %s: %v", dep.Name, err)
+			continue
+		}
+		fmt.Printf("Installed %s\n", dep.Name)
+	}
+	return nil
+}
+
 func init() {
 	// Add config commands
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configShowCmd)
 	configShowCmd.Flags().Bool("raw", false, "Show raw YAML content")
+	addOutputFlag(configShowCmd)
 	configCmd.AddCommand(configValidateCmd)
+	configValidateCmd.Flags().Bool("strict", false, "Also check the workspace, repository paths and dependency sources on disk and over the network")
+	configCmd.AddCommand(configDiffCmd)
+	configCmd.AddCommand(configUndoCmd)
+	configDiffCmd.Flags().IntP("context", "U", 3, "Number of unchanged lines to show around each change")
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	for _, c := range []*cobra.Command{configExportCmd, configImportCmd} {
+		c.Flags().Bool("repos", false, "Include repositories")
+		c.Flags().Bool("deps", false, "Include dependencies")
+		c.Flags().Bool("tools", false, "Include tools")
+	}
+	configExportCmd.Flags().String("out", "", "File to write instead of stdout")
 	configCmd.PersistentFlags().StringP("file", "f", "", "Path to the configuration file")
 
 	// Add init command
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().StringP("workspace", "w", "", "Path to the workspace directory")
 	initCmd.Flags().BoolP("install-deps", "i", false, "Install default dependencies")
+	initCmd.Flags().Bool("force", false, "Reset the workspace path and update frequency of an existing configuration")
 }
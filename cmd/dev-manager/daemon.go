@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"dev-manager/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep repositories synced on a schedule",
+	Long: `Run in the foreground, syncing repositories every updateFrequency until
+interrupted. Each cycle reloads the configuration, so repositories added or
+settings changed while the daemon runs are picked up on the next cycle, and
+only repositories that are due according to their own updateFrequency are
+synced. Stop it with Ctrl-C or SIGTERM; in-flight syncs are cancelled.
+Repositories with hooks are only synced with --allow-hooks, which runs them
+unattended, or --no-hooks, which skips them.
+
+Example:
+  dev-manager daemon
+  dev-manager daemon --interval 30m --jobs 4`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		allowHooks, _ := cmd.Flags().GetBool("allow-hooks")
+
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := syncAllOptions{jobs: jobs, timeout: timeout, ifStale: true, noHooks: noHooks, allowHooks: allowHooks}
+		next := runDaemonCycle(ctx, cfgPath, interval, opts)
+		ticker := time.NewTicker(next)
+		defer ticker.Stop()
+
+		for {
+			log.Printf("Next sync in %s", next)
+			select {
+			case <-ctx.Done():
+				log.Println("Shutting down")
+				return nil
+			case <-ticker.C:
+			}
+
+			// The interval may have changed with the reloaded config
+			if n := runDaemonCycle(ctx, cfgPath, interval, opts); n != next {
+				next = n
+				ticker.Reset(next)
+			}
+		}
+	},
+}
+
+// runDaemonCycle loads the config and syncs the repositories that are due,
+// returning how long to wait before the next cycle: interval when set,
+// otherwise the config's updateFrequency. Errors are logged rather than
+// returned so one bad cycle doesn't stop the daemon.
+func runDaemonCycle(ctx context.Context, cfgPath string, interval time.Duration, opts syncAllOptions) time.Duration {
+	next := interval
+	if next <= 0 {
+		next = config.DefaultUpdateFrequency
+	}
+
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		log.Printf("Sync cycle skipped: failed to create config manager: %v", err)
+		return next
+	}
+	if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+		log.Printf("Sync cycle skipped: failed to load config: %v", err)
+		return next
+	}
+	if cfg := mgr.GetConfig(); interval <= 0 && cfg.UpdateFrequency > 0 {
+		next = cfg.UpdateFrequency
+	}
+
+	log.Println("Starting sync cycle")
+	if err := syncAll(ctx, mgr, opts); err != nil {
+		log.Printf("Sync cycle failed: %v", err)
+		return next
+	}
+	log.Println("Sync cycle finished")
+	return next
+}
+
+func init() {
+	daemonCmd.Flags().Duration("interval", 0, "Time between sync cycles (defaults to the config's updateFrequency)")
+	daemonCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of repositories to sync concurrently")
+	daemonCmd.Flags().Duration("timeout", 0, "Maximum time to spend syncing a single repository (e.g. 2m, 0 for no limit)")
+	daemonCmd.Flags().Bool("no-hooks", false, "Don't run the repositories' postSync and postClone hooks")
+	daemonCmd.Flags().Bool("allow-hooks", false, "Run the repositories' hooks (they execute arbitrary shell commands)")
+	daemonCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"dev-manager/internal/testutil/mockgit"
+	"dev-manager/pkg/config"
+)
+
+func TestRunDaemonCycle(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	workspace := t.TempDir()
+	cloned := filepath.Join(workspace, "cloned")
+	if err := os.MkdirAll(filepath.Join(cloned, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both were synced moments ago, but "added" has no clone yet
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := mgr.GetConfig()
+	cfg.WorkspacePath = workspace
+	cfg.UpdateFrequency = time.Hour
+	cfg.Repositories = []config.Repository{
+		{Name: "cloned", URL: "https://example.com/cloned.git", Path: cloned, Branch: "main", LastSync: time.Now()},
+		{Name: "added", URL: "https://example.com/added.git", Path: filepath.Join(workspace, "added"), Branch: "main", LastSync: time.Now()},
+	}
+	if err := mgr.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	runDaemonCycle(context.Background(), cfgPath, time.Hour, syncAllOptions{jobs: 1, ifStale: true})
+
+	// Only the new repository is cloned; the other one isn't due
+	var clones []string
+	for _, args := range mock.Invocations(t) {
+		if i := slices.Index(args, "clone"); i >= 0 {
+			clones = append(clones, args[len(args)-1])
+		}
+	}
+	if want := []string{filepath.Join(workspace, "added")}; !slices.Equal(clones, want) {
+		t.Errorf("cloned %v, want %v", clones, want)
+	}
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 
+	"dev-manager/pkg/app"
 	"dev-manager/pkg/config"
 	"dev-manager/pkg/deps"
 
@@ -22,6 +31,12 @@ var depsAddCmd = &cobra.Command{
 	Short: "Add a new dependency to the configuration",
 	Long: `Add a new dependency to the configuration.
 The dependency can be specified with name, version, and source using flags.
+If the dependency is already installed, e.g. from an earlier attempt, you are
+asked whether to reinstall it; --force reinstalls it without asking.
+Without --source, the official download for this OS and architecture is used
+for well-known tools (go, node and python); python versions name a
+python-build-standalone release, e.g. 3.12.3+20240415.
+Example: dev-manager deps add --name go --version 1.22.0
 Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev/dl/go1.21.0.darwin-amd64.tar.gz`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
@@ -30,7 +45,7 @@ Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev
 			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := cfgMgr.Load(); err != nil {
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
@@ -40,12 +55,20 @@ Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev
 		name, _ := cmd.Flags().GetString("name")
 		version, _ := cmd.Flags().GetString("version")
 		source, _ := cmd.Flags().GetString("source")
+		installScript, _ := cmd.Flags().GetString("install-script")
+		checksum, _ := cmd.Flags().GetString("checksum")
+		headerFlags, _ := cmd.Flags().GetStringArray("header")
 
 		// Validate required flags
 		if name == "" {
 			return fmt.Errorf("dependency name is required")
 		}
 
+		headers, err := parseHeaders(headerFlags)
+		if err != nil {
+			return err
+		}
+
 		// Check if dependency already exists
 		for _, dep := range cfg.Dependencies {
 			if dep.Name == name {
@@ -53,11 +76,22 @@ Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev
 			}
 		}
 
+		if source == "" {
+			source, err = deps.InferSource(name, version, runtime.GOOS, runtime.GOARCH)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Using source %s\n", source)
+		}
+
 		// Create new dependency
 		newDep := config.Dependency{
-			Name:    name,
-			Version: version,
-			Source:  source,
+			Name:          name,
+			Version:       version,
+			Source:        source,
+			Checksum:      checksum,
+			InstallScript: installScript,
+			Headers:       headers,
 		}
 
 		// Add to configuration
@@ -71,15 +105,38 @@ Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev
 		fmt.Printf("Added dependency %s to configuration\n", name)
 
 		// Ask user if they want to install now
-		fmt.Print("Would you like to install this dependency now? (Y/n): ")
-		var resp string
-		fmt.Scanln(&resp)
-		if resp == "" || resp == "Y" || resp == "y" {
-			depMgr := deps.New(filepath.Join(cfg.WorkspacePath, "deps"))
-			if err := depMgr.Install(newDep, false); err != nil {
-				return fmt.Errorf("failed to install %s: %w", name, err)
+		if confirm(cmd, "Would you like to install this dependency now?", true) {
+			// Reload so references in the new dependency's fields are resolved
+			if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			depMgr := app.DepsManager(cfgMgr.GetConfig())
+			depMgr.AllowInstallScripts, _ = cmd.Flags().GetBool("allow-install-scripts")
+			depMgr.NoCache, _ = cmd.Flags().GetBool("no-cache")
+			force, _ := cmd.Flags().GetBool("force")
+			ctx, stop := app.InterruptContext(context.Background())
+			defer stop()
+			newDep, err = app.InstallDependency(ctx, depMgr, cfgMgr.GetConfig(), name, force)
+			// Files left by an earlier attempt are only replaced when asked to
+			if errors.Is(err, deps.ErrAlreadyInstalled) {
+				if !confirm(cmd, fmt.Sprintf("%s is already installed in %s. Reinstall it?", name, depMgr.InstallDir), false) {
+					fmt.Printf("Kept the existing installation of %s\n", name)
+					return nil
+				}
+				newDep, err = app.InstallDependency(ctx, depMgr, cfgMgr.GetConfig(), name, true)
+			}
+			if err != nil {
+				return err
 			}
 			fmt.Printf("Installed %s\n", name)
+
+			if link, _ := cmd.Flags().GetBool("link"); link {
+				if err := linkDependency(depMgr, newDep, force); err != nil {
+					return err
+				}
+				printBinDirHint(depMgr)
+			}
 		} else {
 			fmt.Printf("Dependency %s will be installed during the next sync\n", name)
 		}
@@ -91,38 +148,243 @@ Example: dev-manager deps add --name go --version 1.21.0 --source https://go.dev
 var depsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all dependencies",
-	Long:  `List all dependencies in the configuration and their installation status.`,
+	Long: `List all dependencies in the configuration and their installation status.
+Use --output json to get the dependencies as JSON, e.g. for scripts.
+
+Example:
+  dev-manager deps list
+  dev-manager deps list -o json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
 		cfgMgr, err := config.NewManager(cfgPath)
 		if err != nil {
 			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := cfgMgr.Load(); err != nil {
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := cfgMgr.GetConfig()
 
 		// List all dependencies
+		entries := []depListEntry{}
+		depMgr := app.DepsManager(cfg)
 		for _, dep := range cfg.Dependencies {
-			depPath := filepath.Join(cfg.WorkspacePath, "deps", dep.Name)
+			entries = append(entries, depListEntry{Dependency: dep, Headers: headerNames(dep), Installed: depMgr.IsInstalled(dep)})
+		}
+
+		if format == outputJSON {
+			return printJSON(entries)
+		}
+
+		for _, entry := range entries {
 			installed := "not installed"
-			if _, err := os.Stat(depPath); err == nil {
+			if entry.Installed {
 				installed = "installed"
 			}
-			fmt.Printf("%s (%s): %s\n", dep.Name, dep.Version, installed)
+			fmt.Printf("%s (%s): %s\n", entry.Name, entry.Version, installed)
+		}
+
+		return nil
+	},
+}
+
+// depListEntry is a configured dependency along with its install status
+type depListEntry struct {
+	config.Dependency
+	// Headers shadows the dependency's headers with their names only, as the
+	// values usually hold credentials
+	Headers   []string `json:"headers,omitempty"`
+	Installed bool     `json:"installed"`
+}
+
+var depsInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show everything known about a dependency",
+	Long: `Show a dependency's configuration alongside what is installed: the version
+recorded at install time, the install path and its size on disk, and the
+binaries linked into the deps bin directory. The source downloaded on this
+OS and architecture is shown too, along with the official download of a
+well-known tool when the configured source differs from it.
+
+Example:
+  dev-manager deps info --name go
+  dev-manager deps info --name go -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfgMgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := cfgMgr.GetConfig()
+		dep, ok := cfg.FindDependency(name)
+		if !ok {
+			return fmt.Errorf("dependency '%s' not found", name)
+		}
+
+		depMgr := app.DepsManager(cfg)
+		info, err := depMgr.Info(*dep)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", dep.Name, err)
+		}
+
+		source := resolveSource(*dep)
+		if format == outputJSON {
+			return printJSON(depInfoEntry{Dependency: *dep, Headers: headerNames(*dep), platformSource: source, Info: info})
 		}
 
+		fmt.Printf("Name:      %s\n", dep.Name)
+		fmt.Printf("Version:   %s\n", dep.Version)
+		fmt.Printf("Source:    %s\n", dep.Source)
+		fmt.Printf("Resolved:  %s (%s)\n", source.ResolvedSource, source.Platform)
+		if source.OfficialSource != "" {
+			fmt.Printf("Official:  %s (differs from the configured source)\n", source.OfficialSource)
+		}
+		if dep.Checksum != "" {
+			fmt.Printf("Checksum:  %s\n", dep.Checksum)
+		}
+		if dep.InstallScript != "" {
+			fmt.Printf("Script:    %s\n", dep.InstallScript)
+		}
+		if len(dep.Headers) > 0 {
+			// Values are left out as they usually hold credentials
+			fmt.Printf("Headers:   %s\n", strings.Join(headerNames(*dep), ", "))
+		}
+		fmt.Printf("Path:      %s\n", info.Path)
+
+		if !info.Installed {
+			fmt.Println("Installed: no")
+			return nil
+		}
+		if meta := info.Metadata; meta != nil {
+			fmt.Printf("Installed: %s from %s on %s\n", meta.Version, meta.Source, meta.InstalledAt.Format(time.RFC3339))
+		} else {
+			fmt.Println("Installed: yes (no install record)")
+		}
+		fmt.Printf("Size:      %s\n", formatSize(info.Size))
+
+		if len(info.Links) == 0 {
+			fmt.Println("Linked:    no")
+			return nil
+		}
+		fmt.Println("Linked:")
+		for _, link := range info.Links {
+			fmt.Printf("  %s\n", link)
+		}
+		if !onPath(depMgr.BinDir()) {
+			printBinDirHint(depMgr)
+		}
 		return nil
 	},
 }
 
+// parseHeaders parses "Name: value" header flags into a map, returning nil
+// when there are none
+func parseHeaders(flags []string) (map[string]string, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(flags))
+	for _, f := range flags {
+		name, value, ok := strings.Cut(f, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q (want \"Name: value\")", f)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// depInfoEntry is a configured dependency along with its on-disk state
+type depInfoEntry struct {
+	config.Dependency
+	// Headers shadows the dependency's headers with their names only
+	Headers []string `json:"headers,omitempty"`
+	platformSource
+	*deps.Info
+}
+
+// platformSource is where a dependency is downloaded from on this OS and
+// architecture
+type platformSource struct {
+	Platform       string `json:"platform"`
+	ResolvedSource string `json:"resolvedSource"`
+	// OfficialSource is the download URL published for a well-known tool on
+	// this platform, when it differs from the configured source, e.g. one
+	// written for another OS
+	OfficialSource string `json:"officialSource,omitempty"`
+}
+
+// resolveSource works out where installing dep downloads from on this
+// platform: the configured source or, without one, the official download of
+// a well-known tool
+func resolveSource(dep config.Dependency) platformSource {
+	ps := platformSource{Platform: runtime.GOOS + "/" + runtime.GOARCH, ResolvedSource: dep.Source}
+	official, err := deps.InferSource(dep.Name, dep.Version, runtime.GOOS, runtime.GOARCH)
+	switch {
+	case err != nil:
+	case dep.Source == "":
+		ps.ResolvedSource = official
+	case official != dep.Source:
+		ps.OfficialSource = official
+	}
+	return ps
+}
+
+// headerNames returns the sorted names of a dependency's headers, leaving out
+// their values
+func headerNames(dep config.Dependency) []string {
+	return slices.Sorted(maps.Keys(dep.Headers))
+}
+
+// formatSize renders a byte count with a binary unit, e.g. 12.3 MiB
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// onPath reports whether dir is one of the directories in $PATH
+func onPath(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(p) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
 var depsRemoveCmd = &cobra.Command{
-	Use:   "remove",
-	Short: "Remove a dependency",
-	Long:  `Remove a dependency from the configuration and uninstall it. If no dependency is specified with --name, you will be prompted to select one.`,
+	Use:     "remove",
+	Aliases: []string{"uninstall"},
+	Short:   "Remove a dependency",
+	Long: `Remove a dependency from the configuration and uninstall it, along with the
+links to its binaries in the deps bin directory. If no dependency is specified
+with --name, you will be prompted to select one.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 		cfgMgr, err := config.NewManager(cfgPath)
@@ -130,7 +392,7 @@ var depsRemoveCmd = &cobra.Command{
 			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := cfgMgr.Load(); err != nil {
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
@@ -180,8 +442,27 @@ var depsRemoveCmd = &cobra.Command{
 			return fmt.Errorf("dependency %s not found in configuration", name)
 		}
 
-		// Remove from configuration
 		depToRemove := cfg.Dependencies[index]
+		depMgr := app.DepsManager(cfg)
+		if !confirm(cmd, fmt.Sprintf("Remove %s and delete its installation from %s?", name, depMgr.InstallDir), false) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		// Uninstall first so a rejected path leaves the configuration untouched.
+		// Links go before the files so none are left dangling.
+		links, err := depMgr.Unlink(depToRemove, depMgr.BinDir())
+		if err != nil {
+			return fmt.Errorf("failed to unlink %s: %w", name, err)
+		}
+		for _, link := range links {
+			fmt.Printf("Removed link %s\n", link)
+		}
+		if err := depMgr.Remove(depToRemove); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+
+		// Remove from configuration
 		cfg.Dependencies = append(cfg.Dependencies[:index], cfg.Dependencies[index+1:]...)
 
 		// Save configuration
@@ -189,12 +470,6 @@ var depsRemoveCmd = &cobra.Command{
 			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
-		// Uninstall dependency
-		depMgr := deps.New(filepath.Join(cfg.WorkspacePath, "deps"))
-		if err := depMgr.Remove(depToRemove); err != nil {
-			return fmt.Errorf("failed to remove %s: %w", name, err)
-		}
-
 		fmt.Printf("Removed dependency %s\n", name)
 		return nil
 	},
@@ -203,31 +478,299 @@ var depsRemoveCmd = &cobra.Command{
 var depsSyncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Install all uninstalled dependencies",
-	Long:  `Install all dependencies that are in the configuration but not yet installed.`,
+	Long: `Install all dependencies that are in the configuration but not yet installed.
+Dependencies that are already installed are skipped, unless their source was
+changed with "deps edit" since they were installed. With --dry-run, print
+what would be installed without downloading anything.
+Use --name to sync a single dependency, and --force to reinstall dependencies
+that are already installed.
+
+With --link, a binary that another dependency already links into the deps bin
+directory (two toolchains shipping bin/go, say) is reported as a conflict and
+nothing is linked; --force replaces such links.
+
+Example:
+  dev-manager deps sync
+  dev-manager deps sync --name node --force`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+		force, _ := cmd.Flags().GetBool("force")
 		cfgMgr, err := config.NewManager(cfgPath)
 		if err != nil {
 			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := cfgMgr.Load(); err != nil {
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := cfgMgr.GetConfig()
 
 		// Create dependency manager
-		depMgr := deps.New(filepath.Join(cfg.WorkspacePath, "deps"))
+		depMgr := app.DepsManager(cfg)
+		depMgr.AllowInstallScripts, _ = cmd.Flags().GetBool("allow-install-scripts")
+		depMgr.NoCache, _ = cmd.Flags().GetBool("no-cache")
+		depMgr.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
+		link, _ := cmd.Flags().GetBool("link")
+
+		if depMgr.DryRun {
+			fmt.Println("Dry run: nothing will be downloaded or installed.")
+		}
+
+		// Install all dependencies, or only the named one
+		ctx, stop := app.InterruptContext(context.Background())
+		defer stop()
+		_, err = app.SyncDependencies(ctx, depMgr, cfg, app.DepSyncOptions{
+			Link:  link,
+			Name:  name,
+			Force: force,
+			Progress: func(result app.DepInstallResult) {
+				dep := result.Dependency
+				switch result.Status {
+				case app.StatusUpToDate:
+					fmt.Printf("Skipping %s: already installed\n", dep.Name)
+				case app.StatusPlanned:
+					fmt.Printf("Would install %s %s from %s\n", dep.Name, dep.Version, dep.Source)
+				case app.StatusFailed:
+					fmt.Printf("Failed to install %s (%s)\n", dep.Name, roundDuration(result.Duration))
+				default:
+					fmt.Printf("Installed %s (%s)\n", dep.Name, roundDuration(result.Duration))
+					for _, link := range result.Links {
+						fmt.Printf("Linked %s\n", link)
+					}
+				}
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if link && !depMgr.DryRun {
+			printBinDirHint(depMgr)
+		}
+
+		return nil
+	},
+}
+
+var depsEditCmd = &cobra.Command{
+	Use:     "edit",
+	Aliases: []string{"rename"},
+	Short:   "Rename a dependency or change its source",
+	Long: `Change the name, source or checksum of a dependency in place, without
+removing and re-adding it.
+
+Renaming moves the installed files to the new name and re-creates any links to
+its binaries in the deps bin directory. Changing the source clears the install
+record, so the next "deps sync" reinstalls the dependency from the new source.
+
+Example: dev-manager deps edit --name go --new-name go1.21 --source https://go.dev/dl/go1.21.0.linux-amd64.tar.gz`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		cfgMgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := cfgMgr.GetConfig()
+
+		name, _ := cmd.Flags().GetString("name")
+		newName, _ := cmd.Flags().GetString("new-name")
+		source, _ := cmd.Flags().GetString("source")
+		checksum, _ := cmd.Flags().GetString("checksum")
+
+		dep, ok := cfg.FindDependency(name)
+		if !ok {
+			return fmt.Errorf("dependency %s not found in configuration", name)
+		}
+
+		renamed := newName != "" && newName != dep.Name
+		sourceChanged := cmd.Flags().Changed("source") && source != dep.Source
+		checksumChanged := cmd.Flags().Changed("checksum") && checksum != dep.Checksum
+		if !renamed && !sourceChanged && !checksumChanged {
+			fmt.Printf("Nothing to change for %s\n", name)
+			return nil
+		}
+
+		if renamed {
+			if _, exists := cfg.FindDependency(newName); exists {
+				return fmt.Errorf("dependency %s already exists in configuration", newName)
+			}
+		}
+
+		// Move the installation before touching the configuration, so a failed
+		// rename leaves both as they were; it is moved back if the
+		// configuration can't be saved
+		depMgr := app.DepsManager(cfg)
+		if renamed {
+			links, err := depMgr.Rename(*dep, newName)
+			if err != nil {
+				return fmt.Errorf("failed to rename %s: %w", name, err)
+			}
+			dep.Name = newName
+			for _, link := range links {
+				fmt.Printf("Linked %s\n", link)
+			}
+		}
+		if sourceChanged {
+			dep.Source = source
+			if err := depMgr.Invalidate(*dep); err != nil {
+				return err
+			}
+		}
+		if checksumChanged {
+			dep.Checksum = checksum
+		}
+
+		if err := cfgMgr.Save(); err != nil {
+			if renamed {
+				if _, rerr := depMgr.Rename(*dep, name); rerr != nil {
+					return fmt.Errorf("failed to save configuration: %w (and failed to move %s back to %s: %v)", err, newName, name, rerr)
+				}
+			}
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		if renamed {
+			fmt.Printf("Renamed dependency %s to %s\n", name, newName)
+		}
+		if sourceChanged {
+			if depMgr.IsInstalled(*dep) {
+				fmt.Printf("Changed the source of %s; it will be reinstalled during the next sync\n", dep.Name)
+			} else {
+				fmt.Printf("Changed the source of %s\n", dep.Name)
+			}
+			if !checksumChanged && dep.Checksum != "" {
+				fmt.Printf("Note: %s still expects checksum %s; pass --checksum to update it\n", dep.Name, dep.Checksum)
+			}
+		}
+		if checksumChanged {
+			fmt.Printf("Changed the checksum of %s\n", dep.Name)
+		}
+		return nil
+	},
+}
+
+var depsCleanCacheCmd = &cobra.Command{
+	Use:   "clean-cache",
+	Short: "Delete cached dependency downloads",
+	Long: `Delete the archives cached from previous dependency installs. Installed
+dependencies are not affected; later installs download their sources again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		cfgMgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		depMgr := app.DepsManager(cfgMgr.GetConfig())
+		if err := depMgr.CleanCache(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed download cache at %s\n", depMgr.CacheDir())
+		return nil
+	},
+}
 
-		// Install all dependencies
+// linkDependency symlinks a dependency's executables into the manager's bin
+// directory, replacing links to other dependencies only when force is set
+func linkDependency(depMgr *deps.Manager, dep config.Dependency, force bool) error {
+	links, err := depMgr.Link(dep, depMgr.BinDir(), force)
+	if err != nil {
+		return fmt.Errorf("failed to link %s: %w", dep.Name, err)
+	}
+	for _, link := range links {
+		fmt.Printf("Linked %s\n", link)
+	}
+	return nil
+}
+
+// printBinDirHint tells the user which directory to add to their PATH
+func printBinDirHint(depMgr *deps.Manager) {
+	fmt.Printf("\nAdd %s to your PATH to use linked binaries:\n", depMgr.BinDir())
+	fmt.Printf("  export PATH=\"%s:$PATH\"\n", depMgr.BinDir())
+}
+
+var depsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check installed dependencies against the configuration",
+	Long: `Check every configured dependency against what is installed, reporting
+dependencies that are MISSING or have DRIFTED from the configured version or
+source. Installed files are re-hashed and compared with those recorded at
+install time, reporting INCOMPLETE installs whose files were removed and
+MODIFIED ones whose files were changed. Files added since the install, such
+as packages installed into it, are only mentioned. The command exits non-zero
+when any dependency fails, so it can gate CI. Use --repair to reinstall those
+dependencies.
+
+Example:
+  dev-manager deps verify
+  dev-manager deps verify --repair`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		repair, _ := cmd.Flags().GetBool("repair")
+
+		cfgMgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := cfgMgr.GetConfig()
+		depMgr := app.DepsManager(cfg)
+		depMgr.AllowInstallScripts, _ = cmd.Flags().GetBool("allow-install-scripts")
+		depMgr.NoCache, _ = cmd.Flags().GetBool("no-cache")
+
+		ctx, stop := app.InterruptContext(context.Background())
+		defer stop()
+
+		failed := 0
 		for _, dep := range cfg.Dependencies {
-			if err := depMgr.Install(dep, false); err != nil {
-				return fmt.Errorf("failed to install %s: %w", dep.Name, err)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var v deps.Verification
+			if repair {
+				v, err = depMgr.Repair(ctx, dep)
+			} else {
+				v, err = depMgr.Verify(dep)
+			}
+			if err != nil {
+				fmt.Printf("%s: ERROR (%v)\n", dep.Name, err)
+				failed++
+				continue
+			}
+
+			switch {
+			case v.Status == deps.StatusOK && v.Detail != "":
+				fmt.Printf("%s: %s (%s)\n", dep.Name, v.Status, v.Detail)
+			case v.Status == deps.StatusOK:
+				fmt.Printf("%s: %s\n", dep.Name, v.Status)
+			case repair:
+				fmt.Printf("%s: %s (%s), repaired\n", dep.Name, v.Status, v.Detail)
+			default:
+				fmt.Printf("%s: %s (%s)\n", dep.Name, v.Status, v.Detail)
+				failed++
 			}
-			fmt.Printf("Installed %s\n", dep.Name)
 		}
 
+		if failed > 0 {
+			return fmt.Errorf("%d of %d dependencies failed verification", failed, len(cfg.Dependencies))
+		}
 		return nil
 	},
 }
@@ -235,15 +778,48 @@ var depsSyncCmd = &cobra.Command{
 func init() {
 	depsCmd.AddCommand(depsAddCmd)
 	depsCmd.AddCommand(depsListCmd)
+	addOutputFlag(depsListCmd)
+	depsCmd.AddCommand(depsInfoCmd)
+	addOutputFlag(depsInfoCmd)
+	depsCmd.AddCommand(depsEditCmd)
 	depsCmd.AddCommand(depsRemoveCmd)
 	depsCmd.AddCommand(depsSyncCmd)
+	depsCmd.AddCommand(depsVerifyCmd)
+	depsCmd.AddCommand(depsCleanCacheCmd)
 
 	// Add flags for deps add command
 	depsAddCmd.Flags().StringP("name", "n", "", "Name of the dependency")
 	depsAddCmd.Flags().StringP("version", "v", "", "Version of the dependency")
 	depsAddCmd.Flags().StringP("source", "s", "", "Source URL for the dependency")
+	depsAddCmd.Flags().String("checksum", "", "Expected sha256 of the downloaded source")
+	depsAddCmd.Flags().String("install-script", "", "Script inside the archive to run after extraction")
+	depsAddCmd.Flags().StringArray("header", nil, `Header to send when downloading, as "Name: value" (repeatable; ${env:VAR} references are kept in the config)`)
+	depsAddCmd.Flags().Bool("allow-install-scripts", false, "Run dependency install scripts (they execute arbitrary code)")
+	depsAddCmd.Flags().Bool("link", false, "Symlink the installed binaries into the deps bin directory")
+	depsAddCmd.Flags().Bool("no-cache", false, "Download the source even if a cached copy exists")
+	depsAddCmd.Flags().Bool("force", false, "Reinstall the dependency if it is already installed, and with --link replace links to other dependencies")
 	depsAddCmd.MarkFlagRequired("name")
 
+	depsSyncCmd.Flags().StringP("name", "n", "", "Only sync the dependency with this name")
+	depsSyncCmd.Flags().Bool("force", false, "Reinstall dependencies that are already installed, and with --link replace links to other dependencies")
+	depsSyncCmd.Flags().Bool("link", false, "Symlink installed binaries into the deps bin directory")
+	depsSyncCmd.Flags().Bool("allow-install-scripts", false, "Run dependency install scripts (they execute arbitrary code)")
+	depsSyncCmd.Flags().Bool("no-cache", false, "Download sources even if cached copies exist")
+	depsSyncCmd.Flags().Bool("dry-run", false, "Show which dependencies would be installed without installing them")
+
+	depsVerifyCmd.Flags().Bool("repair", false, "Reinstall missing or drifted dependencies")
+	depsVerifyCmd.Flags().Bool("allow-install-scripts", false, "Run dependency install scripts during repair (they execute arbitrary code)")
+	depsVerifyCmd.Flags().Bool("no-cache", false, "Download sources during repair even if cached copies exist")
+
+	depsInfoCmd.Flags().StringP("name", "n", "", "Name of the dependency")
+	depsInfoCmd.MarkFlagRequired("name")
+
+	depsEditCmd.Flags().StringP("name", "n", "", "Name of the dependency to edit")
+	depsEditCmd.Flags().String("new-name", "", "New name for the dependency")
+	depsEditCmd.Flags().StringP("source", "s", "", "New source URL for the dependency")
+	depsEditCmd.Flags().String("checksum", "", "New expected sha256 of the downloaded source")
+	depsEditCmd.MarkFlagRequired("name")
+
 	// Add name flag to depsRemoveCmd
 	depsRemoveCmd.Flags().StringP("name", "n", "", "Name of the dependency to remove")
 
@@ -1,14 +1,17 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
+
+	"dev-manager/pkg/git"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
@@ -25,60 +28,172 @@ var gitCommitCmd = &cobra.Command{
 	Short: "Stage, commit, and push changes with an LLM-generated commit message",
 	Long: `Stage, commit, and push changes with an LLM-generated commit message.
 If no custom message is provided, an LLM will generate one based on the changes.
-You can review the changes before committing.`,
+You can review the changes before committing.
+Use --new-branch to move the changes onto a new branch before committing.
+Use --amend to fold the changes into the last commit instead; with --no-llm
+and no --message the existing message is kept. Pushing an amended commit
+rewrites history, so it asks before force-pushing.
+Use --type and --scope to fix the conventional commit prefix, e.g. feat(api):.
+All changes are staged with "git add ." unless --paths limits the commit to
+the given pathspecs or --staged-only commits the index as it is.
+Use --sign to sign the commit (git commit -S), optionally with --signing-key.
+Use --long to have the LLM write a body under the subject line, with a
+BREAKING CHANGE: footer when the changes break compatibility.
+A proposed LLM message can be accepted, rejected, or opened in $VISUAL/$EDITOR
+with "e" to tweak it before committing.
+Without OPENAI_API_KEY, a message such as "chore: update 3 files in pkg/config"
+is proposed from the changed file names instead.
+In the review loop, "u <n>" unstages a file and "p <n>" picks hunks of it to
+unstage, so they are left out of the commit and its generated message.
+
+Example:
+  dev-manager git-ops commit
+  dev-manager git-ops commit --new-branch fix/typo --branch-from origin/main
+  dev-manager git-ops commit --amend --no-llm
+  dev-manager git-ops commit --type fix --scope deps -m "handle missing archives"
+  dev-manager git-ops commit --paths cmd/ --paths README.md
+  dev-manager git-ops commit --sign --signing-key ABCD1234
+  dev-manager git-ops commit --long`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get flags
 		customMsg, _ := cmd.Flags().GetString("message")
 		noPush, _ := cmd.Flags().GetBool("no-push")
 		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		newBranch, _ := cmd.Flags().GetString("new-branch")
+		branchFrom, _ := cmd.Flags().GetString("branch-from")
+		switchExisting, _ := cmd.Flags().GetBool("switch-existing")
+		setUpstream, _ := cmd.Flags().GetBool("set-upstream")
+		amend, _ := cmd.Flags().GetBool("amend")
+		commitType, _ := cmd.Flags().GetString("type")
+		scope, _ := cmd.Flags().GetString("scope")
+		paths, _ := cmd.Flags().GetStringArray("paths")
+		stagedOnly, _ := cmd.Flags().GetBool("staged-only")
+		sign, _ := cmd.Flags().GetBool("sign")
+		signingKey, _ := cmd.Flags().GetString("signing-key")
+		long, _ := cmd.Flags().GetBool("long")
+		if body, _ := cmd.Flags().GetBool("body"); body {
+			long = true
+		}
+		if gpgKey, _ := cmd.Flags().GetString("gpg-key"); gpgKey != "" {
+			if signingKey != "" && signingKey != gpgKey {
+				return fmt.Errorf("--gpg-key and --signing-key name different keys")
+			}
+			signingKey = gpgKey
+		}
+		// Choosing a key means the commit should be signed with it
+		sign = sign || signingKey != ""
 
-		// Stage all changes
-		stageCmd := exec.Command("git", "add", ".")
-		stageCmd.Stdout = os.Stdout
-		stageCmd.Stderr = os.Stderr
-		if err := stageCmd.Run(); err != nil {
-			return fmt.Errorf("failed to stage changes: %w", err)
+		if branchFrom != "" && newBranch == "" {
+			return fmt.Errorf("--branch-from requires --new-branch")
+		}
+		if setUpstream && noPush {
+			return fmt.Errorf("--set-upstream cannot be combined with --no-push")
+		}
+		if amend && newBranch != "" {
+			return fmt.Errorf("--amend cannot be combined with --new-branch")
+		}
+		if stagedOnly && len(paths) > 0 {
+			return fmt.Errorf("--staged-only cannot be combined with --paths")
 		}
 
-		// Get staged changes
-		diffCmd := exec.Command("git", "diff", "--cached")
-		diffOutput, err := diffCmd.Output()
-		if err != nil {
-			return fmt.Errorf("failed to get staged changes: %w", err)
+		// Conventional commit prefix required by --type/--scope
+		var prefix string
+		if commitType != "" {
+			var err error
+			if prefix, err = git.CommitPrefix(commitType, scope); err != nil {
+				return err
+			}
+		} else if scope != "" {
+			return fmt.Errorf("--scope requires --type")
+		}
+		if prefix != "" && amend && noLLM && customMsg == "" {
+			return fmt.Errorf("--type needs a new message; pass --message to amend with one")
 		}
 
-		// Get list of changed files
-		filesCmd := exec.Command("git", "diff", "--cached", "--name-only")
-		filesOutput, err := filesCmd.Output()
-		if err != nil {
-			return fmt.Errorf("failed to get changed files: %w", err)
+		// Move to the target branch first; staged and unstaged changes come along
+		if newBranch != "" {
+			repo := &git.Repository{Path: "."}
+			exists, err := repo.BranchExists(newBranch)
+			if err != nil {
+				return err
+			}
+			switch {
+			case exists && !switchExisting:
+				return fmt.Errorf("branch %s already exists (use --switch-existing to commit onto it)", newBranch)
+			case exists:
+				if err := repo.SwitchBranch(newBranch); err != nil {
+					return err
+				}
+				fmt.Printf("Switched to existing branch %s\n", newBranch)
+			default:
+				if err := repo.CreateBranch(newBranch, branchFrom); err != nil {
+					return err
+				}
+				fmt.Printf("Switched to new branch %s\n", newBranch)
+			}
+			// A new branch has no upstream yet, so the first push must create it
+			setUpstream = true
 		}
 
-		changedFiles := strings.Split(strings.TrimSpace(string(filesOutput)), "\n")
+		// Stage the requested changes; --paths also limits the diff and the
+		// commit to those pathspecs so other staged files stay out of it
+		var pathspec []string
+		if len(paths) > 0 {
+			pathspec = append([]string{"--"}, paths...)
+		}
+		if !stagedOnly {
+			addArgs := []string{"add", "."}
+			if len(paths) > 0 {
+				addArgs = append([]string{"add"}, pathspec...)
+			}
+			stageCmd := exec.Command("git", addArgs...)
+			stageCmd.Stdout = os.Stdout
+			stageCmd.Stderr = os.Stderr
+			if err := stageCmd.Run(); err != nil {
+				return fmt.Errorf("failed to stage changes: %w", err)
+			}
+		}
+
+		// Get staged changes; when amending, the amended commit covers the
+		// last commit's changes as well. Unstaging resets the index to base,
+		// the commit being built on.
+		diffArgs := []string{"diff", "--cached"}
+		base := "HEAD"
+		if amend {
+			base = amendBase()
+			diffArgs = append(diffArgs, base)
+		}
+		diffOutput, changedFiles, err := stagedChanges(diffArgs, pathspec)
+		if err != nil {
+			return err
+		}
 		if len(changedFiles) == 0 {
 			return fmt.Errorf("no changes to commit")
 		}
 
-		// Interactive file review loop
-		reader := bufio.NewReader(os.Stdin)
-		for {
+		// Interactive file review loop, skipped when running unattended
+		for !assumeYes(cmd) {
 			// Show changed files
 			fmt.Println("\nChanged files:")
 			for i, file := range changedFiles {
 				fmt.Printf("%d. %s\n", i+1, file)
 			}
 
-			// Ask for file number to review
-			fmt.Print("\nEnter file number to review (or press enter to continue): ")
-			fileNumStr, err := reader.ReadString('\n')
+			// Ask for file number to review, or to unstage
+			fmt.Print("\nEnter file number to review, u <n> to unstage it, p <n> to unstage hunks (or press enter to continue): ")
+			input, err := stdin.ReadString('\n')
 			if err != nil {
 				return fmt.Errorf("failed to read file number: %w", err)
 			}
 
-			fileNumStr = strings.TrimSpace(fileNumStr)
-			if fileNumStr == "" {
+			fields := strings.Fields(input)
+			if len(fields) == 0 {
 				break
 			}
+			action, fileNumStr := "", fields[0]
+			if len(fields) == 2 && (fields[0] == "u" || fields[0] == "p") {
+				action, fileNumStr = fields[0], fields[1]
+			}
 
 			fileNum, err := strconv.Atoi(fileNumStr)
 			if err != nil || fileNum < 1 || fileNum > len(changedFiles) {
@@ -86,8 +201,39 @@ You can review the changes before committing.`,
 				continue
 			}
 
+			if action != "" {
+				// git commit -- <paths> commits the working tree copy of the
+				// paths, which would bring unstaged changes right back
+				if len(paths) > 0 {
+					fmt.Println("Unstaging is not available with --paths")
+					continue
+				}
+
+				resetArgs := []string{"reset", "-q", base, "--", changedFiles[fileNum-1]}
+				if action == "p" {
+					resetArgs = []string{"reset", "-p", base, "--", changedFiles[fileNum-1]}
+				}
+				resetCmd := exec.Command("git", resetArgs...)
+				resetCmd.Stdin = os.Stdin
+				resetCmd.Stdout = os.Stdout
+				resetCmd.Stderr = os.Stderr
+				if err := resetCmd.Run(); err != nil {
+					return fmt.Errorf("failed to unstage %s: %w", changedFiles[fileNum-1], err)
+				}
+
+				// Re-read the index so the diff sent to the LLM matches what is committed
+				diffOutput, changedFiles, err = stagedChanges(diffArgs, pathspec)
+				if err != nil {
+					return err
+				}
+				if len(changedFiles) == 0 {
+					return fmt.Errorf("no changes to commit")
+				}
+				continue
+			}
+
 			// Show diff for selected file
-			fileDiffCmd := exec.Command("git", "diff", "--cached", "--", changedFiles[fileNum-1])
+			fileDiffCmd := exec.Command("git", append(diffArgs, "--", changedFiles[fileNum-1])...)
 			fileDiffOutput, err := fileDiffCmd.Output()
 			if err != nil {
 				return fmt.Errorf("failed to get file diff: %w", err)
@@ -97,59 +243,114 @@ You can review the changes before committing.`,
 			fmt.Println(string(fileDiffOutput))
 		}
 
-		// Get commit message
+		// Get commit message; an empty message when amending keeps the existing one
 		var commitMsg string
 		if customMsg != "" {
 			commitMsg = customMsg
+		} else if amend && noLLM {
+			commitMsg = ""
 		} else if !noLLM {
-			// Generate commit message using OpenAI
+			// Generate commit message using OpenAI, or from the file names
+			// alone when there is no API key
 			apiKey := os.Getenv("OPENAI_API_KEY")
 			if apiKey == "" {
-				return fmt.Errorf("OPENAI_API_KEY environment variable is required for LLM commit messages")
-			}
-
-			commitMsg, err = generateCommitMessageWithLLM(string(diffOutput), apiKey)
-			if err != nil {
-				return fmt.Errorf("failed to generate commit message: %w", err)
+				fmt.Println("\nOPENAI_API_KEY is not set; using the offline commit message generator.")
+				commitMsg = git.OfflineCommitMessage(changedFiles, prefix)
+			} else {
+				stream := llmStream(cmd)
+				if stream != nil {
+					fmt.Println("\nGenerating commit message:")
+				}
+				llm, err := llmSettings(cmd)
+				if err != nil {
+					return err
+				}
+				// Templates see HEAD when detached, and no branch before the first commit
+				branch, _ := (&git.Repository{Path: "."}).CurrentBranch()
+				data := commitPromptData{Diff: diffOutput, Files: changedFiles, Branch: branch, Prefix: prefix, Long: long}
+				commitMsg, err = generateCommitMessageWithLLM(data, llm.CommitPromptPath, newLLMClient(cmd, apiKey), stream)
+				if err != nil {
+					return fmt.Errorf("failed to generate commit message: %w", err)
+				}
+				if !strings.HasPrefix(commitMsg, prefix) {
+					return fmt.Errorf("generated commit message %q does not start with %q; pass --message instead", commitMsg, prefix)
+				}
 			}
 
-			// Show proposed commit message
-			fmt.Println("\nProposed commit message:")
-			fmt.Println(commitMsg)
-			fmt.Println("\nDo you want to use this commit message? (y/N): ")
-
-			// Get user confirmation
-			response, err := reader.ReadString('\n')
+			var ok bool
+			commitMsg, ok, err = reviewCommitMessage(cmd, commitMsg)
 			if err != nil {
-				return fmt.Errorf("failed to read user input: %w", err)
+				return err
 			}
-
-			response = strings.TrimSpace(strings.ToLower(response))
-			if response != "y" && response != "yes" {
+			if !ok {
 				fmt.Println("Aborted.")
 				return nil
 			}
+		} else if assumeYes(cmd) {
+			return fmt.Errorf("a commit message is required with --yes and --no-llm (use --message)")
 		} else {
 			// Prompt for manual commit message
 			fmt.Print("\nEnter commit message: ")
-			commitMsg, err = reader.ReadString('\n')
+			commitMsg, err = stdin.ReadString('\n')
 			if err != nil {
 				return fmt.Errorf("failed to read commit message: %w", err)
 			}
 			commitMsg = strings.TrimSpace(commitMsg)
 		}
 
+		// Prefix messages written by the user unless they already carry it
+		if commitMsg != "" && !strings.HasPrefix(commitMsg, prefix) {
+			commitMsg = prefix + commitMsg
+		}
+
 		// Commit changes
-		commitCmd := exec.Command("git", "commit", "-m", commitMsg)
+		var commitArgs []string
+		if signingKey != "" {
+			commitArgs = append(commitArgs, "-c", "user.signingkey="+signingKey)
+		}
+		commitArgs = append(commitArgs, "commit")
+		if sign {
+			commitArgs = append(commitArgs, "-S")
+		}
+		if amend {
+			commitArgs = append(commitArgs, "--amend")
+		}
+		if commitMsg != "" {
+			commitArgs = append(commitArgs, git.MessageArgs(commitMsg)...)
+		} else {
+			commitArgs = append(commitArgs, "--no-edit")
+		}
+		commitArgs = append(commitArgs, pathspec...)
+		var commitErr bytes.Buffer
+		commitCmd := exec.Command("git", commitArgs...)
 		commitCmd.Stdout = os.Stdout
-		commitCmd.Stderr = os.Stderr
+		commitCmd.Stderr = io.MultiWriter(os.Stderr, &commitErr)
 		if err := commitCmd.Run(); err != nil {
+			if hint := signingErrorHint(commitErr.String()); hint != "" {
+				return fmt.Errorf("failed to sign commit: %s", hint)
+			}
 			return fmt.Errorf("failed to commit changes: %w", err)
 		}
 
+		// An amended commit that was already pushed can only be replaced by force
+		if amend && !noPush {
+			fmt.Println("\nWarning: --amend rewrote the last commit; pushing it needs a force push (--force-with-lease).")
+			if !confirm(cmd, "Force-push the amended commit?", false) {
+				fmt.Println("Commit amended locally; not pushed.")
+				return nil
+			}
+		}
+
 		// Push changes if not disabled
 		if !noPush {
-			pushCmd := exec.Command("git", "push")
+			pushArgs := []string{"push"}
+			if amend {
+				pushArgs = append(pushArgs, "--force-with-lease")
+			}
+			if setUpstream {
+				pushArgs = append(pushArgs, "--set-upstream", "origin", "HEAD")
+			}
+			pushCmd := exec.Command("git", pushArgs...)
 			pushCmd.Stdout = os.Stdout
 			pushCmd.Stderr = os.Stderr
 			if err := pushCmd.Run(); err != nil {
@@ -164,6 +365,76 @@ You can review the changes before committing.`,
 	},
 }
 
+// reviewCommitMessage shows a proposed commit message and asks whether to use
+// it, offering to edit it in the user's editor first. It returns the message
+// to commit and false when the commit should be aborted.
+func reviewCommitMessage(cmd *cobra.Command, msg string) (string, bool, error) {
+	for {
+		fmt.Println("\nProposed commit message:")
+		fmt.Println(msg)
+		fmt.Println()
+
+		if assumeYes(cmd) {
+			fmt.Println("Use this commit message? (y/N/e): y (--yes)")
+			return msg, true, nil
+		}
+
+		fmt.Print("Use this commit message? (y/N/e to edit): ")
+		resp, err := stdin.ReadString('\n')
+		if err != nil && resp == "" {
+			return "", false, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(resp)) {
+		case "y", "yes":
+			return msg, true, nil
+		case "e", "edit":
+			edited, err := editMessage(msg, "Edit the commit message. Lines starting with '#' are ignored,\nand an empty message aborts the commit.")
+			if err != nil {
+				return "", false, err
+			}
+			if edited == "" {
+				fmt.Println("Empty commit message.")
+				return "", false, nil
+			}
+			msg = edited
+		default:
+			return "", false, nil
+		}
+	}
+}
+
+// emptyTree is the hash of git's empty tree, what a root commit is built on
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// amendBase returns what the last commit is built on, its parent or, for a
+// repository's root commit, the empty tree
+func amendBase() string {
+	if err := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD^").Run(); err != nil {
+		return emptyTree
+	}
+	return "HEAD^"
+}
+
+// stagedChanges returns the diff of the staged changes and the files it touches
+func stagedChanges(diffArgs, pathspec []string) (string, []string, error) {
+	diffOutput, err := exec.Command("git", append(diffArgs, pathspec...)...).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get staged changes: %w", err)
+	}
+
+	filesOutput, err := exec.Command("git", append(append(diffArgs, "--name-only"), pathspec...)...).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get changed files: %w", err)
+	}
+
+	var changedFiles []string
+	if files := strings.TrimSpace(string(filesOutput)); files != "" {
+		changedFiles = strings.Split(files, "\n")
+	}
+	return string(diffOutput), changedFiles, nil
+}
+
 var gitReviewCmd = &cobra.Command{
 	Use:   "review",
 	Short: "Analyze PR comments and provide LLM-powered suggestions",
@@ -172,8 +443,33 @@ This command will:
 1. Fetch PR comments from the current repository
 2. Analyze comments using LLM
 3. Provide suggestions for addressing each comment
-4. Help generate responses to reviewers`,
+4. Help generate responses to reviewers
+
+With --post, a reply is drafted for each inline review comment thread instead,
+and each one you approve is posted to the thread.
+
+Both GitHub pull requests (through gh) and GitLab merge requests (through glab)
+are supported. The host is detected from the origin remote; use --provider for
+self-hosted GitLab instances whose host name doesn't contain "gitlab".
+
+On long-lived PRs, --since limits the review to comments made after a time,
+given as a timestamp (2024-05-01 or RFC 3339) or a duration ago (48h).
+--since-last-run picks up where the previous review of the PR with that flag
+left off.
+
+Example:
+  dev-manager git-ops review --pr 42
+  dev-manager git-ops review --pr 42 --post
+  dev-manager git-ops review --pr 42 --since 48h
+  dev-manager git-ops review --pr 42 --since-last-run
+  dev-manager git-ops review --pr 17 --provider gitlab`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		providerName, _ := cmd.Flags().GetString("provider")
+		provider, err := newReviewProvider(providerName)
+		if err != nil {
+			return err
+		}
+
 		// Get PR number from flag
 		prNumber, _ := cmd.Flags().GetInt("pr")
 		if prNumber == 0 {
@@ -185,32 +481,21 @@ This command will:
 			}
 			branchName := strings.TrimSpace(string(branchOutput))
 
-			// Search for PRs associated with current branch and user
-			searchCmd := exec.Command("gh", "search", "prs", "--json", "number,title", "--jq", ".[0]", fmt.Sprintf("head:%s", branchName), "is:open")
-			searchOutput, err := searchCmd.Output()
-			if err == nil && len(searchOutput) > 0 {
-				var pr struct {
-					Number int    `json:"number"`
-					Title  string `json:"title"`
-				}
-				if err := json.Unmarshal(searchOutput, &pr); err == nil {
-					fmt.Printf("Found PR #%d: %s\nUse this PR? [y/N]: ", pr.Number, pr.Title)
-					reader := bufio.NewReader(os.Stdin)
-					response, err := reader.ReadString('\n')
-					if err != nil {
-						return fmt.Errorf("failed to read response: %w", err)
-					}
-					if strings.ToLower(strings.TrimSpace(response)) == "y" {
-						prNumber = pr.Number
-					}
+			// Search for PRs associated with current branch
+			if number, title, err := provider.FindPR(branchName); err == nil && number != 0 {
+				fmt.Printf("Found PR #%d: %s\n", number, title)
+				if confirm(cmd, "Use this PR?", false) {
+					prNumber = number
 				}
 			}
 
 			// If no PR number yet, prompt user
 			if prNumber == 0 {
+				if assumeYes(cmd) {
+					return fmt.Errorf("no PR found for the current branch (use --pr)")
+				}
 				fmt.Print("Enter PR number: ")
-				reader := bufio.NewReader(os.Stdin)
-				prStr, err := reader.ReadString('\n')
+				prStr, err := stdin.ReadString('\n')
 				if err != nil {
 					return fmt.Errorf("failed to read PR number: %w", err)
 				}
@@ -221,17 +506,15 @@ This command will:
 			}
 		}
 
-		// Validate PR exists
-		validateCmd := exec.Command("gh", "pr", "view", fmt.Sprintf("%d", prNumber), "--json", "number")
-		if err := validateCmd.Run(); err != nil {
-			return fmt.Errorf("PR #%d not found or not accessible: %w", prNumber, err)
+		since, recordRun, err := reviewSince(cmd, provider, prNumber)
+		if err != nil {
+			return err
 		}
 
-		// Get PR details including comments, diff, and metadata
-		prCmd := exec.Command("gh", "pr", "view", fmt.Sprintf("%d", prNumber), "--json", "title,body,comments,reviewComments,commits,files")
-		prOutput, err := prCmd.Output()
+		// Get PR details including comments and changed files
+		pr, err := provider.PR(prNumber)
 		if err != nil {
-			return fmt.Errorf("failed to get PR details: %w", err)
+			return err
 		}
 
 		// Generate suggestions using OpenAI
@@ -240,16 +523,48 @@ This command will:
 			return fmt.Errorf("OPENAI_API_KEY environment variable is required")
 		}
 
-		suggestions, err := generatePRReviewSuggestions(string(prOutput), apiKey)
+		if post, _ := cmd.Flags().GetBool("post"); post {
+			if err := postReviewReplies(cmd, provider, prNumber, pr.Title, since, newLLMClient(cmd, apiKey)); err != nil {
+				return err
+			}
+			return recordRun()
+		}
+
+		// Review comments are fetched separately since they carry the file
+		// and line they were left on, which is used to pick the matching diff hunk
+		reviewComments, err := provider.ReviewComments(prNumber)
 		if err != nil {
-			return fmt.Errorf("failed to generate suggestions: %w", err)
+			return err
+		}
+		if !since.IsZero() && len(prCommentsSince(pr.Comments, since)) == 0 && len(reviewCommentsSince(reviewComments, since)) == 0 {
+			fmt.Printf("No new comments since %s.\n", since.Local().Format(time.RFC1123))
+			return recordRun()
+		}
+		diff, err := provider.Diff(prNumber)
+		if err != nil {
+			return err
 		}
 
-		// Print suggestions
-		fmt.Println("\nPR Review Suggestions:")
-		fmt.Println(suggestions)
+		llm, err := llmSettings(cmd)
+		if err != nil {
+			return err
+		}
 
-		return nil
+		// Suggestions are printed as they arrive when streamed, and at the end otherwise
+		stream := llmStream(cmd)
+		if stream != nil {
+			fmt.Println("\nPR Review Suggestions:")
+		}
+		suggestions, err := generatePRReviewSuggestions(pr, reviewComments, diff, since, llm.ReviewPromptPath, newLLMClient(cmd, apiKey), stream)
+		if err != nil {
+			return fmt.Errorf("failed to generate suggestions: %w", err)
+		}
+		if stream == nil {
+			fmt.Println("\nPR Review Suggestions:")
+			fmt.Println(suggestions)
+		}
+
+		return recordRun()
 	},
 }
 
@@ -257,26 +572,87 @@ func init() {
 	gitOpsCmd.AddCommand(gitCommitCmd)
 	gitOpsCmd.AddCommand(gitReviewCmd)
 
+	gitOpsCmd.PersistentFlags().Int("llm-retries", 3, "Times to retry LLM requests rejected by rate limiting")
+	gitOpsCmd.PersistentFlags().Bool("no-stream", false, "Print LLM responses once complete instead of as they are generated")
+
 	// Add flags
 	gitCommitCmd.Flags().StringP("message", "m", "", "Custom commit message")
 	gitCommitCmd.Flags().Bool("no-push", false, "Don't push after commit")
 	gitCommitCmd.Flags().Bool("no-llm", false, "Don't use LLM for commit message")
+	gitCommitCmd.Flags().String("new-branch", "", "Create and switch to this branch before committing")
+	gitCommitCmd.Flags().String("branch-from", "", "Ref to start --new-branch from (defaults to HEAD)")
+	gitCommitCmd.Flags().Bool("switch-existing", false, "Switch to --new-branch if it already exists instead of failing")
+	gitCommitCmd.Flags().Bool("set-upstream", false, "Set the upstream of the current branch when pushing")
+	gitCommitCmd.Flags().StringArray("paths", nil, "Only stage and commit these pathspecs (repeatable)")
+	gitCommitCmd.Flags().Bool("staged-only", false, "Commit what is already staged without running git add")
+	gitCommitCmd.Flags().Bool("sign", false, "Sign the commit (git commit -S)")
+	gitCommitCmd.Flags().String("signing-key", "", "Key to sign the commit with (sets user.signingkey; implies --sign)")
+	gitCommitCmd.Flags().String("gpg-key", "", "Alias for --signing-key")
+	gitCommitCmd.Flags().String("type", "", "Conventional commit type (feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert)")
+	gitCommitCmd.Flags().String("scope", "", "Conventional commit scope, used with --type")
+	gitCommitCmd.Flags().Bool("long", false, "Have the LLM write a message body and footer, not just a subject")
+	gitCommitCmd.Flags().Bool("body", false, "Alias for --long")
+	gitCommitCmd.Flags().Bool("amend", false, "Amend the last commit instead of creating a new one (force-pushes after confirmation)")
 
 	gitReviewCmd.Flags().IntP("pr", "p", 0, "PR number (optional, will try to detect from branch name)")
+	gitReviewCmd.Flags().Bool("post", false, "Draft a reply to each review comment thread and post the ones you approve")
+	gitReviewCmd.Flags().String("since", "", "Only review comments made after this time (2024-05-01, RFC 3339, or a duration ago such as 48h)")
+	gitReviewCmd.Flags().Bool("since-last-run", false, "Only review comments made since the last review of this PR with this flag")
+	gitReviewCmd.MarkFlagsMutuallyExclusive("since", "since-last-run")
+	gitReviewCmd.Flags().String("provider", "", "Code host: github or gitlab (detected from the origin remote by default)")
+}
+
+// signingErrorHint explains common commit signing failures found in git's
+// error output, or returns "" when the failure is unrelated to signing
+func signingErrorHint(stderr string) string {
+	switch {
+	case strings.Contains(stderr, "gpg failed to sign the data"):
+		return "gpg could not sign the commit. Check that gpg-agent is running and can prompt " +
+			"for your passphrase (export GPG_TTY=$(tty)), and that the signing key exists (gpg --list-secret-keys)"
+	case strings.Contains(stderr, "Couldn't load public key"), strings.Contains(stderr, "ssh-keygen"):
+		return "ssh could not sign the commit. With gpg.format=ssh, user.signingkey must point to " +
+			"a public key whose private key is loaded in ssh-agent (dev-manager ssh add-agent)"
+	default:
+		return ""
+	}
 }
 
-// generateCommitMessageWithLLM uses OpenAI to generate a commit message based on the changes
-func generateCommitMessageWithLLM(diff, apiKey string) (string, error) {
-	client := openai.NewClient(apiKey)
+// generateCommitMessageWithLLM uses OpenAI to generate a commit message based on the changes.
+// A non-empty data.Prefix (e.g. "feat(api): ") is required at the start of the message.
+// A non-empty promptPath names a template replacing the built-in prompt.
+// With a non-nil stream the message is written to it as it is generated.
+func generateCommitMessageWithLLM(data commitPromptData, promptPath string, client *openai.Client, stream io.Writer) (string, error) {
+	format := "Follow conventional commit format (e.g., feat:, fix:, chore:, etc.)."
+	if data.Prefix != "" {
+		format = fmt.Sprintf("The message must start with exactly %q.", data.Prefix)
+	}
 
 	// Prepare the prompt
+	length := "Keep the message under 72 characters."
+	maxTokens := 100
+	if data.Long {
+		length = `Write a subject line under 72 characters, then a blank line, then a body
+wrapped at 72 characters explaining what changed and why. If the changes break
+compatibility, end with a blank line and a "BREAKING CHANGE: <description>" footer.`
+		maxTokens = 500
+	}
 	prompt := fmt.Sprintf(`Generate a concise and descriptive commit message for the following changes.
-Follow conventional commit format (e.g., feat:, fix:, chore:, etc.).
+%s
 Focus on the main changes and their impact.
-Keep the message under 72 characters.
+%s
 
 Changes:
-%s`, diff)
+%s`, format, length, data.Diff)
+	system := "You are a helpful assistant that generates commit messages. Be concise and follow conventional commit format."
+
+	if promptPath != "" {
+		var err error
+		if system, prompt, err = renderPrompt(promptPath, data, system); err != nil {
+			return "", err
+		}
+		// Team conventions may call for a body whether or not --long is set
+		maxTokens = 500
+	}
 
 	// Create the completion request
 	req := openai.ChatCompletionRequest{
@@ -284,53 +660,38 @@ Changes:
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are a helpful assistant that generates commit messages. Be concise and follow conventional commit format.",
+				Content: system,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
 				Content: prompt,
 			},
 		},
-		MaxTokens:   100,
+		MaxTokens:   maxTokens,
 		Temperature: 0.7,
 	}
 
 	// Get the completion
-	resp, err := client.CreateChatCompletion(context.Background(), req)
-	if err != nil {
-		return "", fmt.Errorf("failed to get completion: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
-	}
-
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	return completeChat(context.Background(), client, req, stream)
 }
 
-// generatePRReviewSuggestions uses OpenAI to generate suggestions based on PR comments
-func generatePRReviewSuggestions(prData, apiKey string) (string, error) {
-	client := openai.NewClient(apiKey)
-
-	// Parse PR data
-	var pr struct {
-		Title    string `json:"title"`
-		Body     string `json:"body"`
-		Comments []struct {
-			Body string `json:"body"`
-		} `json:"comments"`
-		ReviewComments []struct {
-			Body string `json:"body"`
-		} `json:"reviewComments"`
-		Files []struct {
-			Path      string `json:"path"`
-			Additions int    `json:"additions"`
-			Deletions int    `json:"deletions"`
-			Changes   int    `json:"changes"`
-		} `json:"files"`
+// generatePRReviewSuggestions uses OpenAI to generate suggestions based on PR
+// comments made after since, showing each review comment alongside the diff
+// hunk it was left on. A non-empty promptPath names a template replacing the
+// built-in prompt. With a non-nil stream the suggestions are written to it as
+// they are generated.
+func generatePRReviewSuggestions(pr *pullRequest, reviewComments []reviewComment, diff string, since time.Time, promptPath string, client *openai.Client, stream io.Writer) (string, error) {
+	data := reviewPromptData{
+		Title:          pr.Title,
+		Body:           pr.Body,
+		Branch:         pr.Branch,
+		Comments:       formatComments(prCommentsSince(pr.Comments, since)),
+		ReviewComments: formatReviewComments(reviewCommentsSince(reviewComments, since), parseDiffHunks(diff), maxReviewDiffBytes),
+		FileStats:      formatFiles(pr.Files),
+		Diff:           diff,
 	}
-	if err := json.Unmarshal([]byte(prData), &pr); err != nil {
-		return "", fmt.Errorf("failed to parse PR data: %w", err)
+	for _, f := range pr.Files {
+		data.Files = append(data.Files, f.Path)
 	}
 
 	// Prepare the prompt
@@ -352,11 +713,19 @@ PR Review Comments:
 
 Changed Files:
 %s`,
-		pr.Title,
-		pr.Body,
-		formatComments(pr.Comments),
-		formatComments(pr.ReviewComments),
-		formatFiles(pr.Files))
+		data.Title,
+		data.Body,
+		data.Comments,
+		data.ReviewComments,
+		data.FileStats)
+	system := "You are a helpful assistant that analyzes PR comments and provides actionable suggestions. Be specific and practical in your recommendations."
+
+	if promptPath != "" {
+		var err error
+		if system, prompt, err = renderPrompt(promptPath, data, system); err != nil {
+			return "", err
+		}
+	}
 
 	// Create the completion request
 	req := openai.ChatCompletionRequest{
@@ -364,7 +733,7 @@ Changed Files:
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are a helpful assistant that analyzes PR comments and provides actionable suggestions. Be specific and practical in your recommendations.",
+				Content: system,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
@@ -376,22 +745,11 @@ Changed Files:
 	}
 
 	// Get the completion
-	resp, err := client.CreateChatCompletion(context.Background(), req)
-	if err != nil {
-		return "", fmt.Errorf("failed to get completion: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
-	}
-
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	return completeChat(context.Background(), client, req, stream)
 }
 
 // formatComments formats a list of comments into a readable string
-func formatComments(comments []struct {
-	Body string `json:"body"`
-}) string {
+func formatComments(comments []prComment) string {
 	var result strings.Builder
 	for i, comment := range comments {
 		result.WriteString(fmt.Sprintf("Comment %d:\n%s\n\n", i+1, comment.Body))
@@ -400,12 +758,7 @@ func formatComments(comments []struct {
 }
 
 // formatFiles formats a list of changed files into a readable string
-func formatFiles(files []struct {
-	Path      string `json:"path"`
-	Additions int    `json:"additions"`
-	Deletions int    `json:"deletions"`
-	Changes   int    `json:"changes"`
-}) string {
+func formatFiles(files []prFile) string {
 	var result strings.Builder
 	for _, file := range files {
 		result.WriteString(fmt.Sprintf("%s: +%d -%d (%d changes)\n",
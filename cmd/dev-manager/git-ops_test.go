@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"dev-manager/internal/testutil/mockgit"
+)
+
+func TestGitCommitNewBranch(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	notFound := map[string]mockgit.Config{
+		"rev-parse": {ExitCode: 1},
+		"diff":      {Output: "main.go\n"},
+	}
+	found := map[string]mockgit.Config{
+		"diff": {Output: "main.go\n"},
+	}
+	branchExists := []string{"-C", ".", "rev-parse", "--verify", "--quiet", "refs/heads/feature"}
+	commit := [][]string{
+		{"add", "."},
+		{"diff", "--cached"},
+		{"diff", "--cached", "--name-only"},
+		{"commit", "-m", "fix typo"},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		commands map[string]mockgit.Config
+		wantErr  bool
+		want     [][]string
+	}{
+		{
+			name:     "new branch from HEAD",
+			args:     []string{"--new-branch", "feature"},
+			commands: notFound,
+			want:     append([][]string{branchExists, {"-C", ".", "checkout", "-b", "feature"}}, commit...),
+		},
+		{
+			name:     "new branch from a ref",
+			args:     []string{"--new-branch", "feature", "--branch-from", "origin/main"},
+			commands: notFound,
+			want:     append([][]string{branchExists, {"-C", ".", "checkout", "-b", "feature", "origin/main"}}, commit...),
+		},
+		{
+			name:     "existing branch",
+			args:     []string{"--new-branch", "feature", "--switch-existing"},
+			commands: found,
+			want:     append([][]string{branchExists, {"-C", ".", "checkout", "feature"}}, commit...),
+		},
+		{
+			name:     "existing branch without --switch-existing",
+			args:     []string{"--new-branch", "feature"},
+			commands: found,
+			wantErr:  true,
+			want:     [][]string{branchExists},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, mockgit.Config{Commands: tt.commands})
+			mock.Reset(t)
+			t.Cleanup(func() {
+				resetFlags(t, "new-branch", "branch-from", "switch-existing", "message", "no-llm", "no-push", "yes")
+			})
+
+			rootCmd.SetArgs(append([]string{"git-ops", "commit", "--message", "fix typo", "--no-llm", "--no-push", "--yes"}, tt.args...))
+			if err := rootCmd.Execute(); (err != nil) != tt.wantErr {
+				t.Fatalf("git-ops commit error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// The branch is switched before anything is staged or committed,
+			// so the commit lands on it
+			if got := mock.Invocations(t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("git invocations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// resetFlags restores flags of git-ops commit to their defaults, since
+// cobra keeps the values parsed by an earlier Execute
+func resetFlags(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		flag := gitCommitCmd.Flags().Lookup(name)
+		if err := flag.Value.Set(flag.DefValue); err != nil {
+			t.Fatalf("failed to reset --%s: %v", name, err)
+		}
+		flag.Changed = false
+	}
+}
+
+func TestGitCommitAmend(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name     string
+		commands map[string]mockgit.Config
+		base     string
+	}{
+		{
+			name:     "amends onto the parent",
+			commands: map[string]mockgit.Config{"diff": {Output: "main.go\n"}},
+			base:     "HEAD^",
+		},
+		{
+			name: "root commit amends onto the empty tree",
+			commands: map[string]mockgit.Config{
+				"rev-parse": {ExitCode: 1},
+				"diff":      {Output: "main.go\n"},
+			},
+			base: emptyTree,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, mockgit.Config{Commands: tt.commands})
+			mock.Reset(t)
+			t.Cleanup(func() { resetFlags(t, "amend", "message", "no-llm", "no-push", "yes") })
+
+			rootCmd.SetArgs([]string{"git-ops", "commit", "--amend", "--message", "fix typo", "--no-llm", "--no-push", "--yes"})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("git-ops commit --amend error = %v", err)
+			}
+
+			want := [][]string{
+				{"add", "."},
+				{"rev-parse", "--verify", "--quiet", "HEAD^"},
+				{"diff", "--cached", tt.base},
+				{"diff", "--cached", tt.base, "--name-only"},
+				{"commit", "--amend", "-m", "fix typo"},
+			}
+			if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+				t.Errorf("git invocations = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/spf13/cobra"
+)
+
+// llmStream returns where LLM responses should be streamed as they arrive:
+// stdout when it is a terminal, or nil when output is redirected or
+// --no-stream is set, in which case responses arrive all at once
+func llmStream(cmd *cobra.Command) io.Writer {
+	if noStream, _ := cmd.Flags().GetBool("no-stream"); noStream || !stdoutIsTerminal() {
+		return nil
+	}
+	return os.Stdout
+}
+
+// Backoff between retries of rate-limited LLM requests that don't say when
+// to retry: doubling from llmRetryBase up to llmRetryMax
+const (
+	llmRetryBase = time.Second
+	llmRetryMax  = 30 * time.Second
+)
+
+// llmRetryAfterMax caps the delay a server asks for in Retry-After, so that a
+// server asking for hours doesn't leave the command hanging
+const llmRetryAfterMax = time.Minute
+
+// newLLMClient returns an OpenAI client that retries requests rejected with
+// 429 Too Many Requests up to --llm-retries times
+func newLLMClient(cmd *cobra.Command, apiKey string) *openai.Client {
+	retries, _ := cmd.Flags().GetInt("llm-retries")
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.HTTPClient = &retryingDoer{client: http.DefaultClient, retries: max(retries, 0)}
+	return openai.NewClientWithConfig(cfg)
+}
+
+// retryingDoer sends requests with client, retrying rate-limited ones with
+// exponential backoff, or after the delay the server asks for in Retry-After
+type retryingDoer struct {
+	client  *http.Client
+	retries int
+}
+
+func (d *retryingDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := d.client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= d.retries {
+			return resp, err
+		}
+
+		// An exhausted quota is reported as a 429 too, but waiting won't help
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil || bytes.Contains(body, []byte("insufficient_quota")) {
+			return resp, nil
+		}
+
+		wait := retryDelay(resp.Header.Get("Retry-After"), attempt, time.Now())
+		fmt.Fprintf(os.Stderr, "Rate limited by OpenAI; retrying in %s (%d/%d)\n", wait, attempt+1, d.retries)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retry attempt+1: the
+// Retry-After value, given in seconds or as a date and capped at
+// llmRetryAfterMax, or else an exponential backoff
+func retryDelay(retryAfter string, attempt int, now time.Time) time.Duration {
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		return min(time.Duration(secs)*time.Second, llmRetryAfterMax)
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil {
+		return min(max(t.Sub(now), 0), llmRetryAfterMax)
+	}
+	return min(llmRetryBase<<attempt, llmRetryMax)
+}
+
+// rateLimitError explains an error from a request that was still rate
+// limited after its retries, and returns other errors unchanged
+func rateLimitError(err error) error {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	if (errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusTooManyRequests) ||
+		(errors.As(err, &reqErr) && reqErr.HTTPStatusCode == http.StatusTooManyRequests) {
+		return fmt.Errorf("OpenAI rate limit still exceeded after retrying; wait a minute or raise --llm-retries: %w", err)
+	}
+	return err
+}
+
+// completeChat sends a chat completion request and returns the response,
+// trimmed. With a non-nil stream the response is written to it token by
+// token while it is generated.
+func completeChat(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest, stream io.Writer) (string, error) {
+	if stream == nil {
+		resp, err := client.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to get completion: %w", rateLimitError(err))
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("no completion choices returned")
+		}
+		return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	}
+
+	req.Stream = true
+	s, err := client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get completion: %w", rateLimitError(err))
+	}
+	defer s.Close()
+
+	var content strings.Builder
+	for {
+		resp, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get completion: %w", rateLimitError(err))
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		content.WriteString(delta)
+		fmt.Fprint(stream, delta)
+	}
+	fmt.Fprintln(stream)
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("no completion choices returned")
+	}
+	return strings.TrimSpace(content.String()), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    int
+		want       time.Duration
+	}{
+		{name: "seconds", retryAfter: "5", want: 5 * time.Second},
+		{name: "date", retryAfter: now.Add(10 * time.Second).Format(http.TimeFormat), want: 10 * time.Second},
+		{name: "date in the past", retryAfter: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{name: "seconds capped", retryAfter: "86400", want: llmRetryAfterMax},
+		{name: "date capped", retryAfter: now.Add(24 * time.Hour).Format(http.TimeFormat), want: llmRetryAfterMax},
+		{name: "backoff", attempt: 2, want: 4 * llmRetryBase},
+		{name: "backoff capped", attempt: 10, want: llmRetryMax},
+		{name: "invalid value backs off", retryAfter: "soon", want: llmRetryBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.retryAfter, tt.attempt, now); got != tt.want {
+				t.Errorf("retryDelay(%q, %d) = %s, want %s", tt.retryAfter, tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
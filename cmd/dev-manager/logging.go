@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// setupLogging installs the default slog logger used by the pkg/ packages,
+// at the level chosen by --verbose or --quiet. Log records go to stderr so
+// they never mix with command output or prompts on stdout.
+func setupLogging(cmd *cobra.Command) error {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if verbose && quiet {
+		return fmt.Errorf("--verbose and --quiet cannot be used together")
+	}
+
+	// Progress is reported by the commands themselves, so package logs are
+	// limited to warnings unless asked for
+	level := slog.LevelWarn
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelError
+	}
+
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Timestamps are noise for an interactive CLI
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	slog.SetDefault(slog.New(handler))
+
+	// SetDefault also redirects the log package into the handler at info
+	// level, which would hide log.Fatal messages; keep it writing to stderr
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+
+	return nil
+}
@@ -14,53 +14,27 @@ var rootCmd = &cobra.Command{
 - Managing git repositories
 - Syncing tool configurations (nvim, tmux, zsh)
 - Keeping repositories up to date`,
-}
-
-var toolsCmd = &cobra.Command{
-	Use:   "tools",
-	Short: "Manage tool configurations",
-	Long:  `Commands for managing tool configurations (nvim, tmux, zsh).`,
-}
-
-var nvimCmd = &cobra.Command{
-	Use:   "nvim",
-	Short: "Manage nvim configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Managing nvim configuration...")
-	},
-}
-
-var tmuxCmd = &cobra.Command{
-	Use:   "tmux",
-	Short: "Manage tmux configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Managing tmux configuration...")
-	},
-}
-
-var zshCmd = &cobra.Command{
-	Use:   "zsh",
-	Short: "Manage zsh configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Managing zsh configuration...")
+	// Execute reports errors itself, once
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags parsed fine, so a failure from here on is not a usage problem
+		cmd.SilenceUsage = true
+		return setupLogging(cmd)
 	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringP("file", "f", "", "Path to the configuration file")
-
-	// Add tools commands
-	rootCmd.AddCommand(toolsCmd)
-	toolsCmd.AddCommand(nvimCmd)
-	toolsCmd.AddCommand(tmuxCmd)
-	toolsCmd.AddCommand(zshCmd)
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Answer yes to confirmation prompts")
+	rootCmd.PersistentFlags().BoolP("verbose", "V", false, "Log the underlying git and download operations")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only log errors")
 
 	// Add git operations commands
 	rootCmd.AddCommand(gitOpsCmd)
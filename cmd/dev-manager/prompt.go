@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// stdin is shared by all prompts so buffered input isn't lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// assumeYes reports whether the global --yes flag is set
+func assumeYes(cmd *cobra.Command) bool {
+	yes, _ := cmd.Flags().GetBool("yes")
+	return yes
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal rather
+// than a pipe or file, e.g. when run from a script
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// The null device is a character device too, but nobody can answer on it
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than being
+// redirected to a file or pipe
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question; an empty answer selects def. With the
+// global --yes flag the question is answered yes without prompting.
+func confirm(cmd *cobra.Command, question string, def bool) bool {
+	hint := "(y/N)"
+	if def {
+		hint = "(Y/n)"
+	}
+
+	if assumeYes(cmd) {
+		fmt.Printf("%s %s: y (--yes)\n", question, hint)
+		return true
+	}
+
+	fmt.Printf("%s %s: ", question, hint)
+	resp, err := stdin.ReadString('\n')
+	if err != nil && resp == "" {
+		return def
+	}
+
+	switch strings.ToLower(strings.TrimSpace(resp)) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
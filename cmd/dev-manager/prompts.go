@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"dev-manager/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// commitPromptData is available to commit message prompt templates
+type commitPromptData struct {
+	Diff   string
+	Files  []string
+	Branch string
+	// Prefix is the conventional commit prefix the message must start with,
+	// e.g. "feat(api): ", or empty
+	Prefix string
+	// Long is set when a body and footer were asked for with --long
+	Long bool
+}
+
+// reviewPromptData is available to PR review prompt templates
+type reviewPromptData struct {
+	Title  string
+	Body   string
+	Branch string
+	// Comments and ReviewComments are formatted for the prompt, with each
+	// review comment followed by the diff hunk it was left on
+	Comments       string
+	ReviewComments string
+	Files          []string
+	// FileStats lists the changed files with their added and removed lines
+	FileStats string
+	Diff      string
+}
+
+// llmSettings returns the llm section of the config, with prompt paths
+// resolved. A missing config file leaves everything at the built-in defaults.
+func llmSettings(cmd *cobra.Command) (config.LLM, error) {
+	cfgPath, _ := cmd.Flags().GetString("file")
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		return config.LLM{}, fmt.Errorf("failed to create config manager: %w", err)
+	}
+	if err := mgr.Load(); err != nil {
+		if errors.Is(err, config.ErrConfigNotFound) {
+			return config.LLM{}, nil
+		}
+		return config.LLM{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	llm := mgr.GetConfig().LLM
+	for _, path := range []*string{&llm.CommitPromptPath, &llm.ReviewPromptPath} {
+		if *path == "" {
+			continue
+		}
+		if *path, err = config.ExpandPath(*path); err != nil {
+			return config.LLM{}, err
+		}
+		if !filepath.IsAbs(*path) {
+			*path = filepath.Join(filepath.Dir(mgr.Path()), *path)
+		}
+	}
+	return llm, nil
+}
+
+// renderPrompt executes the prompt template at path with data and returns
+// the system and user messages. The template's output is the user message;
+// the system message stays system unless the template defines a "system"
+// template, e.g. {{define "system"}}...{{end}}.
+func renderPrompt(path string, data any, system string) (string, string, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse prompt template %s: %w", path, err)
+	}
+
+	var user strings.Builder
+	if err := tmpl.Execute(&user, data); err != nil {
+		return "", "", fmt.Errorf("failed to render prompt template %s: %w", path, err)
+	}
+	if t := tmpl.Lookup("system"); t != nil {
+		var sys strings.Builder
+		if err := t.Execute(&sys, data); err != nil {
+			return "", "", fmt.Errorf("failed to render prompt template %s: %w", path, err)
+		}
+		system = sys.String()
+	}
+	return strings.TrimSpace(system), strings.TrimSpace(user.String()), nil
+}
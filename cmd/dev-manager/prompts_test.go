@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dev-manager/pkg/config"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/spf13/cobra"
+)
+
+// writePrompt writes a prompt template to a temporary file and returns its path
+func writePrompt(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeLLM returns a client for a chat completion server answering reply, and
+// the messages of the last request the server received
+func fakeLLM(t *testing.T, reply string) (*openai.Client, *[]openai.ChatCompletionMessage) {
+	t.Helper()
+	var messages []openai.ChatCompletionMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		messages = req.Messages
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply}},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := openai.DefaultConfig("test-key")
+	cfg.BaseURL = srv.URL
+	return openai.NewClientWithConfig(cfg), &messages
+}
+
+func TestRenderPrompt(t *testing.T) {
+	data := commitPromptData{Diff: "+added line", Files: []string{"a.go", "b.go"}, Branch: "main"}
+
+	tests := []struct {
+		name       string
+		template   string
+		wantSystem string
+		wantUser   string
+		wantErr    string
+	}{
+		{
+			name:       "user message",
+			template:   "Branch {{.Branch}}{{range .Files}}, {{.}}{{end}}:\n{{.Diff}}\n",
+			wantSystem: "built-in system",
+			wantUser:   "Branch main, a.go, b.go:\n+added line",
+		},
+		{
+			name:       "system override",
+			template:   `{{define "system"}} Commits on {{.Branch}} {{end}}{{.Diff}}`,
+			wantSystem: "Commits on main",
+			wantUser:   "+added line",
+		},
+		{
+			name:     "parse failure",
+			template: "{{.Diff",
+			wantErr:  "failed to parse prompt template",
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Title}}",
+			wantErr:  "failed to render prompt template",
+		},
+		{
+			name:     "system fails to render",
+			template: `{{define "system"}}{{.Title}}{{end}}{{.Diff}}`,
+			wantErr:  "failed to render prompt template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePrompt(t, tt.template)
+
+			system, user, err := renderPrompt(path, data, "built-in system")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+					t.Fatalf("renderPrompt() error = %v, want %q naming %s", err, tt.wantErr, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderPrompt() error = %v", err)
+			}
+			if system != tt.wantSystem {
+				t.Errorf("renderPrompt() system = %q, want %q", system, tt.wantSystem)
+			}
+			if user != tt.wantUser {
+				t.Errorf("renderPrompt() user = %q, want %q", user, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestRenderPromptMissingFile(t *testing.T) {
+	_, _, err := renderPrompt(filepath.Join(t.TempDir(), "missing.tmpl"), commitPromptData{}, "")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("renderPrompt() error = %v, want one matching fs.ErrNotExist", err)
+	}
+}
+
+func TestLLMSettings(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.GetConfig().LLM = config.LLM{
+		CommitPromptPath: "prompts/commit.tmpl",
+		ReviewPromptPath: "/etc/review.tmpl",
+	}
+	if err := mgr.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("file", cfgPath, "")
+	llm, err := llmSettings(cmd)
+	if err != nil {
+		t.Fatalf("llmSettings() error = %v", err)
+	}
+	if want := filepath.Join(dir, "prompts", "commit.tmpl"); llm.CommitPromptPath != want {
+		t.Errorf("CommitPromptPath = %q, want %q resolved against the config directory", llm.CommitPromptPath, want)
+	}
+	if llm.ReviewPromptPath != "/etc/review.tmpl" {
+		t.Errorf("ReviewPromptPath = %q, want the absolute path unchanged", llm.ReviewPromptPath)
+	}
+
+	// Without a config file the built-in prompts are used
+	cmd = &cobra.Command{}
+	cmd.Flags().String("file", filepath.Join(dir, "missing.yaml"), "")
+	if llm, err = llmSettings(cmd); err != nil {
+		t.Fatalf("llmSettings() without config error = %v", err)
+	}
+	if llm != (config.LLM{}) {
+		t.Errorf("llmSettings() without config = %+v, want the defaults", llm)
+	}
+}
+
+func TestGenerateCommitMessagePrompt(t *testing.T) {
+	data := commitPromptData{Diff: "+added line", Branch: "main", Prefix: "feat(api): "}
+
+	t.Run("built-in", func(t *testing.T) {
+		client, messages := fakeLLM(t, "feat(api): add line\n")
+		msg, err := generateCommitMessageWithLLM(data, "", client, nil)
+		if err != nil {
+			t.Fatalf("generateCommitMessageWithLLM() error = %v", err)
+		}
+		if msg != "feat(api): add line" {
+			t.Errorf("generateCommitMessageWithLLM() = %q, want %q", msg, "feat(api): add line")
+		}
+		if len(*messages) != 2 || !strings.Contains((*messages)[0].Content, "generates commit messages") {
+			t.Fatalf("messages = %+v, want the built-in system message", *messages)
+		}
+		user := (*messages)[1].Content
+		if !strings.Contains(user, `start with exactly "feat(api): "`) || !strings.Contains(user, "+added line") {
+			t.Errorf("user message = %q, want the built-in prompt with the prefix and diff", user)
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		client, messages := fakeLLM(t, "feat(api): add line")
+		path := writePrompt(t, `{{define "system"}}Team rules{{end}}{{.Prefix}}on {{.Branch}}: {{.Diff}}`)
+		if _, err := generateCommitMessageWithLLM(data, path, client, nil); err != nil {
+			t.Fatalf("generateCommitMessageWithLLM() error = %v", err)
+		}
+		if len(*messages) != 2 || (*messages)[0].Content != "Team rules" || (*messages)[1].Content != "feat(api): on main: +added line" {
+			t.Errorf("messages = %+v, want the rendered template", *messages)
+		}
+	})
+
+	t.Run("broken template", func(t *testing.T) {
+		client, messages := fakeLLM(t, "unused")
+		path := writePrompt(t, "{{.Missing}}")
+		if _, err := generateCommitMessageWithLLM(data, path, client, nil); err == nil {
+			t.Fatal("generateCommitMessageWithLLM() with a broken template succeeded")
+		}
+		if *messages != nil {
+			t.Errorf("a request was sent despite the broken template: %+v", *messages)
+		}
+	})
+}
+
+func TestGeneratePRReviewSuggestionsPrompt(t *testing.T) {
+	pr := &pullRequest{Title: "Add widgets", Body: "Widgets everywhere", Branch: "widgets"}
+
+	t.Run("built-in", func(t *testing.T) {
+		client, messages := fakeLLM(t, "suggestions")
+		if _, err := generatePRReviewSuggestions(pr, nil, "", time.Time{}, "", client, nil); err != nil {
+			t.Fatalf("generatePRReviewSuggestions() error = %v", err)
+		}
+		if len(*messages) != 2 || !strings.Contains((*messages)[1].Content, "PR Title: Add widgets") {
+			t.Errorf("messages = %+v, want the built-in prompt", *messages)
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		client, messages := fakeLLM(t, "suggestions")
+		path := writePrompt(t, "Review {{.Title}} on {{.Branch}}")
+		if _, err := generatePRReviewSuggestions(pr, nil, "", time.Time{}, path, client, nil); err != nil {
+			t.Fatalf("generatePRReviewSuggestions() error = %v", err)
+		}
+		if len(*messages) != 2 || (*messages)[1].Content != "Review Add widgets on widgets" {
+			t.Errorf("messages = %+v, want the rendered template", *messages)
+		}
+		if !strings.Contains((*messages)[0].Content, "analyzes PR comments") {
+			t.Errorf("system message = %q, want the built-in one kept", (*messages)[0].Content)
+		}
+	})
+}
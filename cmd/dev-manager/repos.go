@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"dev-manager/pkg/app"
 	"dev-manager/pkg/config"
 	"dev-manager/pkg/git"
 
@@ -19,39 +28,71 @@ var reposCmd = &cobra.Command{
 	Long:  `Commands for managing repositories in your workspace.`,
 }
 
+// defaultBranchTimeout bounds asking a remote for its default branch, so
+// adding a repository while offline doesn't hang
+const defaultBranchTimeout = 30 * time.Second
+
 var repoAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a repository to manage",
 	Long: `Add a new repository to be managed by dev-manager.
 The repository will be cloned to the workspace directory under the specified name.
+For forks, pass the original repository with --fork-of; it is added as the
+"upstream" remote and "repos sync" will rebase onto it.
+Use --ref to pin the repository to a tag or commit; syncing then fetches and
+checks out that ref instead of rebasing onto the branch.
+Use --clone or --clone=false to decide whether to clone right away without
+being asked; when stdin is not a terminal the repository isn't cloned unless
+--clone is given.
+Use --url-scheme to clone GitHub, GitLab and Bitbucket repositories over
+https or ssh regardless of the URL's form.
+Use --remote to clone the repository under a remote name other than "origin";
+syncing then fetches from and rebases onto that remote.
+Without --branch the repository follows the branch its remote checks out by
+default (e.g. master or develop), falling back to the defaults block's branch,
+or main, when the remote can't be asked.
 
 Example:
-  dev-manager repos add --name my-project --url https://github.com/username/my-project.git`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager repos add --name my-project --url https://github.com/username/my-project.git
+  dev-manager repos add --name my-fork --url git@github.com:me/project.git --fork-of https://github.com/org/project.git
+  dev-manager repos add --name tool --url https://github.com/org/tool.git --ref v1.4.0
+  dev-manager repos add --name app --url https://github.com/org/app.git --branch release
+  dev-manager repos add --name my-project --url https://github.com/username/my-project.git --url-scheme ssh`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Show help if no flags are provided
 		if !cmd.Flags().Changed("name") && !cmd.Flags().Changed("url") {
-			cmd.Help()
-			os.Exit(0)
+			return cmd.Help()
 		}
 
 		cfgPath, _ := cmd.Flags().GetString("file")
 		repoName, _ := cmd.Flags().GetString("name")
 		repoURL, _ := cmd.Flags().GetString("url")
+		upstreamURL, _ := cmd.Flags().GetString("fork-of")
+		ref, _ := cmd.Flags().GetString("ref")
+		recurse, _ := cmd.Flags().GetBool("recurse-submodules")
+		urlScheme, _ := cmd.Flags().GetString("url-scheme")
+		remote, _ := cmd.Flags().GetString("remote")
+		if remote == git.DefaultRemote {
+			remote = ""
+		}
 
 		if repoName == "" {
-			log.Fatal("repository name is required (--name)")
+			return fmt.Errorf("repository name is required (--name)")
 		}
 		if repoURL == "" {
-			log.Fatal("repository URL is required (--url)")
+			return fmt.Errorf("repository URL is required (--url)")
+		}
+		if err := git.ValidateScheme(urlScheme); err != nil {
+			return err
 		}
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := mgr.GetConfig()
@@ -59,47 +100,124 @@ Example:
 		// Check if repository already exists
 		for _, repo := range cfg.Repositories {
 			if repo.Name == repoName {
-				log.Fatalf("repository with name '%s' already exists", repoName)
+				return fmt.Errorf("repository with name '%s' already exists", repoName)
 			}
 		}
 
 		// Create repository path
 		repoPath := filepath.Join(cfg.WorkspacePath, repoName)
 
+		// Without --branch, follow the remote's default branch; detected
+		// branches exist, so only a given or fallback one needs checking
+		branch, _ := cmd.Flags().GetString("branch")
+		checkBranch := branch != ""
+		if branch == "" {
+			remote := git.New(repoPath, repoURL, "")
+			remote.URLScheme = urlScheme
+			ctx, cancel := context.WithTimeout(context.Background(), defaultBranchTimeout)
+			detected, err := remote.DefaultBranch(ctx)
+			cancel()
+			if err == nil {
+				branch = detected
+				fmt.Printf("Using the remote's default branch %s\n", branch)
+			} else {
+				branch = fallbackBranch(cfg)
+				checkBranch = true
+				fmt.Printf("Could not detect the default branch (%v); using %s\n", err, branch)
+			}
+		}
+
 		// Add new repository
-		newRepo := config.Repository{
-			Name:     repoName,
-			URL:      repoURL,
-			Path:     repoPath,
-			Branch:   "main", // Default to main branch
-			LastSync: time.Now(),
+		newRepo := newRepository(cfg, repoName, repoURL, urlScheme, branch)
+		newRepo.UpstreamURL = upstreamURL
+		newRepo.Remote = remote
+		newRepo.Ref = ref
+		newRepo.Submodules = recurse
+		if err := app.GitRepo(newRepo).ValidateRemote(); err != nil {
+			return err
+		}
+
+		// Clone when asked to, otherwise prompt unless nobody is there to answer
+		var clone bool
+		switch {
+		case cmd.Flags().Changed("clone"):
+			clone, _ = cmd.Flags().GetBool("clone")
+		case !assumeYes(cmd) && !stdinIsTerminal():
+			fmt.Println("Not cloning (stdin is not a terminal; pass --clone to clone).")
+		default:
+			clone = confirm(cmd, fmt.Sprintf("Clone %s into %s now?", repoURL, repoPath), true)
+		}
+
+		// Catch a mistyped branch before it is saved; pinned repositories
+		// check out their ref instead
+		repo := app.GitRepo(newRepo)
+		if clone && checkBranch && newRepo.Ref == "" {
+			if err := repo.CheckRemoteBranch(context.Background()); err != nil {
+				return err
+			}
 		}
 
 		cfg.Repositories = append(cfg.Repositories, newRepo)
 
 		// Save configuration
 		if err := mgr.Save(); err != nil {
-			log.Fatalf("failed to save configuration: %v", err)
+			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
 		fmt.Printf("Added repository '%s' from %s\n", repoName, repoURL)
+		if upstreamURL != "" {
+			fmt.Printf("Tracking upstream: %s\n", upstreamURL)
+		}
+		if ref != "" {
+			fmt.Printf("Pinned to: %s\n", ref)
+		}
 		fmt.Printf("Repository will be cloned to: %s\n", repoPath)
 
-		// Prompt for immediate cloning
-		fmt.Print("Would you like to clone the repository now? (Y/n): ")
-		var resp string
-		fmt.Scanln(&resp)
-		if resp == "" || resp == "Y" || resp == "y" {
+		if clone {
 			fmt.Println("Cloning repository...")
-			repo := git.New(newRepo.Path, newRepo.URL, newRepo.Branch)
-			if err := repo.Clone(); err != nil {
-				log.Fatalf("failed to clone repository: %v", err)
+			if err := app.CloneRepo(context.Background(), newRepo, hookOptions(cmd)); err != nil {
+				return fmt.Errorf("failed to clone repository: %w", err)
 			}
 			fmt.Println("Repository cloned successfully.")
 		}
+		return nil
 	},
 }
 
+// newRepository builds the config entry for a repository added under name,
+// cloned from url into the workspace and carrying the defaults block's tags
+func newRepository(cfg *config.Config, name, url, urlScheme, branch string) config.Repository {
+	return config.Repository{
+		Name:      name,
+		URL:       url,
+		URLScheme: urlScheme,
+		Path:      filepath.Join(cfg.WorkspacePath, name),
+		Branch:    branch,
+		Tags:      cfg.Defaults.Tags,
+		LastSync:  time.Now(),
+	}
+}
+
+// fallbackBranch is the branch followed when a repository's default branch
+// isn't known: the defaults block's, or main
+func fallbackBranch(cfg *config.Config) string {
+	if cfg.Defaults.Branch != "" {
+		return cfg.Defaults.Branch
+	}
+	return "main"
+}
+
+// findRemote returns the configured repository cloned from url, however the
+// URL is written
+func findRemote(cfg *config.Config, url string) (config.Repository, bool) {
+	for _, repo := range cfg.Repositories {
+		if git.SameRemote(repo.URL, url) {
+			return repo, true
+		}
+	}
+	return config.Repository{}, false
+}
+
 var repoRemoveCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove a managed repository",
@@ -108,21 +226,21 @@ Does not delete the repository from the filesystem.
 
 Example:
   dev-manager repos remove --name my-project`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 		repoName, _ := cmd.Flags().GetString("name")
 
 		if repoName == "" {
-			log.Fatal("repository name is required (--name)")
+			return fmt.Errorf("repository name is required (--name)")
 		}
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := mgr.GetConfig()
@@ -137,42 +255,187 @@ Example:
 		}
 
 		if !found {
-			log.Fatalf("repository with name '%s' not found", repoName)
+			return fmt.Errorf("repository with name '%s' not found", repoName)
 		}
 
 		// Save configuration
 		if err := mgr.Save(); err != nil {
-			log.Fatalf("failed to save configuration: %v", err)
+			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
 		fmt.Printf("Removed repository '%s' from management.\n", repoName)
+		return nil
 	},
 }
 
+var repoPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete repository directories that are no longer managed",
+	Long: `Find git repositories directly under the workspace directory that don't
+belong to any configured repository, such as ones left behind by "repos remove",
+and offer to delete each of them. Directories without a .git entry are never
+touched, nor are those matching the config's excludePaths globs (relative to
+the workspace) or the deps directory. With a project-local config, the
+repositories of the global config count as managed too.
+
+Example:
+  dev-manager repos prune --dry-run
+  dev-manager repos prune --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		others, err := globalConfigFor(mgr)
+		if err != nil {
+			return err
+		}
+		orphans, err := app.OrphanedRepos(mgr.GetConfig(), others...)
+		if err != nil {
+			return err
+		}
+		if len(orphans) == 0 {
+			fmt.Println("No unmanaged repositories found.")
+			return nil
+		}
+
+		removed, err := app.PruneRepos(orphans, app.PruneOptions{
+			DryRun: dryRun,
+			Confirm: func(dir string, dirty bool) bool {
+				question := fmt.Sprintf("Delete %s?", dir)
+				if dirty {
+					question = fmt.Sprintf("Delete %s (it has uncommitted changes)?", dir)
+				}
+				return confirm(cmd, question, false)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			for _, dir := range removed {
+				fmt.Printf("Would delete %s\n", dir)
+			}
+			return nil
+		}
+		fmt.Printf("Deleted %d of %d unmanaged repositories.\n", len(removed), len(orphans))
+		return nil
+	},
+}
+
+// globalConfigFor returns the global config when mgr uses a project-local
+// one, so that repositories it manages in a shared workspace aren't taken
+// for unmanaged ones
+func globalConfigFor(mgr *config.Manager) ([]*config.Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	global := config.GlobalConfigPath(home)
+	if current, err := filepath.Abs(mgr.Path()); err == nil && current == global {
+		return nil, nil
+	}
+
+	globalMgr, err := config.NewManager(global)
+	if err != nil {
+		return nil, err
+	}
+	if err := globalMgr.Load(); err != nil {
+		if errors.Is(err, config.ErrConfigNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load global config: %w", err)
+	}
+	return []*config.Config{globalMgr.GetConfig()}, nil
+}
+
 var repoListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all managed repositories",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `List all managed repositories.
+Use --output json to get the repositories as JSON, e.g. for scripts.
+
+--filter keeps repositories whose name or URL contains the given text, and
+--dirty those with uncommitted changes; repositories that aren't cloned yet
+are never dirty. --sort orders the list by name, or by lastSync with the
+longest unsynced first.
+
+Example:
+  dev-manager repos list
+  dev-manager repos list --filter api --sort name
+  dev-manager repos list --dirty
+  dev-manager repos list -o json | jq '.[].name'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		filter, _ := cmd.Flags().GetString("filter")
+		dirty, _ := cmd.Flags().GetBool("dirty")
+		sortBy, _ := cmd.Flags().GetString("sort")
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := mgr.GetConfig()
 
+		// Sorting first rejects a bad --sort before --dirty runs git in every repository
+		repos := slices.Clone(cfg.Repositories)
+		if err := app.SortRepos(repos, sortBy); err != nil {
+			return err
+		}
+		repos, err = app.FilterRepos(repos, app.RepoFilter{Match: filter, Dirty: dirty})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check some repositories for changes:\n%v\n", err)
+		}
+
+		// Show references as written rather than the secrets they resolve to
+		unresolved := make(map[string]config.Repository)
+		for _, repo := range mgr.Unresolved().Repositories {
+			unresolved[repo.Name] = repo
+		}
+		for i, repo := range repos {
+			if raw, ok := unresolved[repo.Name]; ok {
+				repos[i] = raw
+			}
+		}
+
+		if format == outputJSON {
+			if repos == nil {
+				repos = []config.Repository{}
+			}
+			if err := printJSON(repos); err != nil {
+				return fmt.Errorf("failed to encode repositories: %w", err)
+			}
+			return nil
+		}
+
 		if len(cfg.Repositories) == 0 {
 			fmt.Println("No repositories configured.")
-			return
+			return nil
+		}
+		if len(repos) == 0 {
+			fmt.Println("No repositories match.")
+			return nil
 		}
 
-		fmt.Printf("Managed repositories (%d):\n\n", len(cfg.Repositories))
-		for _, repo := range cfg.Repositories {
+		fmt.Printf("Managed repositories (%d):\n\n", len(repos))
+		for _, repo := range repos {
 			fmt.Printf("Name: %s\n", repo.Name)
 			fmt.Printf("  URL: %s\n", repo.URL)
 			fmt.Printf("  Path: %s\n", repo.Path)
@@ -180,59 +443,693 @@ var repoListCmd = &cobra.Command{
 			fmt.Printf("  Last Sync: %s\n", repo.LastSync.Format(time.RFC3339))
 			fmt.Println()
 		}
+		return nil
+	},
+}
+
+var repoStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the working tree status of all repositories",
+	Long: `Show an overview of every managed repository: the checked out branch,
+whether the working tree is clean, and how many commits it is ahead of or
+behind its upstream.
+
+Example:
+  dev-manager repos status`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+
+		if len(cfg.Repositories) == 0 {
+			fmt.Println("No repositories configured.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tBRANCH\tSTATE\tAHEAD\tBEHIND")
+		for _, repo := range cfg.Repositories {
+			if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+				fmt.Fprintf(w, "%s\t-\tnot cloned\t-\t-\n", repo.Name)
+				continue
+			}
+
+			r := app.GitRepo(repo)
+
+			branch, err := r.CurrentBranch()
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\terror: %v\t-\t-\n", repo.Name, err)
+				continue
+			}
+
+			state := "clean"
+			if clean, err := r.IsClean(); err != nil {
+				state = "unknown"
+			} else if !clean {
+				state = "dirty"
+			}
+
+			// Branches without an upstream have nothing to compare against
+			ahead, behind := "-", "-"
+			if a, b, err := r.AheadBehind(); err == nil {
+				ahead, behind = strconv.Itoa(a), strconv.Itoa(b)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", repo.Name, branch, state, ahead, behind)
+		}
+		w.Flush()
+		return nil
 	},
 }
 
 var repoSyncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync a specific repository",
-	Run: func(cmd *cobra.Command, args []string) {
-		// TODO: Implement sync for a specific repository
-		fmt.Println("Syncing a specific repository...")
+	Long: `Sync a single repository by pulling the latest changes from its remote.
+Forks added with --fork-of are additionally rebased onto their upstream.
+A postSync hook only runs with --allow-hooks; --no-hooks skips it.
+Pass --remote to sync with a different remote than the configured one (origin
+by default); the choice is saved for later syncs.
+
+Example:
+  dev-manager repos sync --name my-project`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		repoName, _ := cmd.Flags().GetString("name")
+
+		if repoName == "" {
+			return fmt.Errorf("repository name is required (--name)")
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+
+		repo, ok := cfg.FindRepository(repoName)
+		if !ok {
+			return fmt.Errorf("repository with name '%s' not found", repoName)
+		}
+
+		remoteChanged := false
+		if cmd.Flags().Changed("remote") {
+			remote, _ := cmd.Flags().GetString("remote")
+			if remote == git.DefaultRemote {
+				remote = ""
+			}
+			remoteChanged = remote != repo.Remote
+			repo.Remote = remote
+			if err := app.GitRepo(*repo).ValidateRemote(); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Syncing repository: %s...\n", repo.Name)
+		if err := app.SyncRepo(context.Background(), *repo, hookOptions(cmd)); err != nil {
+			return fmt.Errorf("failed to sync repository %s: %w", repo.Name, err)
+		}
+
+		repo.LastSync = time.Now()
+		if remoteChanged {
+			// A new remote is the user's own change, so keep it undoable
+			err = mgr.Save()
+		} else {
+			err = saveLastSync(mgr, *repo)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		fmt.Printf("Synced repository: %s\n", repo.Name)
+		return nil
+	},
+}
+
+var repoCheckoutCmd = &cobra.Command{
+	Use:   "checkout",
+	Short: "Switch a repository to another branch",
+	Long: `Switch a repository to another branch and follow that branch from then on.
+A branch that only exists on the remote is fetched and checked out tracking
+it. The switch is refused while the repository has uncommitted changes.
+Repositories that aren't cloned yet only have their tracked branch updated.
+
+Example:
+  dev-manager repos checkout --name my-project --branch release`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		repoName, _ := cmd.Flags().GetString("name")
+		branch, _ := cmd.Flags().GetString("branch")
+
+		if repoName == "" {
+			return fmt.Errorf("repository name is required (--name)")
+		}
+		if branch == "" {
+			return fmt.Errorf("branch is required (--branch)")
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+
+		repo, ok := cfg.FindRepository(repoName)
+		if !ok {
+			return fmt.Errorf("repository with name '%s' not found", repoName)
+		}
+		if repo.Ref != "" {
+			return fmt.Errorf("repository %s is pinned to %s; remove the ref to follow a branch", repo.Name, repo.Ref)
+		}
+		if repo.Branch == branch {
+			fmt.Printf("Repository %s already follows %s\n", repo.Name, branch)
+			return nil
+		}
+
+		target := *repo
+		target.Branch = branch
+		gitRepo := app.GitRepo(target)
+		if _, err := os.Stat(filepath.Join(repo.Path, ".git")); err != nil {
+			// Nothing on disk to switch; just make sure the next clone works
+			if err := gitRepo.CheckRemoteBranch(context.Background()); err != nil {
+				return err
+			}
+		} else if err := gitRepo.Checkout(context.Background(), branch); err != nil {
+			if errors.Is(err, git.ErrDirtyWorktree) {
+				return fmt.Errorf("repository %s has uncommitted changes; commit or stash them before switching branches", repo.Name)
+			}
+			return fmt.Errorf("failed to check out %s in %s: %w", branch, repo.Name, err)
+		}
+
+		repo.Branch = branch
+		if err := mgr.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		fmt.Printf("Repository %s now follows %s\n", repo.Name, branch)
+		return nil
 	},
 }
 
 var repoSyncAllCmd = &cobra.Command{
 	Use:   "sync-all",
 	Short: "Sync all repositories",
-	Long:  `Sync all repositories by pulling the latest changes from their remotes.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Sync all repositories by pulling the latest changes from their remotes.
+Repositories are synced concurrently, up to --jobs at a time. Use --timeout
+to give up on a repository that takes too long, so one stuck remote doesn't
+hang the whole batch. Failures, including failed postSync and postClone
+hooks, are summarized once every repository has been attempted. Hooks only
+run with --allow-hooks; pass --no-hooks to skip them. The command exits
+non-zero if any repository failed to sync. With --fail-fast, no further
+repositories are started after the first failure, which suits CI.
+
+With --if-stale, repositories synced more recently than their update
+frequency are skipped, which makes sync-all cheap enough to run from a cron
+job or login hook. Repositories that aren't cloned yet are never skipped.
+
+With --prune-remotes, remote-tracking refs of branches that were deleted
+upstream are removed as each repository is fetched.
+
+Example:
+  dev-manager repos sync-all
+  dev-manager repos sync-all --jobs 8 --timeout 2m
+  dev-manager repos sync-all --if-stale
+  dev-manager repos sync-all --dry-run
+  dev-manager repos sync-all --fail-fast
+  dev-manager repos sync-all --prune-remotes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		ifStale, _ := cmd.Flags().GetBool("if-stale")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		allowHooks, _ := cmd.Flags().GetBool("allow-hooks")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		pruneRemotes, _ := cmd.Flags().GetBool("prune-remotes")
+
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := syncAll(context.Background(), mgr, syncAllOptions{jobs: jobs, timeout: timeout, ifStale: ifStale, dryRun: dryRun, noHooks: noHooks, allowHooks: allowHooks, failFast: failFast, pruneRemotes: pruneRemotes}); err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+var repoCloneAllCmd = &cobra.Command{
+	Use:   "clone-all",
+	Short: "Clone every repository that isn't checked out yet",
+	Long: `Clone each configured repository whose path doesn't exist yet, e.g. after
+restoring the config on a new machine. Repositories that are already present
+are skipped; run "repos sync-all" afterwards to update them, as are those
+whose path matches the config's excludePaths. Each new clone runs its
+postClone hook with --allow-hooks, or skips it with --no-hooks.
+
+Example:
+  dev-manager repos clone-all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfgPath, _ := cmd.Flags().GetString("file")
 
 		mgr, err := config.NewManager(cfgPath)
 		if err != nil {
-			log.Fatalf("failed to create config manager: %v", err)
+			return fmt.Errorf("failed to create config manager: %w", err)
 		}
 
-		if err := mgr.Load(); err != nil {
-			log.Fatalf("failed to load config: %v", err)
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		cfg := mgr.GetConfig()
 
+		if len(cfg.Repositories) == 0 {
+			fmt.Println("No repositories configured.")
+			return nil
+		}
+
+		var cloned, skipped int
+		failures := make(map[string]error)
 		for _, repo := range cfg.Repositories {
-			fmt.Printf("Syncing repository: %s...\n", repo.Name)
-			r := git.New(repo.Path, repo.URL, repo.Branch)
-			if err := r.Update(); err != nil {
-				log.Printf("failed to sync repository %s: %v\n", repo.Name, err)
+			if cfg.IsExcluded(repo.Path) {
+				fmt.Printf("Skipping %s: %s matches excludePaths\n", repo.Name, repo.Path)
+				skipped++
+				continue
+			}
+			if _, err := os.Stat(repo.Path); err == nil {
+				fmt.Printf("Skipping %s: %s already exists\n", repo.Name, repo.Path)
+				skipped++
+				continue
+			}
+
+			fmt.Printf("Cloning %s into %s...\n", repo.Name, repo.Path)
+			if err := app.CloneRepo(context.Background(), repo, hookOptions(cmd)); err != nil {
+				fmt.Printf("Failed to clone repository: %s\n", repo.Name)
+				failures[repo.Name] = err
+				continue
+			}
+			cloned++
+		}
+
+		fmt.Printf("\nCloned %d, skipped %d, failed %d.\n", cloned, skipped, len(failures))
+		if len(failures) > 0 {
+			fmt.Printf("\nFailed repositories (%d):\n", len(failures))
+			for _, repo := range cfg.Repositories {
+				if err, ok := failures[repo.Name]; ok {
+					fmt.Printf("  %s: %v\n", repo.Name, err)
+				}
+			}
+			return fmt.Errorf("%d of %d repositories failed to clone", len(failures), len(cfg.Repositories))
+		}
+		return nil
+	},
+}
+
+var repoImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Add every repository of a GitHub organization",
+	Long: `Add the repositories of a GitHub organization or user to the config in one
+go. Repositories are listed with the gh CLI, which must be installed and
+logged in, and are added under their GitHub name with their ssh URL and
+default branch. Repositories whose name or URL is already configured are
+skipped. Use --limit to cap how many repositories are listed, --clone to clone
+the added repositories right away and --url-scheme to clone them over https
+instead, as with "repos add".
+
+Example:
+  dev-manager repos import --github my-org
+  dev-manager repos import --github my-org --limit 200 --clone
+  dev-manager repos import --github my-org --url-scheme https`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("github") {
+			return cmd.Help()
+		}
+
+		cfgPath, _ := cmd.Flags().GetString("file")
+		org, _ := cmd.Flags().GetString("github")
+		limit, _ := cmd.Flags().GetInt("limit")
+		clone, _ := cmd.Flags().GetBool("clone")
+		urlScheme, _ := cmd.Flags().GetString("url-scheme")
+
+		if org == "" {
+			return fmt.Errorf("organization is required (--github)")
+		}
+		if limit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		if err := git.ValidateScheme(urlScheme); err != nil {
+			return err
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+
+		remotes, err := listGitHubRepos(org, limit)
+		if err != nil {
+			return err
+		}
+		if len(remotes) == 0 {
+			fmt.Printf("No repositories found for %s.\n", org)
+			return nil
+		}
+
+		var added []config.Repository
+		skipped := 0
+		for _, remote := range remotes {
+			if _, ok := cfg.FindRepository(remote.Name); ok {
+				fmt.Printf("Skipping %s: already configured\n", remote.Name)
+				skipped++
+				continue
+			}
+			if existing, ok := findRemote(cfg, remote.SSHURL); ok {
+				fmt.Printf("Skipping %s: already configured as %s\n", remote.Name, existing.Name)
+				skipped++
+				continue
+			}
+
+			// Empty repositories have no default branch yet
+			branch := remote.DefaultBranchRef.Name
+			if branch == "" {
+				branch = fallbackBranch(cfg)
+			}
+
+			repo := newRepository(cfg, remote.Name, git.ConvertURL(remote.SSHURL, urlScheme), urlScheme, branch)
+			cfg.Repositories = append(cfg.Repositories, repo)
+			added = append(added, repo)
+			fmt.Printf("Adding %s (%s)\n", repo.Name, repo.Branch)
+		}
+
+		if len(added) > 0 {
+			if err := mgr.Save(); err != nil {
+				return fmt.Errorf("failed to save configuration: %w", err)
+			}
+		}
+		fmt.Printf("\nAdded %d, skipped %d of %d repositories from %s.\n", len(added), skipped, len(remotes), org)
+		if len(remotes) == limit {
+			fmt.Printf("Only the first %d repositories were listed; raise --limit to import more.\n", limit)
+		}
+
+		if !clone || len(added) == 0 {
+			return nil
+		}
+
+		failures := make(map[string]error)
+		for _, repo := range added {
+			if cfg.IsExcluded(repo.Path) {
+				fmt.Printf("Skipping clone of %s: %s matches excludePaths\n", repo.Name, repo.Path)
+				continue
+			}
+			if _, err := os.Stat(repo.Path); err == nil {
+				fmt.Printf("Skipping clone of %s: %s already exists\n", repo.Name, repo.Path)
 				continue
 			}
-			fmt.Printf("Synced repository: %s\n", repo.Name)
+			fmt.Printf("Cloning %s into %s...\n", repo.Name, repo.Path)
+			if err := app.CloneRepo(context.Background(), repo, hookOptions(cmd)); err != nil {
+				fmt.Printf("Failed to clone repository: %s\n", repo.Name)
+				failures[repo.Name] = err
+			}
 		}
+		if len(failures) > 0 {
+			fmt.Printf("\nFailed repositories (%d):\n", len(failures))
+			for _, repo := range added {
+				if err, ok := failures[repo.Name]; ok {
+					fmt.Printf("  %s: %v\n", repo.Name, err)
+				}
+			}
+			return fmt.Errorf("%d of %d repositories failed to clone", len(failures), len(added))
+		}
+		return nil
 	},
 }
 
+// githubRepo is a repository as listed by "gh repo list"
+type githubRepo struct {
+	Name             string `json:"name"`
+	SSHURL           string `json:"sshUrl"`
+	DefaultBranchRef struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+}
+
+// listGitHubRepos lists up to limit repositories of a GitHub organization or
+// user through the gh CLI
+func listGitHubRepos(org string, limit int) ([]githubRepo, error) {
+	output, err := exec.Command("gh", "repo", "list", org,
+		"--limit", strconv.Itoa(limit),
+		"--json", "name,sshUrl,defaultBranchRef").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("failed to list repositories of %s: %s", org, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to list repositories of %s (is gh installed?): %w", org, err)
+	}
+
+	var repos []githubRepo
+	if err := json.Unmarshal(output, &repos); err != nil {
+		return nil, fmt.Errorf("failed to parse repositories of %s: %w", org, err)
+	}
+	return repos, nil
+}
+
+// saveLastSync records the LastSync times of synced repositories in the
+// config file, keeping changes other commands made to it during the sync
+func saveLastSync(mgr *config.Manager, synced ...config.Repository) error {
+	return mgr.Update(func(cfg *config.Config) error {
+		for _, repo := range synced {
+			if r, ok := cfg.FindRepository(repo.Name); ok {
+				r.LastSync = repo.LastSync
+			}
+		}
+		return nil
+	})
+}
+
+// syncAllOptions controls a sync-all run
+type syncAllOptions struct {
+	jobs    int
+	timeout time.Duration
+	// ifStale skips repositories synced within their update frequency
+	ifStale bool
+	// dryRun prints what would be done to each repository without running git
+	dryRun bool
+	// noHooks skips the repositories' postClone and postSync hooks
+	noHooks bool
+	// allowHooks permits running the hooks, which execute arbitrary commands
+	allowHooks bool
+	// failFast stops starting syncs after the first failure
+	failFast bool
+	// pruneRemotes deletes stale remote-tracking refs while fetching
+	pruneRemotes bool
+}
+
+// syncAll syncs the repositories of a loaded config concurrently, printing
+// progress and a summary, and records the successful syncs in the config. It
+// returns an error if any repository failed to sync.
+func syncAll(ctx context.Context, mgr *config.Manager, opts syncAllOptions) error {
+	cfg := mgr.GetConfig()
+
+	if len(cfg.Repositories) == 0 {
+		fmt.Println("No repositories configured.")
+		return nil
+	}
+
+	now := time.Now()
+	pending, skipped := app.SelectRepos(cfg, opts.ifStale, now)
+	for _, i := range skipped {
+		repo := cfg.Repositories[i]
+		fmt.Printf("Skipping %s: synced %s ago\n", repo.Name, now.Sub(repo.LastSync).Round(time.Second))
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("All repositories are up to date.")
+		return nil
+	}
+
+	if opts.dryRun {
+		fmt.Printf("Dry run: %d repositories would be synced, nothing will be changed.\n", len(pending))
+		for _, i := range pending {
+			r := app.GitRepo(cfg.Repositories[i])
+			r.Prune = opts.pruneRemotes
+			fmt.Printf("  %s: would %s\n", cfg.Repositories[i].Name, r.UpdatePlan())
+		}
+		return nil
+	}
+
+	fmt.Printf("Syncing %d repositories (%d at a time)...\n", len(pending), opts.jobs)
+
+	results := app.SyncRepos(ctx, cfg, pending, app.SyncOptions{
+		Jobs:         opts.jobs,
+		Timeout:      opts.timeout,
+		NoHooks:      opts.noHooks,
+		AllowHooks:   opts.allowHooks,
+		PruneRemotes: opts.pruneRemotes,
+		FailFast:     opts.failFast,
+		Progress: func(result app.RepoSyncResult) {
+			if result.Status == app.StatusFailed {
+				fmt.Printf("Failed to sync repository: %s (%s)\n", result.Name, roundDuration(result.Duration))
+			} else {
+				fmt.Printf("Synced repository: %s (%s)\n", result.Name, roundDuration(result.Duration))
+			}
+		},
+	})
+
+	var failed []app.RepoSyncResult
+	var synced []config.Repository
+	var slowest app.RepoSyncResult
+	notAttempted := 0
+	for i, result := range results {
+		if result.Duration > slowest.Duration {
+			slowest = result
+		}
+		switch result.Status {
+		case app.StatusNotAttempted:
+			notAttempted++
+		case app.StatusFailed:
+			failed = append(failed, result)
+		default:
+			synced = append(synced, cfg.Repositories[pending[i]])
+		}
+	}
+
+	if len(synced) > 0 {
+		if err := saveLastSync(mgr, synced...); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	fmt.Printf("\nSynced %d/%d repositories.\n", len(synced), len(results))
+	if len(results) > 1 && slowest.Name != "" {
+		fmt.Printf("Slowest: %s (%s)\n", slowest.Name, roundDuration(slowest.Duration))
+	}
+	if notAttempted > 0 {
+		fmt.Printf("Stopped after the first failure; %d repositories were not attempted.\n", notAttempted)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("\nFailed repositories (%d):\n", len(failed))
+		conflicts := 0
+		for _, result := range failed {
+			fmt.Printf("  %s: %v\n", result.Name, result.Err)
+			if errors.Is(result.Err, git.ErrRebaseConflict) {
+				conflicts++
+			}
+		}
+		if conflicts > 0 {
+			fmt.Printf("%d repositories could not be rebased onto their remote; they were left as they were.\n", conflicts)
+		}
+		return fmt.Errorf("%d of %d repositories failed to sync", len(failed), len(results))
+	}
+
+	return nil
+}
+
+// hookOptions returns the sync options selected by the --no-hooks and
+// --allow-hooks flags
+func hookOptions(cmd *cobra.Command) app.SyncOptions {
+	noHooks, _ := cmd.Flags().GetBool("no-hooks")
+	allowHooks, _ := cmd.Flags().GetBool("allow-hooks")
+	return app.SyncOptions{NoHooks: noHooks, AllowHooks: allowHooks}
+}
+
+// roundDuration rounds a sync or install time for display
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
 func init() {
 	// Add repo commands
 	rootCmd.AddCommand(reposCmd)
 	reposCmd.AddCommand(repoAddCmd)
 	repoAddCmd.Flags().StringP("name", "n", "", "Name of the repository")
 	repoAddCmd.Flags().StringP("url", "u", "", "URL of the repository")
+	repoAddCmd.Flags().StringP("branch", "b", "", "Branch to follow (default: the remote's default branch)")
+	repoAddCmd.Flags().String("fork-of", "", "URL of the upstream repository this one is a fork of")
+	repoAddCmd.Flags().String("ref", "", "Tag or commit to pin the checkout to instead of following the branch")
+	repoAddCmd.Flags().Bool("recurse-submodules", false, "Clone and update the repository's submodules")
+	repoAddCmd.Flags().Bool("clone", false, "Clone the repository right away (--clone=false skips it; asks when unset)")
+	repoAddCmd.Flags().String("url-scheme", "", "Clone over https or ssh regardless of the URL's form (https, ssh or as-is)")
+	repoAddCmd.Flags().Bool("no-hooks", false, "Don't run the repository's postClone hook after cloning")
+	repoAddCmd.Flags().Bool("allow-hooks", false, "Run the repository's hooks (they execute arbitrary shell commands)")
+	repoAddCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	repoAddCmd.Flags().String("remote", "", "Name to give the remote the repository is cloned from (default origin)")
 
 	reposCmd.AddCommand(repoRemoveCmd)
 	repoRemoveCmd.Flags().StringP("name", "n", "", "Name of the repository to remove")
+	reposCmd.AddCommand(repoPruneCmd)
+	repoPruneCmd.Flags().Bool("dry-run", false, "List unmanaged repositories without deleting them")
 
 	reposCmd.AddCommand(repoListCmd)
+	addOutputFlag(repoListCmd)
+	repoListCmd.Flags().String("filter", "", "Only list repositories whose name or URL contains this text")
+	repoListCmd.Flags().Bool("dirty", false, "Only list cloned repositories with uncommitted changes")
+	repoListCmd.Flags().String("sort", "", "Sort by name or lastSync (longest unsynced first)")
+	reposCmd.AddCommand(repoStatusCmd)
 	reposCmd.AddCommand(repoSyncCmd)
+	repoSyncCmd.Flags().StringP("name", "n", "", "Name of the repository to sync")
+	repoSyncCmd.Flags().Bool("no-hooks", false, "Don't run the repository's postSync or postClone hook")
+	repoSyncCmd.Flags().Bool("allow-hooks", false, "Run the repository's hooks (they execute arbitrary shell commands)")
+	repoSyncCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	repoSyncCmd.Flags().String("remote", "", "Remote to sync with, saved for later syncs (default origin)")
+	reposCmd.AddCommand(repoCheckoutCmd)
+	repoCheckoutCmd.Flags().StringP("name", "n", "", "Name of the repository to switch")
+	repoCheckoutCmd.Flags().StringP("branch", "b", "", "Branch to check out and follow")
 	reposCmd.AddCommand(repoSyncAllCmd)
+	repoSyncAllCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of repositories to sync concurrently")
+	repoSyncAllCmd.Flags().Duration("timeout", 0, "Maximum time to spend syncing a single repository (e.g. 2m, 0 for no limit)")
+	repoSyncAllCmd.Flags().Bool("if-stale", false, "Only sync repositories whose last sync is older than their update frequency")
+	repoSyncAllCmd.Flags().Bool("dry-run", false, "Show what would be done to each repository without running git")
+	repoSyncAllCmd.Flags().Bool("no-hooks", false, "Don't run the repositories' postSync and postClone hooks")
+	repoSyncAllCmd.Flags().Bool("allow-hooks", false, "Run the repositories' hooks (they execute arbitrary shell commands)")
+	repoSyncAllCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	repoSyncAllCmd.Flags().Bool("fail-fast", false, "Stop starting syncs after the first failure")
+	repoSyncAllCmd.Flags().Bool("prune-remotes", false, "Delete remote-tracking refs of branches deleted upstream")
+	reposCmd.AddCommand(repoCloneAllCmd)
+	repoCloneAllCmd.Flags().Bool("no-hooks", false, "Don't run the repositories' postClone hooks")
+	repoCloneAllCmd.Flags().Bool("allow-hooks", false, "Run the repositories' hooks (they execute arbitrary shell commands)")
+	repoCloneAllCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	reposCmd.AddCommand(repoImportCmd)
+	repoImportCmd.Flags().String("github", "", "GitHub organization or user whose repositories to add")
+	repoImportCmd.Flags().Int("limit", 100, "Maximum number of repositories to list")
+	repoImportCmd.Flags().Bool("clone", false, "Clone the added repositories right away")
+	repoImportCmd.Flags().String("url-scheme", "", "Clone over https or ssh regardless of the URL's form (https, ssh or as-is)")
+	repoImportCmd.Flags().Bool("no-hooks", false, "Don't run the repositories' postClone hooks")
+	repoImportCmd.Flags().Bool("allow-hooks", false, "Run the repositories' hooks (they execute arbitrary shell commands)")
+	repoImportCmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"dev-manager/internal/testutil/mockgit"
+	"dev-manager/pkg/config"
+)
+
+func TestSyncAllIfStale(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{})
+
+	// The repository was recorded as synced moments ago, but its directory
+	// doesn't exist yet
+	workspace := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := mgr.GetConfig()
+	cfg.WorkspacePath = workspace
+	cfg.UpdateFrequency = time.Hour
+	cfg.Repositories = []config.Repository{
+		{Name: "added", URL: "https://example.com/added.git", Path: filepath.Join(workspace, "added"), Branch: "main", LastSync: time.Now()},
+	}
+	if err := mgr.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{"repos", "sync-all", "--file", cfgPath, "--if-stale", "--jobs", "1"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("repos sync-all --if-stale error = %v", err)
+	}
+
+	cloned := slices.ContainsFunc(mock.Invocations(t), func(args []string) bool {
+		return slices.Contains(args, "clone")
+	})
+	if !cloned {
+		t.Errorf("repos sync-all --if-stale didn't clone the repository; git invocations = %v", mock.Invocations(t))
+	}
+}
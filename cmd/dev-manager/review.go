@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/spf13/cobra"
+)
+
+// reviewComment is an inline PR review comment as returned by the GitHub API,
+// which comments from other hosts are normalized into
+type reviewComment struct {
+	ID          int64  `json:"id"`
+	InReplyToID int64  `json:"in_reply_to_id"`
+	Body        string `json:"body"`
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+	User        struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	// Thread identifies the comment's thread on hosts that reply to a thread
+	// rather than to its first comment, such as GitLab discussions
+	Thread string `json:"-"`
+}
+
+// prComment is a PR conversation comment as returned by gh pr view
+type prComment struct {
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// prCommentsSince returns the comments made after since; a zero since keeps
+// them all
+func prCommentsSince(comments []prComment, since time.Time) []prComment {
+	var recent []prComment
+	for _, c := range comments {
+		if c.CreatedAt.After(since) {
+			recent = append(recent, c)
+		}
+	}
+	return recent
+}
+
+// reviewCommentsSince returns the review comments made after since; a zero
+// since keeps them all
+func reviewCommentsSince(comments []reviewComment, since time.Time) []reviewComment {
+	var recent []reviewComment
+	for _, c := range comments {
+		if c.CreatedAt.After(since) {
+			recent = append(recent, c)
+		}
+	}
+	return recent
+}
+
+// reviewStateFile records when each PR was last reviewed with
+// --since-last-run. It lives next to the config file.
+const reviewStateFile = "review-state.json"
+
+// parseSince parses a --since value: a date, an RFC 3339 timestamp, or a
+// duration before now such as 48h
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (want a date such as 2024-05-01, an RFC 3339 timestamp or a duration such as 48h)", value)
+}
+
+// reviewSince returns the time the review of a PR should start from, per
+// --since or --since-last-run, and a function to call once the review is
+// done to record it for the next --since-last-run
+func reviewSince(cmd *cobra.Command, provider reviewProvider, prNumber int) (time.Time, func() error, error) {
+	noop := func() error { return nil }
+
+	if value, _ := cmd.Flags().GetString("since"); value != "" {
+		since, err := parseSince(value, time.Now())
+		return since, noop, err
+	}
+	if lastRun, _ := cmd.Flags().GetBool("since-last-run"); !lastRun {
+		return time.Time{}, noop, nil
+	}
+
+	cfgPath, _ := cmd.Flags().GetString("file")
+	mgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to create config manager: %w", err)
+	}
+	statePath := filepath.Join(filepath.Dir(mgr.Path()), reviewStateFile)
+
+	// PR numbers are only unique within a repository
+	repo, err := provider.Repo()
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	key := fmt.Sprintf("%s#%d", repo, prNumber)
+
+	state, err := loadReviewState(statePath)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	since := state[key]
+	if since.IsZero() {
+		fmt.Println("No previous review of this PR recorded; reviewing all comments.")
+	} else {
+		fmt.Printf("Reviewing comments since the last run on %s.\n", since.Local().Format(time.RFC1123))
+	}
+
+	// Comments made while the review runs are picked up next time
+	started := time.Now()
+	record := func() error {
+		// Reload in case another review finished meanwhile
+		state, err := loadReviewState(statePath)
+		if err != nil {
+			return err
+		}
+		state[key] = started
+		return saveReviewState(statePath, state)
+	}
+	return since, record, nil
+}
+
+// loadReviewState reads the last review times keyed by "owner/repo#PR"
+func loadReviewState(path string) (map[string]time.Time, error) {
+	state := make(map[string]time.Time)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse review state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// saveReviewState writes the last review times
+func saveReviewState(path string, state map[string]time.Time) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save review state: %w", err)
+	}
+	return nil
+}
+
+// decodePages decodes the output of a paginated API call, which holds one
+// JSON array per page, into a single slice
+func decodePages[T any](output []byte) ([]T, error) {
+	var all []T
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for dec.More() {
+		var page []T
+		if err := dec.Decode(&page); err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
+
+// reviewThreads groups review comments by the thread they belong to, keyed
+// by the ID of the comment that started it. GitHub only accepts replies to
+// the first comment of a thread.
+func reviewThreads(comments []reviewComment) (roots []reviewComment, replies map[int64][]reviewComment) {
+	replies = make(map[int64][]reviewComment)
+	for _, c := range comments {
+		if c.InReplyToID == 0 {
+			roots = append(roots, c)
+			continue
+		}
+		replies[c.InReplyToID] = append(replies[c.InReplyToID], c)
+	}
+	return roots, replies
+}
+
+// maxReviewDiffBytes caps how much diff is included in the review prompt so
+// large PRs don't exhaust the model's context
+const maxReviewDiffBytes = 12000
+
+// Lines of diff context kept around a review comment's line
+const (
+	hunkContextBefore = 15
+	hunkContextAfter  = 5
+)
+
+// diffHunk is one hunk of a unified diff
+type diffHunk struct {
+	Path   string
+	Header string
+	Start  int // first line in the new version of the file
+	Lines  []string
+}
+
+// parseDiffHunks splits a unified diff, as printed by `gh pr diff`, into hunks
+func parseDiffHunks(diff string) []diffHunk {
+	var hunks []diffHunk
+	var path string
+	var current *diffHunk
+
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			path, current = "", nil
+		case current == nil && strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ "):
+			hunks = append(hunks, diffHunk{Path: path, Header: line, Start: hunkStart(line)})
+			current = &hunks[len(hunks)-1]
+		case current != nil:
+			current.Lines = append(current.Lines, line)
+		}
+	}
+	return hunks
+}
+
+// hunkStart returns the new-file start line from a hunk header such as
+// "@@ -10,6 +12,8 @@"
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// excerpt returns the hunk header and the lines around line in the new
+// version of the file, or false when the hunk doesn't cover line
+func (h diffHunk) excerpt(line int) (string, bool) {
+	var kept []string
+	covered := false
+	n := h.Start
+	for _, l := range h.Lines {
+		// Removed lines don't exist in the new file, so share the next line's number
+		if n >= line-hunkContextBefore && n <= line+hunkContextAfter {
+			kept = append(kept, l)
+		}
+		if n == line && !strings.HasPrefix(l, "-") {
+			covered = true
+		}
+		if !strings.HasPrefix(l, "-") {
+			n++
+		}
+	}
+	if !covered {
+		return "", false
+	}
+	return h.Header + "\n" + strings.Join(kept, "\n"), true
+}
+
+// formatReviewComments formats review comments together with the diff they
+// were left on. Once maxDiff bytes of diff have been included, the remaining
+// comments are listed without it.
+func formatReviewComments(comments []reviewComment, hunks []diffHunk, maxDiff int) string {
+	var result strings.Builder
+	for i, c := range comments {
+		location := c.Path
+		if c.Line > 0 {
+			location = fmt.Sprintf("%s:%d", c.Path, c.Line)
+		}
+		result.WriteString(fmt.Sprintf("Comment %d (%s):\n%s\n", i+1, location, c.Body))
+
+		if c.Line > 0 {
+			for _, h := range hunks {
+				if h.Path != c.Path {
+					continue
+				}
+				excerpt, ok := h.excerpt(c.Line)
+				if !ok {
+					continue
+				}
+				if len(excerpt) > maxDiff {
+					result.WriteString("(diff omitted: size limit reached)\n")
+				} else {
+					maxDiff -= len(excerpt)
+					result.WriteString("Diff:\n" + excerpt + "\n")
+				}
+				break
+			}
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// postReviewReplies drafts a reply to each review thread with comments made
+// after since, using the LLM, and posts the ones the user approves
+func postReviewReplies(cmd *cobra.Command, provider reviewProvider, prNumber int, prTitle string, since time.Time, client *openai.Client) error {
+	comments, err := provider.ReviewComments(prNumber)
+	if err != nil {
+		return err
+	}
+
+	roots, replies := reviewThreads(comments)
+	roots = slices.DeleteFunc(roots, func(root reviewComment) bool {
+		thread := append([]reviewComment{root}, replies[root.ID]...)
+		return len(reviewCommentsSince(thread, since)) == 0
+	})
+	if len(roots) == 0 {
+		fmt.Println("No review comments to reply to.")
+		return nil
+	}
+
+	posted := 0
+	for i, root := range roots {
+		thread := append([]reviewComment{root}, replies[root.ID]...)
+
+		fmt.Printf("\n[%d/%d] %s:%d\n", i+1, len(roots), root.Path, root.Line)
+		for _, c := range thread {
+			fmt.Printf("  @%s: %s\n", c.User.Login, c.Body)
+		}
+
+		draft, err := generateReviewReply(prTitle, thread, client)
+		if err != nil {
+			return fmt.Errorf("failed to draft reply: %w", err)
+		}
+		fmt.Printf("\nDraft reply:\n%s\n\n", draft)
+
+		if !confirm(cmd, "Post this reply?", false) {
+			fmt.Println("Skipped.")
+			continue
+		}
+		if err := provider.Reply(prNumber, root, draft); err != nil {
+			return err
+		}
+		posted++
+		fmt.Println("Posted.")
+	}
+
+	fmt.Printf("\nPosted %d of %d replies.\n", posted, len(roots))
+	return nil
+}
+
+// generateReviewReply uses OpenAI to draft a reply to a review comment thread
+func generateReviewReply(prTitle string, thread []reviewComment, client *openai.Client) (string, error) {
+	var conversation strings.Builder
+	for _, c := range thread {
+		conversation.WriteString(fmt.Sprintf("@%s: %s\n", c.User.Login, c.Body))
+	}
+
+	prompt := fmt.Sprintf(`Draft a reply from the PR author to this review comment thread.
+Acknowledge the feedback and say how it will be addressed, or explain briefly
+why no change is needed. Reply with the comment text only.
+
+PR Title: %s
+File: %s (line %d)
+
+Thread:
+%s`, prTitle, thread[0].Path, thread[0].Line, conversation.String())
+
+	req := openai.ChatCompletionRequest{
+		Model: openai.GPT4,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: "You are a helpful assistant that drafts concise, polite replies to code review comments.",
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt,
+			},
+		},
+		MaxTokens:   300,
+		Temperature: 0.7,
+	}
+
+	return completeChat(context.Background(), client, req, nil)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Code hosts the review command can fetch comments from
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+)
+
+// pullRequest is a PR (or GitLab merge request) normalized from whichever
+// host it was fetched from
+type pullRequest struct {
+	Title    string      `json:"title"`
+	Body     string      `json:"body"`
+	Branch   string      `json:"headRefName"`
+	Comments []prComment `json:"comments"`
+	Files    []prFile    `json:"files"`
+}
+
+// prFile is a file changed by a PR
+type prFile struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Changes   int    `json:"changes"`
+}
+
+// reviewProvider fetches a PR's comments from a code host, normalized into
+// the structs the review prompt is built from, and posts replies to them
+type reviewProvider interface {
+	// FindPR returns the open PR for a branch, or 0 when there is none
+	FindPR(branch string) (number int, title string, err error)
+	// Repo identifies the current repository on the host, such as owner/repo
+	Repo() (string, error)
+	PR(number int) (*pullRequest, error)
+	ReviewComments(number int) ([]reviewComment, error)
+	Diff(number int) (string, error)
+	// Reply replies to the review thread started by root
+	Reply(number int, root reviewComment, body string) error
+}
+
+// newReviewProvider returns the provider with the given name, detecting it
+// from the origin remote when name is empty
+func newReviewProvider(name string) (reviewProvider, error) {
+	if name == "" {
+		name = detectReviewProvider()
+	}
+	switch name {
+	case providerGitHub:
+		return githubProvider{}, nil
+	case providerGitLab:
+		return gitlabProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q (want %s or %s)", name, providerGitHub, providerGitLab)
+}
+
+// detectReviewProvider picks GitLab when the origin remote is hosted on a
+// host named like it, and GitHub otherwise. Self-hosted GitLab instances on
+// other hosts need --provider.
+func detectReviewProvider() string {
+	output, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err == nil && strings.Contains(strings.ToLower(string(output)), "gitlab") {
+		return providerGitLab
+	}
+	return providerGitHub
+}
+
+// githubProvider talks to GitHub through the gh CLI
+type githubProvider struct{}
+
+func (githubProvider) FindPR(branch string) (int, string, error) {
+	output, err := exec.Command("gh", "search", "prs", "--json", "number,title", "--jq", ".[0]", "head:"+branch, "is:open").Output()
+	if err != nil || len(output) == 0 {
+		return 0, "", err
+	}
+	var pr struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	}
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return 0, "", err
+	}
+	return pr.Number, pr.Title, nil
+}
+
+func (githubProvider) Repo() (string, error) {
+	output, err := exec.Command("gh", "repo", "view", "--json", "nameWithOwner", "--jq", ".nameWithOwner").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the repository: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (githubProvider) PR(number int) (*pullRequest, error) {
+	output, err := exec.Command("gh", "pr", "view", fmt.Sprintf("%d", number), "--json", "title,body,headRefName,comments,files").Output()
+	if err != nil {
+		return nil, fmt.Errorf("PR #%d not found or not accessible: %w", number, err)
+	}
+	var pr pullRequest
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse PR details: %w", err)
+	}
+	return &pr, nil
+}
+
+func (githubProvider) ReviewComments(number int) ([]reviewComment, error) {
+	endpoint := fmt.Sprintf("repos/{owner}/{repo}/pulls/%d/comments", number)
+	output, err := exec.Command("gh", "api", "--paginate", endpoint).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch review comments: %w", err)
+	}
+
+	comments, err := decodePages[reviewComment](output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse review comments: %w", err)
+	}
+	return comments, nil
+}
+
+func (githubProvider) Diff(number int) (string, error) {
+	output, err := exec.Command("gh", "pr", "diff", fmt.Sprintf("%d", number)).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get PR diff: %w", err)
+	}
+	return string(output), nil
+}
+
+// Reply replies to root's thread; GitHub only accepts replies to the first
+// comment of a thread
+func (githubProvider) Reply(number int, root reviewComment, body string) error {
+	endpoint := fmt.Sprintf("repos/{owner}/{repo}/pulls/%d/comments/%d/replies", number, root.ID)
+	cmd := exec.Command("gh", "api", "--method", "POST", endpoint, "-f", "body="+body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to post reply: %s, %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// gitlabProvider talks to GitLab through the glab CLI. Merge requests are
+// addressed by their IID, the number shown as !42.
+type gitlabProvider struct{}
+
+func (gitlabProvider) FindPR(branch string) (int, string, error) {
+	output, err := exec.Command("glab", "mr", "list", "--source-branch", branch, "--output", "json").Output()
+	if err != nil {
+		return 0, "", err
+	}
+	var mrs []struct {
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(output, &mrs); err != nil || len(mrs) == 0 {
+		return 0, "", err
+	}
+	return mrs[0].IID, mrs[0].Title, nil
+}
+
+func (gitlabProvider) Repo() (string, error) {
+	output, err := exec.Command("glab", "repo", "view", "--output", "json").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the repository: %w", err)
+	}
+	var project struct {
+		Path string `json:"path_with_namespace"`
+	}
+	if err := json.Unmarshal(output, &project); err != nil {
+		return "", fmt.Errorf("failed to parse repository details: %w", err)
+	}
+	return project.Path, nil
+}
+
+// gitlabDiscussion is a merge request discussion as returned by the GitLab
+// API. Individual notes are plain comments; the others are threads, which
+// carry a position when they were started on the diff.
+type gitlabDiscussion struct {
+	ID             string       `json:"id"`
+	IndividualNote bool         `json:"individual_note"`
+	Notes          []gitlabNote `json:"notes"`
+}
+
+type gitlabNote struct {
+	ID     int64  `json:"id"`
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	Position  *struct {
+		NewPath string `json:"new_path"`
+		NewLine int    `json:"new_line"`
+	} `json:"position"`
+}
+
+func (gitlabProvider) discussions(number int) ([]gitlabDiscussion, error) {
+	endpoint := fmt.Sprintf("projects/:id/merge_requests/%d/discussions", number)
+	output, err := exec.Command("glab", "api", "--paginate", endpoint).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch merge request discussions: %w", err)
+	}
+	discussions, err := decodePages[gitlabDiscussion](output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse merge request discussions: %w", err)
+	}
+	return discussions, nil
+}
+
+func (p gitlabProvider) PR(number int) (*pullRequest, error) {
+	output, err := exec.Command("glab", "mr", "view", fmt.Sprintf("%d", number), "--output", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("merge request !%d not found or not accessible: %w", number, err)
+	}
+	var mr struct {
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		SourceBranch string `json:"source_branch"`
+	}
+	if err := json.Unmarshal(output, &mr); err != nil {
+		return nil, fmt.Errorf("failed to parse merge request details: %w", err)
+	}
+
+	discussions, err := p.discussions(number)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := p.Diff(number)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &pullRequest{Title: mr.Title, Body: mr.Description, Branch: mr.SourceBranch, Files: diffFileStats(diff)}
+	for _, d := range discussions {
+		if !d.IndividualNote || len(d.Notes) == 0 || d.Notes[0].System {
+			continue
+		}
+		pr.Comments = append(pr.Comments, prComment{Body: d.Notes[0].Body, CreatedAt: d.Notes[0].CreatedAt})
+	}
+	return pr, nil
+}
+
+// ReviewComments returns the notes of the threads started on the diff, with
+// replies pointing at the thread's first note as on GitHub
+func (p gitlabProvider) ReviewComments(number int) ([]reviewComment, error) {
+	discussions, err := p.discussions(number)
+	if err != nil {
+		return nil, err
+	}
+	var comments []reviewComment
+	for _, d := range discussions {
+		if d.IndividualNote || len(d.Notes) == 0 || d.Notes[0].Position == nil {
+			continue
+		}
+		root := d.Notes[0]
+		for i, note := range d.Notes {
+			if note.System {
+				continue
+			}
+			c := reviewComment{
+				ID:        note.ID,
+				Body:      note.Body,
+				Path:      root.Position.NewPath,
+				Line:      root.Position.NewLine,
+				CreatedAt: note.CreatedAt,
+				Thread:    d.ID,
+			}
+			c.User.Login = note.Author.Username
+			if i > 0 {
+				c.InReplyToID = root.ID
+			}
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func (gitlabProvider) Diff(number int) (string, error) {
+	output, err := exec.Command("glab", "mr", "diff", fmt.Sprintf("%d", number), "--raw").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get merge request diff: %w", err)
+	}
+	return string(output), nil
+}
+
+// Reply adds a note to root's discussion
+func (gitlabProvider) Reply(number int, root reviewComment, body string) error {
+	endpoint := fmt.Sprintf("projects/:id/merge_requests/%d/discussions/%s/notes", number, root.Thread)
+	cmd := exec.Command("glab", "api", "--method", "POST", endpoint, "-f", "body="+body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to post reply: %s, %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// diffFileStats counts the lines added and removed per file in a unified
+// diff, for hosts that don't report them with the PR
+func diffFileStats(diff string) []prFile {
+	var files []prFile
+	var oldPath string
+	var current *prFile
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = nil
+		case current == nil && strings.HasPrefix(line, "--- "):
+			oldPath = strings.TrimPrefix(strings.TrimPrefix(line, "--- "), "a/")
+		case current == nil && strings.HasPrefix(line, "+++ "):
+			path := strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			// Deleted files are named after their old path
+			if path == "/dev/null" {
+				path = oldPath
+			}
+			files = append(files, prFile{Path: path})
+			current = &files[len(files)-1]
+		case current != nil && strings.HasPrefix(line, "+"):
+			current.Additions++
+			current.Changes++
+		case current != nil && strings.HasPrefix(line, "-"):
+			current.Deletions++
+			current.Changes++
+		}
+	}
+	return files
+}
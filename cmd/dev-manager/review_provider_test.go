@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"dev-manager/internal/testutil/mockgit"
+)
+
+// cliResponse is what a fake CLI prints for one subcommand
+type cliResponse struct {
+	Output   string
+	ExitCode int
+}
+
+// fakeCLI puts a script named name first on PATH that answers each call by
+// its first two arguments, e.g. "pr view", and fails on any other. It
+// returns the file the script logs its arguments to, one call per line.
+func fakeCLI(t *testing.T, name string, responses map[string]cliResponse) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Fake CLI tests are not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	var script strings.Builder
+	fmt.Fprintf(&script, "#!/bin/sh\necho \"$*\" >> %q\ncase \"$1 $2\" in\n", log)
+	i := 0
+	for key, resp := range responses {
+		out := filepath.Join(dir, fmt.Sprintf("response-%d", i))
+		i++
+		if err := os.WriteFile(out, []byte(resp.Output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		stream := ""
+		if resp.ExitCode != 0 {
+			stream = " >&2"
+		}
+		fmt.Fprintf(&script, "%q) cat %q%s; exit %d ;;\n", key, out, stream, resp.ExitCode)
+	}
+	script.WriteString("*) echo \"unexpected call: $*\" >&2; exit 2 ;;\nesac\n")
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script.String()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// calls returns the argument lines logged by a fake CLI
+func calls(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestNewReviewProvider(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name    string
+		remote  mockgit.Config
+		want    reviewProvider
+		wantErr bool
+	}{
+		{name: providerGitHub, want: githubProvider{}},
+		{name: providerGitLab, want: gitlabProvider{}},
+		{name: "bitbucket", wantErr: true},
+		{name: "", remote: mockgit.Config{Output: "git@github.com:org/repo.git\n"}, want: githubProvider{}},
+		{name: "", remote: mockgit.Config{Output: "https://gitlab.com/group/sub/repo.git\n"}, want: gitlabProvider{}},
+		{name: "", remote: mockgit.Config{Output: "git@GitLab.example.com:team/repo.git\n"}, want: gitlabProvider{}},
+		{name: "", remote: mockgit.Config{ExitCode: 2, Error: "error: No such remote 'origin'\n"}, want: githubProvider{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+strings.TrimSpace(tt.remote.Output), func(t *testing.T) {
+			mock.Configure(t, mockgit.Config{Commands: map[string]mockgit.Config{"remote": tt.remote}})
+
+			got, err := newReviewProvider(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newReviewProvider(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("newReviewProvider(%q) = %T, want %T", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFileStats(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
+-// old
++// new
++// added
+diff --git a/old.txt b/old.txt
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+diff --git a/new.txt b/new.txt
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++--- not a header
+`
+	want := []prFile{
+		{Path: "main.go", Additions: 2, Deletions: 1, Changes: 3},
+		{Path: "old.txt", Deletions: 2, Changes: 2},
+		{Path: "new.txt", Additions: 1, Changes: 1},
+	}
+	if got := diffFileStats(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffFileStats() = %+v, want %+v", got, want)
+	}
+	if got := diffFileStats(""); got != nil {
+		t.Errorf("diffFileStats(\"\") = %+v, want none", got)
+	}
+}
+
+func TestGitHubProvider(t *testing.T) {
+	log := fakeCLI(t, "gh", map[string]cliResponse{
+		"search prs": {Output: `{"number":42,"title":"Fix the parser"}`},
+		"repo view":  {Output: "org/repo\n"},
+		"pr view": {Output: `{"title":"Fix the parser","body":"Closes #1","headRefName":"fix-parser",
+			"comments":[{"body":"LGTM","createdAt":"2024-05-01T10:00:00Z"}],
+			"files":[{"path":"parser.go","additions":3,"deletions":1}]}`},
+		"pr diff": {Output: "diff --git a/parser.go b/parser.go\n"},
+		// Paginated pages are separate arrays
+		"api --paginate": {Output: `[{"id":1,"body":"Rename this","path":"parser.go","line":7,"user":{"login":"alice"}}]
+[{"id":2,"in_reply_to_id":1,"body":"Done","path":"parser.go","line":7,"user":{"login":"bob"}}]`},
+		"api --method": {Output: "HTTP 422: Validation Failed\n", ExitCode: 1},
+	})
+	p := githubProvider{}
+
+	number, title, err := p.FindPR("fix-parser")
+	if err != nil || number != 42 || title != "Fix the parser" {
+		t.Errorf("FindPR() = %d, %q, %v; want 42, %q", number, title, err, "Fix the parser")
+	}
+	if repo, err := p.Repo(); err != nil || repo != "org/repo" {
+		t.Errorf("Repo() = %q, %v; want org/repo", repo, err)
+	}
+
+	pr, err := p.PR(42)
+	if err != nil {
+		t.Fatalf("PR() error = %v", err)
+	}
+	if pr.Branch != "fix-parser" || len(pr.Comments) != 1 || len(pr.Files) != 1 || pr.Files[0].Additions != 3 {
+		t.Errorf("PR() = %+v", pr)
+	}
+
+	comments, err := p.ReviewComments(42)
+	if err != nil {
+		t.Fatalf("ReviewComments() error = %v", err)
+	}
+	if len(comments) != 2 || comments[1].InReplyToID != 1 || comments[0].User.Login != "alice" {
+		t.Errorf("ReviewComments() = %+v, want both pages", comments)
+	}
+
+	if diff, err := p.Diff(42); err != nil || !strings.HasPrefix(diff, "diff --git") {
+		t.Errorf("Diff() = %q, %v", diff, err)
+	}
+
+	err = p.Reply(42, comments[0], "Thanks")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 422") {
+		t.Errorf("Reply() error = %v, want the API's error", err)
+	}
+
+	got := calls(t, log)
+	if want := "api --method POST repos/{owner}/{repo}/pulls/42/comments/1/replies -f body=Thanks"; got[len(got)-1] != want {
+		t.Errorf("reply call = %q, want %q", got[len(got)-1], want)
+	}
+}
+
+func TestGitHubProviderErrors(t *testing.T) {
+	fakeCLI(t, "gh", map[string]cliResponse{
+		"search prs":     {Output: ""},
+		"pr view":        {Output: "GraphQL: Could not resolve to a PullRequest with the number of 7.\n", ExitCode: 1},
+		"api --paginate": {Output: "not json"},
+	})
+	p := githubProvider{}
+
+	if number, _, err := p.FindPR("no-pr"); err != nil || number != 0 {
+		t.Errorf("FindPR() without a PR = %d, %v; want 0, nil", number, err)
+	}
+	if _, err := p.PR(7); err == nil || !strings.Contains(err.Error(), "PR #7 not found") {
+		t.Errorf("PR() error = %v, want not found", err)
+	}
+	if _, err := p.ReviewComments(7); err == nil || !strings.Contains(err.Error(), "failed to parse review comments") {
+		t.Errorf("ReviewComments() error = %v, want a parse error", err)
+	}
+	if _, err := p.Repo(); err == nil {
+		t.Error("Repo() succeeded though gh failed")
+	}
+}
+
+func TestGitLabProvider(t *testing.T) {
+	log := fakeCLI(t, "glab", map[string]cliResponse{
+		"mr list":   {Output: `[{"iid":7,"title":"Add caching"}]`},
+		"repo view": {Output: `{"path_with_namespace":"group/sub/repo"}`},
+		"mr view":   {Output: `{"title":"Add caching","description":"Speeds up builds","source_branch":"cache"}`},
+		"mr diff":   {Output: "diff --git a/cache.go b/cache.go\n--- a/cache.go\n+++ b/cache.go\n@@ -1 +1,2 @@\n+// cached\n"},
+		"api --paginate": {Output: `[
+			{"id":"d1","individual_note":true,"notes":[{"id":10,"body":"Nice","created_at":"2024-05-01T10:00:00Z"}]},
+			{"id":"d2","individual_note":true,"notes":[{"id":11,"body":"added 1 commit","system":true}]}
+		]
+		[
+			{"id":"d3","individual_note":false,"notes":[
+				{"id":20,"body":"Why here?","author":{"username":"alice"},"position":{"new_path":"cache.go","new_line":12}},
+				{"id":21,"body":"resolved all threads","system":true},
+				{"id":22,"body":"Because","author":{"username":"bob"}}
+			]},
+			{"id":"d4","individual_note":false,"notes":[{"id":30,"body":"General thread"}]}
+		]`},
+		"api --method": {Output: `{"id":23}`},
+	})
+	p := gitlabProvider{}
+
+	number, title, err := p.FindPR("cache")
+	if err != nil || number != 7 || title != "Add caching" {
+		t.Errorf("FindPR() = %d, %q, %v; want 7, %q", number, title, err, "Add caching")
+	}
+	if repo, err := p.Repo(); err != nil || repo != "group/sub/repo" {
+		t.Errorf("Repo() = %q, %v; want group/sub/repo", repo, err)
+	}
+
+	pr, err := p.PR(7)
+	if err != nil {
+		t.Fatalf("PR() error = %v", err)
+	}
+	// Only the plain, non-system note is a conversation comment
+	if pr.Title != "Add caching" || pr.Body != "Speeds up builds" || pr.Branch != "cache" {
+		t.Errorf("PR() = %+v", pr)
+	}
+	if len(pr.Comments) != 1 || pr.Comments[0].Body != "Nice" {
+		t.Errorf("PR() comments = %+v, want only %q", pr.Comments, "Nice")
+	}
+	if want := []prFile{{Path: "cache.go", Additions: 1, Changes: 1}}; !reflect.DeepEqual(pr.Files, want) {
+		t.Errorf("PR() files = %+v, want %+v", pr.Files, want)
+	}
+
+	// Threads on the diff become review comments, replies pointing at the
+	// thread's first note and placed where it is
+	comments, err := p.ReviewComments(7)
+	if err != nil {
+		t.Fatalf("ReviewComments() error = %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("ReviewComments() = %+v, want 2 comments", comments)
+	}
+	root, reply := comments[0], comments[1]
+	if root.ID != 20 || root.InReplyToID != 0 || root.Path != "cache.go" || root.Line != 12 || root.Thread != "d3" || root.User.Login != "alice" {
+		t.Errorf("thread root = %+v", root)
+	}
+	if reply.ID != 22 || reply.InReplyToID != 20 || reply.Path != "cache.go" || reply.Line != 12 || reply.Thread != "d3" {
+		t.Errorf("thread reply = %+v", reply)
+	}
+
+	if err := p.Reply(7, root, "Thanks"); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	got := calls(t, log)
+	if want := "api --method POST projects/:id/merge_requests/7/discussions/d3/notes -f body=Thanks"; got[len(got)-1] != want {
+		t.Errorf("reply call = %q, want %q", got[len(got)-1], want)
+	}
+}
+
+func TestGitLabProviderErrors(t *testing.T) {
+	fakeCLI(t, "glab", map[string]cliResponse{
+		"mr list":        {Output: "[]"},
+		"mr view":        {Output: "404 Not Found\n", ExitCode: 1},
+		"api --paginate": {Output: "401 Unauthorized\n", ExitCode: 1},
+		"api --method":   {Output: "403 Forbidden\n", ExitCode: 1},
+	})
+	p := gitlabProvider{}
+
+	if number, _, err := p.FindPR("no-mr"); err != nil || number != 0 {
+		t.Errorf("FindPR() without a merge request = %d, %v; want 0, nil", number, err)
+	}
+	if _, err := p.PR(7); err == nil || !strings.Contains(err.Error(), "merge request !7 not found") {
+		t.Errorf("PR() error = %v, want not found", err)
+	}
+	if _, err := p.ReviewComments(7); err == nil || !strings.Contains(err.Error(), "failed to fetch merge request discussions") {
+		t.Errorf("ReviewComments() error = %v, want a fetch error", err)
+	}
+	if err := p.Reply(7, reviewComment{Thread: "d1"}, "Thanks"); err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+		t.Errorf("Reply() error = %v, want the API's error", err)
+	}
+}
@@ -1,57 +1,97 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
-	"os/exec"
 	"strconv"
+	"strings"
+	"time"
 
 	"dev-manager/internal/ssh"
+	"dev-manager/pkg/config"
 
 	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 )
 
-// newSSHManager is a helper to create a new SSHManager and handle errors.
-func newSSHManager() *ssh.SSHManager {
+// newSSHManager is a helper to create a new SSHManager for the directory
+// given with --ssh-dir, or ~/.ssh, and wrap its errors.
+func newSSHManager(cmd *cobra.Command) (*ssh.SSHManager, error) {
+	if dir, _ := cmd.Flags().GetString("ssh-dir"); dir != "" {
+		dir, err := config.ExpandPath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --ssh-dir: %w", err)
+		}
+		return ssh.NewSSHManagerWithDir(dir), nil
+	}
 	mgr, err := ssh.NewSSHManager()
 	if err != nil {
-		log.Fatalf("Failed to initialize SSH manager: %v", err)
+		return nil, fmt.Errorf("Failed to initialize SSH manager: %w", err)
 	}
-	return mgr
+	return mgr, nil
 }
 
 var sshCmd = &cobra.Command{
 	Use:   "ssh",
 	Short: "Manage SSH keys",
-	Long:  `Commands for managing SSH keys.`,
+	Long: `Commands for managing SSH keys.
+Keys are generated in and listed from ~/.ssh; use --ssh-dir to manage the
+keys in another directory.`,
 }
 
 var sshGenerateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate a new SSH key",
 	Long: `Generate a new SSH key with the specified algorithm and name.
-Supported algorithms: rsa, ed25519.
+Supported algorithms: ed25519 (default), ecdsa, rsa.
+Use --bits to choose the key size: at least 1024 for rsa, 256/384/521 for
+ecdsa; ed25519 keys have a fixed size.
+Use --comment to tag the key, e.g. with your email address.
+Use --to-agent to add the new key to the SSH agent right away.
 
 Example:
-  dev-manager ssh generate --algo ed25519 --name my-key
-  dev-manager ssh generate -a rsa -n another-key`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager ssh generate --algo ed25519 --name my-key --comment me@example.com
+  dev-manager ssh generate -a rsa -n another-key --bits 4096
+  dev-manager ssh generate -a ecdsa -n third-key --bits 521
+  dev-manager ssh generate -n my-key --to-agent --lifetime 8h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		algo, _ := cmd.Flags().GetString("algo")
 		name, _ := cmd.Flags().GetString("name")
+		bits, _ := cmd.Flags().GetInt("bits")
+		comment, _ := cmd.Flags().GetString("comment")
+		toAgent, _ := cmd.Flags().GetBool("to-agent")
 
 		if name == "" {
-			log.Fatal("key name is required (--name)")
+			return fmt.Errorf("key name is required (--name)")
+		}
+
+		opts, err := agentOptions(cmd)
+		if err != nil {
+			return err
 		}
 
-		mgr := newSSHManager()
-		keyPath, err := mgr.GenerateKey(algo, name)
+		mgr, err := newSSHManager(cmd)
 		if err != nil {
-			log.Fatalf("failed to generate key: %v", err)
+			return err
+		}
+		keyPath, err := mgr.GenerateKey(algo, name, bits, comment)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
 		}
 
 		fmt.Printf("Generated SSH key: %s\n", keyPath)
+
+		if toAgent {
+			if err := mgr.AddKeyToAgent(keyPath, opts); err != nil {
+				return fmt.Errorf("failed to add key to agent: %w", err)
+			}
+			fmt.Printf("Added key to SSH agent: %s\n", keyPath)
+		}
+		return nil
 	},
 }
 
@@ -60,36 +100,176 @@ var sshAddAgentCmd = &cobra.Command{
 	Short: "Add a key to SSH agent",
 	Long: `Add an existing SSH key to the SSH agent.
 The key must be unencrypted.
+Use --lifetime to have the agent forget the key after a while and --confirm
+to require confirmation every time the key is used.
 
 Example:
-  dev-manager ssh add-agent --key ~/.ssh/my-key`,
-	Run: func(cmd *cobra.Command, args []string) {
+  dev-manager ssh add-agent --key ~/.ssh/my-key
+  dev-manager ssh add-agent --key ~/.ssh/my-key --lifetime 1h --confirm`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		keyPath, _ := cmd.Flags().GetString("key")
 
 		if keyPath == "" {
-			log.Fatal("key path is required (--key)")
+			return fmt.Errorf("key path is required (--key)")
+		}
+
+		opts, err := agentOptions(cmd)
+		if err != nil {
+			return err
 		}
 
-		mgr := newSSHManager()
-		if err := mgr.AddKeyToAgent(keyPath); err != nil {
-			log.Fatalf("failed to add key to agent: %v", err)
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
+		if err := mgr.AddKeyToAgent(keyPath, opts); err != nil {
+			return fmt.Errorf("failed to add key to agent: %w", err)
 		}
 
 		fmt.Printf("Added key to SSH agent: %s\n", keyPath)
+		return nil
 	},
 }
 
+var sshAddAllCmd = &cobra.Command{
+	Use:   "add-all",
+	Short: "Add every key in the SSH directory to the SSH agent",
+	Long: `Add each private key in ~/.ssh, or --ssh-dir, to the SSH agent, skipping
+keys the agent already holds (matched by fingerprint). Encrypted keys prompt
+for their passphrase one at a time; a key that can't be added is reported and
+the rest are still added.
+
+Example:
+  dev-manager ssh add-all
+  dev-manager ssh add-all --lifetime 8h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := agentOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
+		keys, err := mgr.ListKeysWithInfo()
+		if err != nil {
+			return fmt.Errorf("failed to list SSH keys: %w", err)
+		}
+		agentKeys, err := mgr.ListAgentKeys()
+		if err != nil {
+			return fmt.Errorf("failed to list agent keys: %w", err)
+		}
+		if len(keys) == 0 {
+			fmt.Println("No SSH keys found.")
+			return nil
+		}
+
+		inAgent := make(map[string]bool, len(agentKeys))
+		for _, k := range agentKeys {
+			inAgent[k.Fingerprint] = true
+		}
+
+		var added, present int
+		failures := make(map[string]error)
+		for _, k := range keys {
+			if k.Fingerprint != "" && inAgent[k.Fingerprint] {
+				fmt.Printf("Already in agent: %s\n", k.Path)
+				present++
+				continue
+			}
+
+			// ssh-add asks for the passphrase of encrypted keys itself
+			fmt.Printf("Adding %s...\n", k.Path)
+			if err := mgr.AddKeyToAgent(k.Path, opts); err != nil {
+				fmt.Printf("Failed to add key: %s\n", k.Path)
+				failures[k.Path] = err
+				continue
+			}
+			if k.Fingerprint != "" {
+				inAgent[k.Fingerprint] = true
+			}
+			added++
+		}
+
+		fmt.Printf("\nAdded %d, already present %d, failed %d.\n", added, present, len(failures))
+		if len(failures) > 0 {
+			fmt.Printf("\nFailed keys (%d):\n", len(failures))
+			for _, k := range keys {
+				if err, ok := failures[k.Path]; ok {
+					fmt.Printf("  %s: %v\n", k.Path, err)
+				}
+			}
+			return fmt.Errorf("%d of %d keys could not be added to the agent", len(failures), len(keys))
+		}
+		return nil
+	},
+}
+
+// agentOptions reads the --lifetime and --confirm flags of a command.
+func agentOptions(cmd *cobra.Command) (ssh.AgentOptions, error) {
+	lifetime, _ := cmd.Flags().GetDuration("lifetime")
+	confirm, _ := cmd.Flags().GetBool("confirm")
+
+	if lifetime < 0 || (lifetime > 0 && lifetime < time.Second) {
+		return ssh.AgentOptions{}, fmt.Errorf("invalid --lifetime %s: must be at least 1s", lifetime)
+	}
+
+	return ssh.AgentOptions{Lifetime: lifetime, Confirm: confirm}, nil
+}
+
+// addKeyFlags adds the flags resolveKey reads to a command that acts on a
+// single private key
+func addKeyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("key", "k", "", "Path to the private key")
+	cmd.Flags().String("key-name", "", "Name of the key in the SSH directory, as given to generate or its file name")
+	cmd.Flags().String("fingerprint", "", "Fingerprint of the key, as shown by ssh list (e.g. SHA256:...)")
+}
+
+// resolveKey returns the private key a command should act on, chosen with
+// --key, --key-name or --fingerprint, or else interactively. An empty path
+// means the user aborted the selection.
+func resolveKey(cmd *cobra.Command, action string) (string, error) {
+	keyPath, _ := cmd.Flags().GetString("key")
+	keyName, _ := cmd.Flags().GetString("key-name")
+	fingerprint, _ := cmd.Flags().GetString("fingerprint")
+
+	if keyPath != "" {
+		return keyPath, nil
+	}
+	if keyName == "" && fingerprint == "" {
+		return selectKey(cmd, action)
+	}
+
+	mgr, err := newSSHManager(cmd)
+	if err != nil {
+		return "", err
+	}
+	if keyName != "" {
+		return mgr.FindKey(keyName)
+	}
+	return mgr.FindKeyByFingerprint(fingerprint)
+}
+
 // selectKey interactively prompts the user to select a key from the list of available keys.
-// Returns the selected key path or empty string if aborted.
-func selectKey(action string) string {
-	mgr := newSSHManager()
+// Returns the selected key path or empty string if aborted. It fails instead
+// of waiting for input when stdin is not a terminal.
+func selectKey(cmd *cobra.Command, action string) (string, error) {
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("no key selected and stdin is not a terminal; pass --key, --key-name or --fingerprint")
+	}
+
+	mgr, err := newSSHManager(cmd)
+	if err != nil {
+		return "", err
+	}
 	keys, err := mgr.ListPrivateKeys()
 	if err != nil {
-		log.Fatalf("failed to list keys: %v", err)
+		return "", fmt.Errorf("failed to list keys: %w", err)
 	}
 
 	if len(keys) == 0 {
-		log.Fatal("no SSH keys found")
+		return "", fmt.Errorf("no SSH keys found")
 	}
 
 	fmt.Println("Available SSH keys:")
@@ -99,47 +279,51 @@ func selectKey(action string) string {
 
 	// Prompt for selection
 	fmt.Printf("\nSelect a key to %s (number, or press enter to abort): ", action)
-	var selectionStr string
-	fmt.Scanln(&selectionStr)
+	selectionStr, _ := stdin.ReadString('\n')
+	selectionStr = strings.TrimSpace(selectionStr)
 
 	// If empty input, abort
 	if selectionStr == "" {
 		fmt.Println("Operation aborted.")
-		return ""
+		return "", nil
 	}
 
 	// Convert selection to number
 	selection, err := strconv.Atoi(selectionStr)
 	if err != nil || selection < 1 || selection > len(keys) {
-		log.Fatal("invalid selection")
+		return "", fmt.Errorf("invalid selection")
 	}
 
-	return keys[selection-1]
+	return keys[selection-1], nil
 }
 
 var sshPrintPublicCmd = &cobra.Command{
 	Use:   "print-public",
 	Short: "Print the public key",
 	Long: `Print the public key for an existing SSH private key.
-If no key is specified with --key, you will be prompted to select one from a list.
+Choose the key with --key, --key-name or --fingerprint; otherwise you will be
+prompted to select one from a list, which fails when stdin is not a terminal.
 
 Example:
   dev-manager ssh print-public --key ~/.ssh/my-key
   dev-manager ssh print-public`,
-	Run: func(cmd *cobra.Command, args []string) {
-		keyPath, _ := cmd.Flags().GetString("key")
-
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyPath, err := resolveKey(cmd, "print")
+		if err != nil {
+			return err
+		}
 		if keyPath == "" {
-			keyPath = selectKey("print")
-			if keyPath == "" {
-				return
-			}
+			return nil
 		}
 
-		mgr := newSSHManager()
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
 		if err := mgr.PrintPublicKey(keyPath); err != nil {
-			log.Fatalf("failed to print public key: %v", err)
+			return fmt.Errorf("failed to print public key: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -147,32 +331,207 @@ var sshCopyPublicCmd = &cobra.Command{
 	Use:   "copy-public",
 	Short: "Copy public key to clipboard",
 	Long: `Copy the public key to the clipboard for an existing SSH private key.
-If no key is specified with --key, you will be prompted to select one from a list.
+Choose the key with --key, --key-name or --fingerprint; otherwise you will be
+prompted to select one from a list, which fails when stdin is not a terminal.
 
 Example:
   dev-manager ssh copy-public --key ~/.ssh/my-key
   dev-manager ssh copy-public`,
-	Run: func(cmd *cobra.Command, args []string) {
-		keyPath, _ := cmd.Flags().GetString("key")
-
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyPath, err := resolveKey(cmd, "copy")
+		if err != nil {
+			return err
+		}
 		if keyPath == "" {
-			keyPath = selectKey("copy")
-			if keyPath == "" {
-				return
-			}
+			return nil
 		}
 
 		pubKeyPath := keyPath + ".pub"
 		pubKey, err := os.ReadFile(pubKeyPath)
 		if err != nil {
-			log.Fatalf("failed to get public key: %v", err)
+			return fmt.Errorf("failed to get public key: %w", err)
 		}
 
 		if err := clipboard.WriteAll(string(pubKey)); err != nil {
-			log.Fatalf("failed to copy to clipboard: %v", err)
+			return fmt.Errorf("failed to copy to clipboard: %w", err)
 		}
 
 		fmt.Println("Public key copied to clipboard.")
+		return nil
+	},
+}
+
+var sshUploadCmd = &cobra.Command{
+	Use:   "upload",
+	Short: "Upload a public key to a git hosting provider",
+	Long: `Add the public key of an existing SSH private key to your account on a
+git hosting provider. The API token is read from the environment
+(GITHUB_TOKEN for github).
+Choose the key with --key, --key-name or --fingerprint; otherwise you will be
+prompted to select one from a list, which fails when stdin is not a terminal.
+
+Example:
+  dev-manager ssh upload --provider github --key ~/.ssh/my-key
+  dev-manager ssh upload --provider github --title "work laptop"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		title, _ := cmd.Flags().GetString("title")
+
+		uploader, err := ssh.NewKeyUploader(provider)
+		if err != nil {
+			return fmt.Errorf("failed to set up %s upload: %w", provider, err)
+		}
+
+		keyPath, err := resolveKey(cmd, "upload")
+		if err != nil {
+			return err
+		}
+		if keyPath == "" {
+			return nil
+		}
+
+		pubKey, err := os.ReadFile(keyPath + ".pub")
+		if err != nil {
+			return fmt.Errorf("failed to get public key: %w", err)
+		}
+
+		if title == "" {
+			title = ssh.KeyTitle(keyPath)
+		}
+
+		if err := uploader.UploadKey(context.Background(), title, string(pubKey)); err != nil {
+			return fmt.Errorf("failed to upload key to %s: %w", provider, err)
+		}
+
+		fmt.Printf("Uploaded %s.pub to %s as %q\n", keyPath, provider, title)
+		return nil
+	},
+}
+
+var sshRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace a key with a freshly generated one",
+	Long: `Rotate an SSH key: generate a new key of the same type, add it to the agent
+and upload it to the git hosting provider, then, once you confirm, remove the
+old key from the agent, the provider and disk and move the new key into its
+place. The old key is left untouched until the new one has been uploaded, so
+a failed rotation never locks you out. The API token is read from the
+environment (GITHUB_TOKEN for github).
+Select the key with --name (as given to "ssh generate"), --key or
+--fingerprint; otherwise you will be prompted to select one from a list.
+
+Example:
+  dev-manager ssh rotate --name my-key --provider github
+  dev-manager ssh rotate --key ~/.ssh/id_ed25519 --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		name, _ := cmd.Flags().GetString("name")
+		title, _ := cmd.Flags().GetString("title")
+
+		uploader, err := ssh.NewKeyUploader(provider)
+		if err != nil {
+			return fmt.Errorf("failed to set up %s upload: %w", provider, err)
+		}
+		opts, err := agentOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
+		var keyPath string
+		if name != "" {
+			keyPath, err = mgr.FindKey(name)
+		} else {
+			keyPath, err = resolveKey(cmd, "rotate")
+		}
+		if err != nil || keyPath == "" {
+			return err
+		}
+
+		info, err := mgr.GetKeyInfo(keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", keyPath, err)
+		}
+		algo := strings.ToLower(info.Type)
+		bits := info.Bits
+		if algo == "ed25519" {
+			bits = 0
+		}
+		oldPub, err := os.ReadFile(keyPath + ".pub")
+		if err != nil {
+			return fmt.Errorf("failed to get public key: %w", err)
+		}
+
+		// The new key is generated next to the old one and only moved into
+		// its place once it works
+		newPath := keyPath + ".new"
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("%s already exists, left by an earlier rotation; remove it first", newPath)
+		}
+		discardNew := func() {
+			os.Remove(newPath)
+			os.Remove(newPath + ".pub")
+		}
+
+		if err := mgr.GenerateKeyAt(algo, newPath, bits, info.Comment); err != nil {
+			discardNew()
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		fmt.Printf("Generated new SSH key: %s\n", newPath)
+
+		if err := mgr.AddKeyToAgent(newPath, opts); err != nil {
+			discardNew()
+			return fmt.Errorf("failed to add new key to agent: %w", err)
+		}
+
+		newPub, err := os.ReadFile(newPath + ".pub")
+		if err != nil {
+			discardNew()
+			return fmt.Errorf("failed to get public key: %w", err)
+		}
+		if title == "" {
+			title = ssh.KeyTitle(keyPath)
+		}
+		if err := uploader.UploadKey(context.Background(), title, string(newPub)); err != nil {
+			mgr.RemoveKeyFromAgent(newPath)
+			discardNew()
+			return fmt.Errorf("failed to upload new key to %s (%s is unchanged): %w", provider, keyPath, err)
+		}
+		fmt.Printf("Uploaded new key to %s as %q\n", provider, title)
+
+		if !confirm(cmd, fmt.Sprintf("Remove the old key %s from the agent, %s and disk?", keyPath, provider), false) {
+			fmt.Printf("Kept the old key %s; the new key is at %s\n", keyPath, newPath)
+			return nil
+		}
+
+		// ssh-add identifies the key by its .pub file, so this must happen
+		// before the new key replaces it
+		if err := mgr.RemoveKeyFromAgent(keyPath); err != nil {
+			slog.Debug("old key not removed from agent", "key", keyPath, "error", err)
+		}
+		var removeErr error
+		if remover, ok := uploader.(ssh.KeyRemover); ok {
+			removeErr = remover.RemoveKey(context.Background(), string(oldPub))
+			if errors.Is(removeErr, ssh.ErrKeyNotFound) {
+				removeErr = nil
+			}
+		}
+
+		if err := os.Rename(newPath, keyPath); err != nil {
+			return fmt.Errorf("failed to replace old key: %w", err)
+		}
+		if err := os.Rename(newPath+".pub", keyPath+".pub"); err != nil {
+			return fmt.Errorf("failed to replace old public key: %w", err)
+		}
+		fmt.Printf("Rotated %s\n", keyPath)
+
+		if removeErr != nil {
+			return fmt.Errorf("failed to remove the old key from %s; remove it manually: %w", provider, removeErr)
+		}
+		return nil
 	},
 }
 
@@ -180,27 +539,29 @@ var sshRemoveCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove an SSH key",
 	Long: `Remove an SSH key from the filesystem and agent.
-If no key is specified with --key, you will be prompted to select one from a list.
+Choose the key with --key, --key-name or --fingerprint; otherwise you will be
+prompted to select one from a list, which fails when stdin is not a terminal.
 
 Example:
   dev-manager ssh remove --key ~/.ssh/my-key
   dev-manager ssh remove`,
-	Run: func(cmd *cobra.Command, args []string) {
-		keyPath, _ := cmd.Flags().GetString("key")
-
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyPath, err := resolveKey(cmd, "remove")
+		if err != nil {
+			return err
+		}
 		if keyPath == "" {
-			keyPath = selectKey("remove")
-			if keyPath == "" {
-				return
-			}
+			return nil
 		}
 
 		// Remove from agent first (best effort, ignore error if not loaded)
-		_ = exec.Command("ssh-add", "-d", keyPath).Run()
+		if mgr, err := newSSHManager(cmd); err == nil {
+			_ = mgr.RemoveKeyFromAgent(keyPath)
+		}
 
 		// Delete private key
 		if err := os.Remove(keyPath); err != nil {
-			log.Fatalf("failed to remove private key: %v", err)
+			return fmt.Errorf("failed to remove private key: %w", err)
 		}
 		fmt.Printf("Removed private key: %s\n", keyPath)
 
@@ -213,66 +574,159 @@ Example:
 			}
 		}
 		fmt.Printf("Removed public key: %s\n", pubKeyPath)
+		return nil
+	},
+}
+
+var sshTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test authentication against a git host",
+	Long: `Connect to a git host over SSH and check that it accepts a key.
+Choose the key with --key, --key-name or --fingerprint; otherwise you will be
+prompted to select one from a list, which fails when stdin is not a terminal.
+
+Example:
+  dev-manager ssh test --host github.com --key ~/.ssh/my-key
+  dev-manager ssh test --host gitlab.com`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _ := cmd.Flags().GetString("host")
+		keyPath, err := resolveKey(cmd, "test")
+		if err != nil {
+			return err
+		}
+		if keyPath == "" {
+			return nil
+		}
+
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
+		result, err := mgr.TestConnection(host, keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to test connection: %w", err)
+		}
+
+		if !result.Authenticated {
+			return fmt.Errorf("authentication to %s with %s failed:\n%s", host, keyPath, result.Output)
+		}
+		fmt.Printf("Authenticated to %s with %s\n%s\n", host, keyPath, result.Output)
+		return nil
 	},
 }
 
 var sshListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available SSH key pairs and agent-loaded keys",
-	Run: func(cmd *cobra.Command, args []string) {
-		mgr := newSSHManager()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mgr, err := newSSHManager(cmd)
+		if err != nil {
+			return err
+		}
 
-		fmt.Println("Private SSH keys in ~/.ssh:")
-		keys, err := mgr.ListPrivateKeys()
+		keys, err := mgr.ListKeysWithInfo()
 		if err != nil {
-			log.Fatalf("Failed to list SSH keys: %v", err)
+			return fmt.Errorf("Failed to list SSH keys: %w", err)
+		}
+		// Without an agent the keys on disk are still listed, as status does
+		agentKeys, agentErr := mgr.ListAgentKeys()
+		if agentErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: SSH agent unavailable (%s); listing keys on disk only\n", strings.TrimSpace(agentErr.Error()))
 		}
+
+		inAgent := make(map[string]bool, len(agentKeys))
+		for _, k := range agentKeys {
+			inAgent[k.Fingerprint] = true
+		}
+
+		fmt.Printf("Private SSH keys in %s:\n", mgr.KeyDir)
 		if len(keys) == 0 {
 			fmt.Println("  (none found)")
-		} else {
-			// Get agent keys first
-			agentKeys, err := mgr.ListAgentKeys()
-			if err != nil {
-				log.Fatalf("Failed to list agent keys: %v", err)
+		}
+		onDisk := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			if k.Fingerprint == "" {
+				fmt.Printf("  %s (status unknown)\n", k.Path)
+				continue
 			}
+			onDisk[k.Fingerprint] = true
+
+			status := "not in agent"
+			switch {
+			case agentErr != nil:
+				status = "agent unavailable"
+			case inAgent[k.Fingerprint]:
+				status = "in agent"
+			}
+			fmt.Printf("  %s %s %d %s (%s)\n", k.Path, k.Type, k.Bits, k.Fingerprint, status)
+		}
 
-			// Print each key with its agent status
-			for _, k := range keys {
-				fingerprint, err := mgr.GetKeyFingerprint(k)
-				if err != nil {
-					log.Printf("Warning: failed to get fingerprint for %s: %v", k, err)
-					fmt.Printf("  %s (status unknown)\n", k)
-					continue
-				}
-
-				status := "not in agent"
-				if _, inAgent := agentKeys[fingerprint]; inAgent {
-					status = "in agent"
-				}
-				fmt.Printf("  %s (%s)\n", k, status)
+		// Keys the agent holds that were not found on disk
+		var agentOnly []ssh.KeyInfo
+		for _, k := range agentKeys {
+			if !onDisk[k.Fingerprint] {
+				agentOnly = append(agentOnly, k)
 			}
 		}
+		if len(agentOnly) > 0 {
+			fmt.Println("\nOther keys loaded in the agent:")
+			for _, k := range agentOnly {
+				fmt.Printf("  %s %s %d %s\n", k.Comment, k.Type, k.Bits, k.Fingerprint)
+			}
+		}
+		if agentErr == nil {
+			fmt.Printf("\n%d key(s) loaded in the agent.\n", len(agentKeys))
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(sshCmd)
+	sshCmd.PersistentFlags().String("ssh-dir", "", "Directory holding SSH keys (default ~/.ssh)")
 
 	sshCmd.AddCommand(sshGenerateCmd)
-	sshGenerateCmd.Flags().StringP("algo", "a", "ed25519", "Key generation algorithm (rsa, ed25519)")
+	sshGenerateCmd.Flags().StringP("algo", "a", "ed25519", "Key generation algorithm (ed25519, ecdsa, rsa)")
 	sshGenerateCmd.Flags().StringP("name", "n", "", "Name of the key")
+	sshGenerateCmd.Flags().IntP("bits", "b", 0, "Key size in bits for rsa or ecdsa keys (e.g. 4096)")
+	sshGenerateCmd.Flags().StringP("comment", "C", "", "Comment to embed in the key (e.g. your email)")
+	sshGenerateCmd.Flags().Bool("to-agent", false, "Add the generated key to the SSH agent")
+	sshGenerateCmd.Flags().Duration("lifetime", 0, "With --to-agent, remove the key from the agent after this duration (e.g. 1h)")
+	sshGenerateCmd.Flags().Bool("confirm", false, "With --to-agent, require confirmation each time the key is used")
 
 	sshCmd.AddCommand(sshAddAgentCmd)
 	sshAddAgentCmd.Flags().StringP("key", "k", "", "Path to the private key")
+	sshAddAgentCmd.Flags().Duration("lifetime", 0, "Remove the key from the agent after this duration (e.g. 1h)")
+	sshAddAgentCmd.Flags().Bool("confirm", false, "Require confirmation each time the key is used")
+	sshCmd.AddCommand(sshAddAllCmd)
+	sshAddAllCmd.Flags().Duration("lifetime", 0, "Remove the keys from the agent after this duration (e.g. 1h)")
+	sshAddAllCmd.Flags().Bool("confirm", false, "Require confirmation each time a key is used")
 
 	sshCmd.AddCommand(sshPrintPublicCmd)
-	sshPrintPublicCmd.Flags().StringP("key", "k", "", "Path to the private key")
+	addKeyFlags(sshPrintPublicCmd)
 
 	sshCmd.AddCommand(sshCopyPublicCmd)
-	sshCopyPublicCmd.Flags().StringP("key", "k", "", "Path to the private key")
+	addKeyFlags(sshCopyPublicCmd)
+
+	sshCmd.AddCommand(sshUploadCmd)
+	sshUploadCmd.Flags().String("provider", "github", "Git hosting provider (github)")
+	addKeyFlags(sshUploadCmd)
+	sshUploadCmd.Flags().String("title", "", "Title for the key on the provider (default: key file name and hostname)")
+
+	sshCmd.AddCommand(sshRotateCmd)
+	sshRotateCmd.Flags().String("provider", "github", "Git hosting provider (github)")
+	sshRotateCmd.Flags().StringP("name", "n", "", "Name of the key to rotate, as given to generate")
+	addKeyFlags(sshRotateCmd)
+	sshRotateCmd.Flags().String("title", "", "Title for the new key on the provider (default: key file name and hostname)")
+	sshRotateCmd.Flags().Duration("lifetime", 0, "Remove the new key from the agent after this duration (e.g. 1h)")
+	sshRotateCmd.Flags().Bool("confirm", false, "Require confirmation each time the new key is used")
 
 	sshCmd.AddCommand(sshRemoveCmd)
-	sshRemoveCmd.Flags().StringP("key", "k", "", "Path to the private key")
+	addKeyFlags(sshRemoveCmd)
 
 	sshCmd.AddCommand(sshListCmd)
+
+	sshCmd.AddCommand(sshTestCmd)
+	sshTestCmd.Flags().String("host", "github.com", "Git host to connect to")
+	addKeyFlags(sshTestCmd)
 }
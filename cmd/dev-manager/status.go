@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"dev-manager/internal/ssh"
+	"dev-manager/pkg/app"
+	"dev-manager/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// workspaceStatus is what status reports, and prints with --output json
+type workspaceStatus struct {
+	Workspace    string          `json:"workspace"`
+	Repositories app.RepoSummary `json:"repositories"`
+	Dependencies app.DepSummary  `json:"dependencies"`
+	SSH          sshAgentStatus  `json:"ssh"`
+}
+
+// sshAgentStatus summarizes the keys loaded in the SSH agent
+type sshAgentStatus struct {
+	AgentKeys int `json:"agentKeys"`
+	// Error says why the agent couldn't be queried, e.g. none is running
+	Error string `json:"error,omitempty"`
+}
+
+// healthy reports whether nothing in s needs attention
+func (s workspaceStatus) healthy() bool {
+	r, d := s.Repositories, s.Dependencies
+	return r.NotCloned+r.Dirty+r.Behind+r.Unreadable == 0 && d.Outdated+d.Missing == 0 &&
+		s.SSH.Error == "" && s.SSH.AgentKeys > 0
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Summarize the state of the whole workspace",
+	Long: `Show at a glance whether the environment is healthy: how many repositories
+are configured and how many of them aren't cloned, have uncommitted changes or
+are behind their upstream, how many dependencies are installed, outdated or
+missing, and how many keys the SSH agent holds. Nothing is fetched, so
+"behind" reflects the last sync; use "repos status", "deps verify" and
+"ssh list" for the details. Use --output json to get the summary as JSON,
+e.g. for scripts.
+
+Example:
+  dev-manager status
+  dev-manager status -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		mgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := mgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := mgr.GetConfig()
+		status := workspaceStatus{
+			Workspace:    cfg.WorkspacePath,
+			Repositories: app.SummarizeRepos(cfg),
+			Dependencies: app.SummarizeDeps(app.DepsManager(cfg), cfg),
+			SSH:          agentStatus(),
+		}
+
+		if format == outputJSON {
+			return printJSON(status)
+		}
+
+		r, d := status.Repositories, status.Dependencies
+		fmt.Printf("Workspace:    %s\n", status.Workspace)
+		fmt.Printf("Repositories: %d%s\n", r.Total, details(
+			count{r.NotCloned, "not cloned"}, count{r.Dirty, "dirty"}, count{r.Behind, "behind"}, count{r.Unreadable, "unreadable"}))
+		fmt.Printf("Dependencies: %d%s\n", d.Total, details(
+			count{d.Installed, "installed"}, count{d.Outdated, "outdated"}, count{d.Missing, "missing"}))
+		if status.SSH.Error != "" {
+			fmt.Printf("SSH agent:    unavailable (%s)\n", status.SSH.Error)
+		} else {
+			fmt.Printf("SSH agent:    %d key(s) loaded\n", status.SSH.AgentKeys)
+		}
+
+		if status.healthy() {
+			fmt.Println("\nEverything looks healthy.")
+		} else {
+			fmt.Println("\nSome things need attention; see repos status, deps verify and ssh list.")
+		}
+		return nil
+	},
+}
+
+// agentStatus counts the keys loaded in the SSH agent, recording why it
+// couldn't be queried rather than failing
+func agentStatus() sshAgentStatus {
+	mgr, err := ssh.NewSSHManager()
+	if err != nil {
+		return sshAgentStatus{Error: err.Error()}
+	}
+	keys, err := mgr.ListAgentKeys()
+	if err != nil {
+		return sshAgentStatus{Error: strings.TrimSpace(err.Error())}
+	}
+	return sshAgentStatus{AgentKeys: len(keys)}
+}
+
+// count is a labelled number in a status line
+type count struct {
+	n     int
+	label string
+}
+
+// details formats counts as " (2 dirty, 1 behind)", leaving out zero counts
+func details(counts ...count) string {
+	var parts []string
+	for _, c := range counts {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func init() {
+	addOutputFlag(statusCmd)
+	rootCmd.AddCommand(statusCmd)
+}
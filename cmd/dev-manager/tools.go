@@ -0,0 +1,382 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/tools"
+
+	"github.com/spf13/cobra"
+)
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Manage tool configurations",
+	Long: `Commands for managing tool configurations (nvim, tmux, zsh).
+
+Each tool in the config file links its configPath to a managed source, for
+example a file in your dotfiles repository:
+
+  tools:
+    - name: tmux
+      source: ~/dev/dotfiles/tmux.conf
+      configPath: ~/.tmux.conf
+      backupPath: ~/.tmux.conf.bak`,
+}
+
+var toolsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a tool configuration to manage",
+	Long: `Register a tool whose config dev-manager should manage.
+Paths may start with ~ or be relative; they are stored as absolute paths.
+
+Example:
+  dev-manager tools add --name nvim --config-path ~/.config/nvim --backup-path ~/.config/nvim.bak
+  dev-manager tools add --name tmux --config-path ~/.tmux.conf --backup-path ~/.tmux.conf.bak --source ~/dev/dotfiles/tmux.conf`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+
+		if name == "" {
+			return fmt.Errorf("tool name is required (--name)")
+		}
+
+		var paths [3]string
+		for i, flag := range []string{"config-path", "backup-path", "source"} {
+			value, _ := cmd.Flags().GetString(flag)
+			if value == "" {
+				continue
+			}
+			expanded, err := config.ExpandPath(value)
+			if err != nil {
+				return fmt.Errorf("failed to expand --%s: %w", flag, err)
+			}
+			if paths[i], err = filepath.Abs(expanded); err != nil {
+				return fmt.Errorf("failed to resolve --%s: %w", flag, err)
+			}
+		}
+		if paths[0] == "" {
+			return fmt.Errorf("config path is required (--config-path)")
+		}
+
+		cfgMgr, err := config.NewManager(cfgPath)
+		if err != nil {
+			return fmt.Errorf("failed to create config manager: %w", err)
+		}
+
+		if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cfg := cfgMgr.GetConfig()
+
+		// Check if tool already exists
+		for _, tool := range cfg.Tools {
+			if tool.Name == name {
+				return fmt.Errorf("tool %s already exists in configuration", name)
+			}
+		}
+
+		cfg.Tools = append(cfg.Tools, config.ToolConfig{
+			Name:       name,
+			ConfigPath: paths[0],
+			BackupPath: paths[1],
+			Source:     paths[2],
+		})
+
+		if err := cfgMgr.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		fmt.Printf("Added tool %s managing %s\n", name, paths[0])
+		return nil
+	},
+}
+
+var toolsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Link tool configurations to their managed sources",
+	Long: `Symlink each tool's configPath to its source. An existing config is
+moved to backupPath first; configs that are already linked are left alone.
+
+Example:
+  dev-manager tools sync
+  dev-manager tools sync --name nvim`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+		return syncTools(cfgPath, name)
+	},
+}
+
+var toolsDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show where deployed tool configurations differ from their sources",
+	Long: `Compare each tool's deployed config with its managed source and print a
+unified diff of the differences, or "in sync" when they match. Lines added in
+the diff are local edits that still need to be copied back to the source.
+Directories such as the nvim config are compared file by file; configs that
+tools sync linked to their source are always in sync.
+
+Example:
+  dev-manager tools diff
+  dev-manager tools diff --name nvim`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+
+		toolList, err := loadTools(cfgPath)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			tool, err := findTool(toolList, name)
+			if err != nil {
+				return err
+			}
+			toolList = []config.ToolConfig{tool}
+		}
+
+		for _, tool := range toolList {
+			if tool.Source == "" && name == "" {
+				fmt.Printf("%s: no source configured, skipping\n", tool.Name)
+				continue
+			}
+			result, err := tools.Diff(tool)
+			if err != nil {
+				return fmt.Errorf("failed to diff %s: %w", tool.Name, err)
+			}
+
+			switch {
+			case result.Linked:
+				fmt.Printf("%s: in sync (%s links to %s)\n", tool.Name, tool.ConfigPath, tool.Source)
+			case result.Missing:
+				fmt.Printf("%s: nothing deployed at %s\n", tool.Name, tool.ConfigPath)
+			case result.Diff == "":
+				fmt.Printf("%s: in sync\n", tool.Name)
+			default:
+				fmt.Printf("%s: %s differs from %s\n%s", tool.Name, tool.ConfigPath, tool.Source, result.Diff)
+			}
+		}
+		return nil
+	},
+}
+
+// newToolCmd creates the shortcut command that syncs a single tool
+func newToolCmd(name string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: fmt.Sprintf("Sync %s configuration", name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfgPath, _ := cmd.Flags().GetString("file")
+			return syncTools(cfgPath, name)
+		},
+	}
+}
+
+var (
+	nvimCmd = newToolCmd("nvim")
+	tmuxCmd = newToolCmd("tmux")
+	zshCmd  = newToolCmd("zsh")
+)
+
+var toolsBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Snapshot tool configurations",
+	Long: `Copy each tool's current config to a timestamped snapshot next to its
+backupPath, e.g. ~/.zshrc.bak.20240101-120000. Directories such as the nvim
+config are copied recursively.
+
+Example:
+  dev-manager tools backup
+  dev-manager tools backup --name zsh`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+
+		toolList, err := loadTools(cfgPath)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			tool, err := findTool(toolList, name)
+			if err != nil {
+				return err
+			}
+			toolList = []config.ToolConfig{tool}
+		}
+
+		for _, tool := range toolList {
+			if tool.BackupPath == "" {
+				fmt.Printf("%s: no backupPath configured, skipping\n", tool.Name)
+				continue
+			}
+			backup, err := tools.CreateBackup(tool)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: backed up %s to %s\n", tool.Name, tool.ConfigPath, backup.Path)
+		}
+		return nil
+	},
+}
+
+var toolsRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a tool configuration from a backup",
+	Long: `Replace a tool's config with one of its snapshots.
+If no backup is specified with --backup, you will be prompted to select one from a list.
+
+Example:
+  dev-manager tools restore --name zsh
+  dev-manager tools restore --name zsh --backup ~/.zshrc.bak.20240101-120000`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath, _ := cmd.Flags().GetString("file")
+		name, _ := cmd.Flags().GetString("name")
+		backupPath, _ := cmd.Flags().GetString("backup")
+
+		if name == "" {
+			return fmt.Errorf("tool name is required (--name)")
+		}
+
+		toolList, err := loadTools(cfgPath)
+		if err != nil {
+			return err
+		}
+		tool, err := findTool(toolList, name)
+		if err != nil {
+			return err
+		}
+
+		var backup tools.Backup
+		if backupPath != "" {
+			backup = tools.Backup{Path: backupPath}
+		} else {
+			backups, err := tools.ListBackups(tool)
+			if err != nil {
+				return fmt.Errorf("failed to list backups: %w", err)
+			}
+			if len(backups) == 0 {
+				return fmt.Errorf("no backups found for %s", name)
+			}
+
+			fmt.Printf("Backups of %s:\n", name)
+			for i, b := range backups {
+				fmt.Printf("%d. %s (%s)\n", i+1, b.Path, b.Time.Format("2006-01-02 15:04:05"))
+			}
+
+			fmt.Print("\nSelect a backup to restore (number): ")
+			var selection int
+			fmt.Scanln(&selection)
+
+			if selection < 1 || selection > len(backups) {
+				return fmt.Errorf("invalid selection")
+			}
+			backup = backups[selection-1]
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Overwrite %s with %s?", tool.ConfigPath, backup.Path), false) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		if err := tools.Restore(tool, backup); err != nil {
+			return err
+		}
+		fmt.Printf("%s: restored %s from %s\n", tool.Name, tool.ConfigPath, backup.Path)
+		return nil
+	},
+}
+
+// loadTools returns the tools from the configuration file
+func loadTools(cfgPath string) ([]config.ToolConfig, error) {
+	cfgMgr, err := config.NewManager(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config manager: %w", err)
+	}
+
+	if err := cfgMgr.Load(); err != nil && !errors.Is(err, config.ErrConfigNotFound) {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return cfgMgr.GetConfig().Tools, nil
+}
+
+// findTool returns the tool with the given name
+func findTool(toolList []config.ToolConfig, name string) (config.ToolConfig, error) {
+	for _, tool := range toolList {
+		if tool.Name == name {
+			return tool, nil
+		}
+	}
+	return config.ToolConfig{}, fmt.Errorf("tool %s is not configured", name)
+}
+
+// syncTools links the managed config of every tool, or only of the named one
+func syncTools(cfgPath, name string) error {
+	toolList, err := loadTools(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, tool := range toolList {
+		if name != "" && tool.Name != name {
+			continue
+		}
+		found = true
+
+		if tool.Source == "" && name == "" {
+			fmt.Printf("%s: no source configured, skipping\n", tool.Name)
+			continue
+		}
+
+		result, err := tools.Sync(tool)
+		if err != nil {
+			return fmt.Errorf("failed to sync %s: %w", tool.Name, err)
+		}
+
+		switch {
+		case !result.Changed:
+			fmt.Printf("%s: %s already linked\n", tool.Name, tool.ConfigPath)
+		case result.BackupPath != "":
+			fmt.Printf("%s: linked %s -> %s (previous config moved to %s)\n", tool.Name, tool.ConfigPath, tool.Source, result.BackupPath)
+		default:
+			fmt.Printf("%s: linked %s -> %s\n", tool.Name, tool.ConfigPath, tool.Source)
+		}
+	}
+
+	if name != "" && !found {
+		return fmt.Errorf("tool %s is not configured", name)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(toolsCmd)
+
+	toolsCmd.AddCommand(toolsAddCmd)
+	toolsAddCmd.Flags().StringP("name", "n", "", "Name of the tool")
+	toolsAddCmd.Flags().String("config-path", "", "Path of the tool's live config")
+	toolsAddCmd.Flags().String("backup-path", "", "Where to back up the existing config")
+	toolsAddCmd.Flags().String("source", "", "Managed config to link into place, e.g. in a dotfiles repository")
+
+	toolsCmd.AddCommand(toolsSyncCmd)
+	toolsSyncCmd.Flags().StringP("name", "n", "", "Only sync the tool with this name")
+
+	toolsCmd.AddCommand(toolsDiffCmd)
+	toolsDiffCmd.Flags().StringP("name", "n", "", "Only diff the tool with this name")
+
+	toolsCmd.AddCommand(toolsBackupCmd)
+	toolsBackupCmd.Flags().StringP("name", "n", "", "Only back up the tool with this name")
+
+	toolsCmd.AddCommand(toolsRestoreCmd)
+	toolsRestoreCmd.Flags().StringP("name", "n", "", "Name of the tool to restore")
+	toolsRestoreCmd.Flags().String("backup", "", "Path of the backup to restore")
+
+	toolsCmd.AddCommand(nvimCmd)
+	toolsCmd.AddCommand(tmuxCmd)
+	toolsCmd.AddCommand(zshCmd)
+}
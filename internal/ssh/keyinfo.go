@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyInfo describes an SSH key on disk or loaded in the agent
+type KeyInfo struct {
+	// Path is the private key file; empty for agent keys
+	Path        string
+	Type        string
+	Bits        int
+	Fingerprint string
+	Comment     string
+}
+
+// parseKeyLine parses a line of `ssh-keygen -l` or `ssh-add -l` output,
+// which share the format: <bits> <fingerprint> <comment...> (<type>)
+func parseKeyLine(line string) (KeyInfo, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return KeyInfo{}, fmt.Errorf("unexpected key listing format: %q", line)
+	}
+
+	bits, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return KeyInfo{}, fmt.Errorf("unexpected key size in %q", line)
+	}
+	info := KeyInfo{Bits: bits, Fingerprint: fields[1]}
+
+	rest := fields[2:]
+	if n := len(rest); n > 0 && strings.HasPrefix(rest[n-1], "(") && strings.HasSuffix(rest[n-1], ")") {
+		info.Type = strings.Trim(rest[n-1], "()")
+		rest = rest[:n-1]
+	}
+	info.Comment = strings.Join(rest, " ")
+	return info, nil
+}
+
+// ListKeysWithInfo lists the private keys in the key directory along with their type,
+// size, fingerprint and comment. Keys that ssh-keygen cannot read are
+// returned with only their path set.
+func (m *SSHManager) ListKeysWithInfo() ([]KeyInfo, error) {
+	keys, err := m.ListPrivateKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]KeyInfo, 0, len(keys))
+	for _, key := range keys {
+		info, err := m.GetKeyInfo(key)
+		if err != nil {
+			info = KeyInfo{Path: key}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// GetKeyInfo returns the details of a single private key
+func (m *SSHManager) GetKeyInfo(keyPath string) (KeyInfo, error) {
+	output, err := m.runner().Run("ssh-keygen", "-lf", keyPath)
+	if err != nil {
+		return KeyInfo{}, fmt.Errorf("failed to get key fingerprint: %s", string(output))
+	}
+
+	info, err := parseKeyLine(strings.TrimSpace(string(output)))
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	info.Path = keyPath
+	return info, nil
+}
+
+// FindKeyByFingerprint returns the private key in the key directory with the given
+// fingerprint; the "SHA256:" prefix may be left out
+func (m *SSHManager) FindKeyByFingerprint(fingerprint string) (string, error) {
+	keys, err := m.ListKeysWithInfo()
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if key.Fingerprint != "" && (key.Fingerprint == fingerprint || strings.TrimPrefix(key.Fingerprint, "SHA256:") == fingerprint) {
+			return key.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no SSH key with fingerprint %s found", fingerprint)
+}
@@ -2,22 +2,42 @@ package ssh
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 type SSHManager struct {
-	HomeDir string
+	// KeyDir is the directory keys are generated in and listed from
+	KeyDir string
+	// Runner runs ssh, ssh-add and ssh-keygen; nil uses ExecRunner
+	Runner Runner
 }
 
+// NewSSHManager creates a manager for the keys in ~/.ssh
 func NewSSHManager() (*SSHManager, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
-	return &SSHManager{HomeDir: home}, nil
+	return NewSSHManagerWithDir(filepath.Join(home, ".ssh")), nil
+}
+
+// NewSSHManagerWithDir creates a manager for the keys in dir instead of ~/.ssh
+func NewSSHManagerWithDir(dir string) *SSHManager {
+	return &SSHManager{KeyDir: dir, Runner: ExecRunner{}}
+}
+
+// runner returns the Runner to shell out with
+func (m *SSHManager) runner() Runner {
+	if m.Runner == nil {
+		return ExecRunner{}
+	}
+	return m.Runner
 }
 
 // Check if required SSH tools are installed
@@ -36,109 +56,217 @@ func (m *SSHManager) IsAgentRunning() bool {
 	return os.Getenv("SSH_AUTH_SOCK") != ""
 }
 
-// List private keys in ~/.ssh
+// nonKeyFiles are files in an ssh directory that are never private keys
+var nonKeyFiles = []string{"config", "known_hosts", "known_hosts.old", "authorized_keys", "authorized_keys2", "environment"}
+
+// privateKeyHeaderSize is how much of a file is read to look for a PEM or
+// OpenSSH private key header
+const privateKeyHeaderSize = 64
+
+// isPrivateKey reports whether the file at path looks like a private key:
+// it starts with a private key header or has a .pub counterpart
+func isPrivateKey(path string) bool {
+	name := filepath.Base(path)
+	if filepath.Ext(name) == ".pub" || slices.Contains(nonKeyFiles, name) {
+		return false
+	}
+
+	if f, err := os.Open(path); err == nil {
+		buf := make([]byte, privateKeyHeaderSize)
+		n, _ := io.ReadFull(f, buf)
+		f.Close()
+		header := string(buf[:n])
+		if strings.HasPrefix(header, "-----BEGIN ") && strings.Contains(header, "PRIVATE KEY-----") {
+			return true
+		}
+	}
+
+	_, err := os.Stat(path + ".pub")
+	return err == nil
+}
+
+// List private keys in the key directory
 func (m *SSHManager) ListPrivateKeys() ([]string, error) {
-	sshDir := filepath.Join(m.HomeDir, ".ssh")
-	files, err := os.ReadDir(sshDir)
+	files, err := os.ReadDir(m.KeyDir)
 	if err != nil {
 		return nil, err
 	}
 	var keys []string
-	privateSuffixes := []string{"id_ed25519", "id_rsa", "id_ecdsa", "id_dsa"}
 	for _, f := range files {
-		if f.IsDir() {
+		if !f.Type().IsRegular() {
 			continue
 		}
-		name := f.Name()
-		if filepath.Ext(name) == ".pub" {
-			continue
-		}
-		for _, suffix := range privateSuffixes {
-			if len(name) >= len(suffix) && name[len(name)-len(suffix):] == suffix {
-				keys = append(keys, filepath.Join(sshDir, name))
-				break
-			}
+		path := filepath.Join(m.KeyDir, f.Name())
+		if isPrivateKey(path) {
+			keys = append(keys, path)
 		}
 	}
 	return keys, nil
 }
 
 // List keys loaded in the agent
-func (m *SSHManager) ListAgentKeys() (map[string]string, error) {
-	cmd := exec.Command("ssh-add", "-l")
-	output, err := cmd.CombinedOutput()
+func (m *SSHManager) ListAgentKeys() ([]KeyInfo, error) {
+	output, err := m.runner().Run("ssh-add", "-l")
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		if exitCode(err) == 1 {
 			// No identities loaded
-			return make(map[string]string), nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("ssh-add -l failed: %s", string(output))
 	}
 
-	// Parse the output
-	lines := strings.Split(string(output), "\n")
-	agentKeys := make(map[string]string)
-	for _, line := range lines {
+	var keys []KeyInfo
+	for _, line := range strings.Split(string(output), "\n") {
 		if line == "" {
 			continue
 		}
-		// ssh-add -l output format: <key_size> <fingerprint> <comment>
-		parts := strings.Fields(line)
-		if len(parts) >= 3 {
-			fingerprint := parts[1]
-			comment := parts[len(parts)-1]
-			agentKeys[fingerprint] = comment
+		info, err := parseKeyLine(line)
+		if err != nil {
+			return nil, err
 		}
+		keys = append(keys, info)
 	}
-	return agentKeys, nil
+	return keys, nil
 }
 
 // GetKeyFingerprint returns the fingerprint of a private key
 func (m *SSHManager) GetKeyFingerprint(keyPath string) (string, error) {
-	cmd := exec.Command("ssh-keygen", "-lf", keyPath)
-	output, err := cmd.CombinedOutput()
+	info, err := m.GetKeyInfo(keyPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get key fingerprint: %s", string(output))
+		return "", err
 	}
+	return info.Fingerprint, nil
+}
 
-	// ssh-keygen -lf output format: <key_size> <fingerprint> <comment>
-	parts := strings.Fields(string(output))
-	if len(parts) >= 2 {
-		return parts[1], nil
+// AgentOptions controls how a key is held by the agent
+type AgentOptions struct {
+	// Lifetime removes the key from the agent after this duration (ssh-add -t)
+	Lifetime time.Duration
+	// Confirm requires confirmation each time the key is used (ssh-add -c)
+	Confirm bool
+}
+
+// args converts the options into ssh-add arguments
+func (o AgentOptions) args() ([]string, error) {
+	var args []string
+	if o.Lifetime != 0 {
+		if o.Lifetime < time.Second {
+			return nil, fmt.Errorf("invalid key lifetime %s: must be at least 1s", o.Lifetime)
+		}
+		args = append(args, "-t", fmt.Sprintf("%d", int64(o.Lifetime/time.Second)))
 	}
-	return "", fmt.Errorf("unexpected ssh-keygen output format")
+	if o.Confirm {
+		args = append(args, "-c")
+	}
+	return args, nil
 }
 
 // Add a key to the agent
-func (m *SSHManager) AddKeyToAgent(keyPath string) error {
-	cmd := exec.Command("ssh-add", keyPath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-// Generate a new SSH key pair
-func (m *SSHManager) GenerateKey(algo, name string) (string, error) {
-	sshDir := filepath.Join(m.HomeDir, ".ssh")
-	if err := os.MkdirAll(sshDir, 0700); err != nil {
+func (m *SSHManager) AddKeyToAgent(keyPath string, opts AgentOptions) error {
+	args, err := opts.args()
+	if err != nil {
+		return err
+	}
+	return m.runner().RunInteractive("ssh-add", append(args, keyPath)...)
+}
+
+// SupportedAlgorithms lists the key types GenerateKey accepts
+var SupportedAlgorithms = []string{"ed25519", "ecdsa", "rsa"}
+
+// validateKeySize checks that bits is a valid size for the algorithm;
+// 0 selects ssh-keygen's default
+func validateKeySize(algo string, bits int) error {
+	if bits == 0 {
+		return nil
+	}
+	switch algo {
+	case "rsa":
+		if bits < 1024 {
+			return fmt.Errorf("rsa keys must be at least 1024 bits")
+		}
+	case "ecdsa":
+		if bits != 256 && bits != 384 && bits != 521 {
+			return fmt.Errorf("ecdsa keys must be 256, 384 or 521 bits")
+		}
+	case "ed25519":
+		return fmt.Errorf("ed25519 keys have a fixed size; bits cannot be set")
+	}
+	return nil
+}
+
+// Generate a new SSH key pair. A bits value of 0 uses ssh-keygen's default
+// size and an empty comment keeps ssh-keygen's default (user@host).
+func (m *SSHManager) GenerateKey(algo, name string, bits int, comment string) (string, error) {
+	if err := os.MkdirAll(m.KeyDir, 0700); err != nil {
 		return "", err
 	}
 	keyFile := "id_" + algo
 	if name != "" {
 		keyFile = name + "_id_" + algo
 	}
-	keyPath := filepath.Join(sshDir, keyFile)
-	cmd := exec.Command("ssh-keygen", "-t", algo, "-f", keyPath, "-N", "")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	keyPath := filepath.Join(m.KeyDir, keyFile)
+	if err := m.GenerateKeyAt(algo, keyPath, bits, comment); err != nil {
 		return "", err
 	}
 	return keyPath, nil
 }
 
+// GenerateKeyAt generates a new SSH key pair at keyPath, like GenerateKey
+func (m *SSHManager) GenerateKeyAt(algo, keyPath string, bits int, comment string) error {
+	if !slices.Contains(SupportedAlgorithms, algo) {
+		return fmt.Errorf("unsupported key algorithm %q (supported: %s)", algo, strings.Join(SupportedAlgorithms, ", "))
+	}
+	if err := validateKeySize(algo, bits); err != nil {
+		return err
+	}
+
+	args := []string{"-t", algo, "-f", keyPath, "-N", ""}
+	if bits != 0 {
+		args = append(args, "-b", fmt.Sprintf("%d", bits))
+	}
+	if comment != "" {
+		args = append(args, "-C", comment)
+	}
+	return m.runner().RunInteractive("ssh-keygen", args...)
+}
+
+// FindKey returns the private key in the key directory called name, or generated by
+// GenerateKey under that name (name_id_<algo>)
+func (m *SSHManager) FindKey(name string) (string, error) {
+	keys, err := m.ListPrivateKeys()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, key := range keys {
+		base := filepath.Base(key)
+		if base == name {
+			return key, nil
+		}
+		if strings.HasPrefix(base, name+"_id_") {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no SSH key named %q found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("several SSH keys are named %q: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// RemoveKeyFromAgent removes a key from the agent, identified by its public
+// key next to keyPath
+func (m *SSHManager) RemoveKeyFromAgent(keyPath string) error {
+	output, err := m.runner().Run("ssh-add", "-d", keyPath)
+	if err != nil {
+		return fmt.Errorf("ssh-add -d failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // Print public key and instructions
 func (m *SSHManager) PrintPublicKey(keyPath string) error {
 	pubPath := keyPath + ".pub"
@@ -0,0 +1,42 @@
+package ssh
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Runner runs the ssh tools SSHManager shells out to, so tests can replace
+// them
+type Runner interface {
+	// Run runs a command and returns its combined stdout and stderr
+	Run(name string, args ...string) ([]byte, error)
+	// RunInteractive runs a command connected to the terminal, for commands
+	// that may prompt, such as ssh-add asking for a passphrase
+	RunInteractive(name string, args ...string) error
+}
+
+// ExecRunner runs commands with os/exec
+type ExecRunner struct{}
+
+func (ExecRunner) Run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+func (ExecRunner) RunInteractive(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// exitCode returns the exit code carried by an error from a Runner, or -1
+// when the command didn't run at all
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// authBanners are the messages git hosts print after a successful
+// authentication, in place of a shell
+var authBanners = []string{
+	"successfully authenticated", // GitHub
+	"Welcome to GitLab",          // GitLab
+	"authenticated via",          // Bitbucket
+	"logged in as",               // Bitbucket, older servers
+}
+
+// ConnectionResult is the outcome of a test connection to a git host
+type ConnectionResult struct {
+	Host          string
+	Authenticated bool
+	// Output is what the host and ssh printed
+	Output string
+}
+
+// isAuthenticated reports whether ssh output carries a git host's
+// authentication banner. Hosts such as GitHub exit 1 because they refuse
+// shell access, so the exit code alone says nothing; 255 is ssh's own
+// failure code.
+func isAuthenticated(output string, exitCode int) bool {
+	if exitCode == 255 {
+		return false
+	}
+	for _, banner := range authBanners {
+		if strings.Contains(output, banner) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestConnection checks that the git host accepts the key at keyPath.
+// With an empty keyPath ssh uses its default identities and the agent.
+func (m *SSHManager) TestConnection(host, keyPath string) (ConnectionResult, error) {
+	args := []string{"-T", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=accept-new"}
+	if keyPath != "" {
+		args = append(args, "-i", keyPath, "-o", "IdentitiesOnly=yes")
+	}
+	args = append(args, "git@"+host)
+
+	output, err := m.runner().Run("ssh", args...)
+	code := 0
+	if err != nil {
+		if code = exitCode(err); code < 0 {
+			return ConnectionResult{}, fmt.Errorf("failed to run ssh: %w", err)
+		}
+	}
+
+	out := strings.TrimSpace(string(output))
+	return ConnectionResult{
+		Host:          host,
+		Authenticated: isAuthenticated(out, code),
+		Output:        out,
+	}, nil
+}
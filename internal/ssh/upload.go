@@ -0,0 +1,209 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is returned when the provider rejects the API token
+	ErrUnauthorized = errors.New("provider rejected the API token")
+	// ErrDuplicateKey is returned when the key is already registered
+	ErrDuplicateKey = errors.New("key is already registered with the provider")
+	// ErrKeyNotFound is returned when removing a key the account doesn't have
+	ErrKeyNotFound = errors.New("key is not registered with the provider")
+)
+
+// KeyUploader adds public keys to an account on a git hosting provider
+type KeyUploader interface {
+	UploadKey(ctx context.Context, title, publicKey string) error
+}
+
+// KeyRemover is implemented by uploaders that can also remove a public key
+// from the account, e.g. after rotating it
+type KeyRemover interface {
+	RemoveKey(ctx context.Context, publicKey string) error
+}
+
+// SupportedProviders lists the providers NewKeyUploader accepts
+var SupportedProviders = []string{"github"}
+
+// NewKeyUploader returns the uploader for a provider, reading its API
+// token from the environment
+func NewKeyUploader(provider string) (KeyUploader, error) {
+	switch provider {
+	case "github":
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("GITHUB_TOKEN is not set")
+		}
+		return NewGitHubUploader(token), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q (supported: %s)", provider, strings.Join(SupportedProviders, ", "))
+	}
+}
+
+// KeyTitle derives the title a key is uploaded under from its file name
+func KeyTitle(keyPath string) string {
+	title := filepath.Base(keyPath)
+	if host, err := os.Hostname(); err == nil && host != "" {
+		title += " (" + host + ")"
+	}
+	return title
+}
+
+// GitHubUploader uploads keys through the GitHub REST API
+type GitHubUploader struct {
+	Token   string
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewGitHubUploader creates an uploader for api.github.com
+func NewGitHubUploader(token string) *GitHubUploader {
+	return &GitHubUploader{
+		Token:   token,
+		BaseURL: "https://api.github.com",
+		Client:  http.DefaultClient,
+	}
+}
+
+// githubError is the error body returned by the GitHub API
+type githubError struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// UploadKey adds a public key to the authenticated user's account
+func (u *GitHubUploader) UploadKey(ctx context.Context, title, publicKey string) error {
+	body, err := json.Marshal(map[string]string{
+		"title": title,
+		"key":   strings.TrimSpace(publicKey),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(u.BaseURL, "/")+"/user/keys", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+
+	var apiErr githubError
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusUnprocessableEntity:
+		for _, e := range apiErr.Errors {
+			if strings.Contains(e.Message, "already in use") {
+				return ErrDuplicateKey
+			}
+		}
+	}
+
+	msg := apiErr.Message
+	for _, e := range apiErr.Errors {
+		msg += ": " + e.Message
+	}
+	return fmt.Errorf("GitHub API returned %s: %s", resp.Status, msg)
+}
+
+// githubKey is a key listed by the GitHub API
+type githubKey struct {
+	ID  int64  `json:"id"`
+	Key string `json:"key"`
+}
+
+// RemoveKey deletes a public key from the authenticated user's account. Keys
+// are matched on their type and data, ignoring the comment.
+func (u *GitHubUploader) RemoveKey(ctx context.Context, publicKey string) error {
+	want := keyData(publicKey)
+	for page := 1; ; page++ {
+		resp, err := u.do(ctx, http.MethodGet, fmt.Sprintf("/user/keys?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return err
+		}
+		var keys []githubKey
+		err = json.NewDecoder(resp.Body).Decode(&keys)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse GitHub keys: %w", err)
+		}
+
+		for _, key := range keys {
+			if keyData(key.Key) != want {
+				continue
+			}
+			resp, err := u.do(ctx, http.MethodDelete, fmt.Sprintf("/user/keys/%d", key.ID), nil)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			return nil
+		}
+		if len(keys) < 100 {
+			return ErrKeyNotFound
+		}
+	}
+}
+
+// do sends a request to the GitHub API and returns its response if it
+// succeeded
+func (u *GitHubUploader) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(u.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach GitHub: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var apiErr githubError
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+	return nil, fmt.Errorf("GitHub API returned %s: %s", resp.Status, apiErr.Message)
+}
+
+// keyData returns the type and base64 data of an authorized_keys style
+// public key, without its comment
+func keyData(publicKey string) string {
+	fields := strings.Fields(publicKey)
+	if len(fields) > 2 {
+		fields = fields[:2]
+	}
+	return strings.Join(fields, " ")
+}
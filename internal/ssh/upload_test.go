@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubUploader_UploadKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+		anyErr  bool
+	}{
+		{
+			name:   "created",
+			status: http.StatusCreated,
+			body:   `{"id": 1}`,
+		},
+		{
+			name:    "bad token",
+			status:  http.StatusUnauthorized,
+			body:    `{"message": "Bad credentials"}`,
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:    "duplicate key",
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"message": "Validation Failed", "errors": [{"message": "key is already in use"}]}`,
+			wantErr: ErrDuplicateKey,
+		},
+		{
+			name:   "other validation error",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"message": "Validation Failed", "errors": [{"message": "key is invalid"}]}`,
+			anyErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/user/keys" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+					t.Errorf("Authorization = %q", auth)
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			u := NewGitHubUploader("secret")
+			u.BaseURL = server.URL
+
+			err := u.UploadKey(context.Background(), "my-key", "ssh-ed25519 AAAA me@example.com\n")
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UploadKey() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("UploadKey() expected error")
+				}
+			case err != nil:
+				t.Fatalf("UploadKey() error = %v", err)
+			}
+
+			if got["title"] != "my-key" || got["key"] != "ssh-ed25519 AAAA me@example.com" {
+				t.Errorf("request body = %v", got)
+			}
+		})
+	}
+}
+
+func TestGitHubUploader_RemoveKey(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/user/keys":
+			w.Write([]byte(`[{"id": 1, "key": "ssh-ed25519 AAAA"}, {"id": 2, "key": "ssh-ed25519 BBBB"}]`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	u := NewGitHubUploader("secret")
+	u.BaseURL = server.URL
+
+	if err := u.RemoveKey(context.Background(), "ssh-ed25519 BBBB me@example.com\n"); err != nil {
+		t.Fatalf("RemoveKey() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/user/keys/2" {
+		t.Errorf("deleted %v, want /user/keys/2", deleted)
+	}
+
+	if err := u.RemoveKey(context.Background(), "ssh-ed25519 CCCC"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("RemoveKey() of an unknown key error = %v, want ErrKeyNotFound", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 // MockGitConfig represents the configuration for mock git behavior
@@ -14,9 +16,56 @@ type MockGitConfig struct {
 	Output string `json:"output"`
 	// Error is the stderr output to produce
 	Error string `json:"error"`
+	// Delay is how long to run before producing output and exiting
+	Delay time.Duration `json:"delay,omitempty"`
+	// Commands overrides the behavior for specific git subcommands
+	Commands map[string]MockGitConfig `json:"commands,omitempty"`
+}
+
+// subcommand returns the git subcommand in args, skipping global options
+// such as -C <path> and -c <name>=<value>, and the arguments following it
+func subcommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c" || arg == "--git-dir" || arg == "--work-tree":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg, args[i+1:]
+		}
+	}
+	return "", nil
+}
+
+// record appends the invocation's arguments, as a JSON array, to the log
+// file named by MOCK_GIT_LOG
+func record(args []string) error {
+	logPath := os.Getenv("MOCK_GIT_LOG")
+	if logPath == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
 }
 
 func main() {
+	if err := record(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record invocation: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Read config from environment
 	configJSON := os.Getenv("MOCK_GIT_CONFIG")
 	if configJSON == "" {
@@ -30,6 +79,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	// An override for the subcommand with its first argument, such as
+	// "rebase --abort", takes precedence over one for the subcommand alone
+	sub, rest := subcommand(os.Args[1:])
+	keys := []string{sub}
+	if len(rest) > 0 {
+		keys = []string{sub + " " + rest[0], sub}
+	}
+	for _, key := range keys {
+		if override, ok := config.Commands[key]; ok {
+			config = override
+			break
+		}
+	}
+
+	// Simulate a successful clone creating its target directory
+	if config.ExitCode == 0 && sub == "clone" {
+		if err := os.MkdirAll(os.Args[len(os.Args)-1], 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create clone directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	time.Sleep(config.Delay)
+
 	// Print output to stdout if any
 	if config.Output != "" {
 		fmt.Print(config.Output)
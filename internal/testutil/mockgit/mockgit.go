@@ -6,7 +6,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // MockGit represents a mock git binary
@@ -15,16 +17,25 @@ type MockGit struct {
 	Path string
 	// OriginalPath is the original PATH value
 	OriginalPath string
+	// LogPath is the file the mock appends each invocation's arguments to
+	LogPath string
 }
 
 // Config represents the configuration for mock git behavior
 type Config struct {
 	// ExitCode is the exit code to return
-	ExitCode int
+	ExitCode int `json:"exit_code"`
 	// Output is the stdout output to produce
-	Output string
+	Output string `json:"output"`
 	// Error is the stderr output to produce
-	Error string
+	Error string `json:"error"`
+	// Delay is how long to run before producing output and exiting, e.g. to
+	// let a context expire while git is running
+	Delay time.Duration `json:"delay,omitempty"`
+	// Commands overrides the behavior above for specific git subcommands,
+	// e.g. "fetch" or "rebase", or a subcommand with its first argument, e.g.
+	// "rebase --abort", which takes precedence; their own Commands are ignored
+	Commands map[string]Config `json:"commands,omitempty"`
 }
 
 // New creates a new mock git binary for testing
@@ -41,7 +52,7 @@ func New(t *testing.T) *MockGit {
 	mockPath := filepath.Join(tempDir, "git")
 
 	// Build the mock git binary
-	cmd := exec.Command("go", "build", "-o", mockPath, "github.com/kaanyalti/dev-manager/internal/testutil/mockgit/cmd")
+	cmd := exec.Command("go", "build", "-o", mockPath, "dev-manager/internal/testutil/mockgit/cmd")
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build mock git: %v", err)
 	}
@@ -55,9 +66,51 @@ func New(t *testing.T) *MockGit {
 		t.Fatalf("Failed to set PATH: %v", err)
 	}
 
+	// Record invocations next to the binary
+	logPath := filepath.Join(tempDir, "invocations.log")
+	if err := os.Setenv("MOCK_GIT_LOG", logPath); err != nil {
+		t.Fatalf("Failed to set MOCK_GIT_LOG: %v", err)
+	}
+
 	return &MockGit{
 		Path:         mockPath,
 		OriginalPath: originalPath,
+		LogPath:      logPath,
+	}
+}
+
+// Invocations returns the arguments of every git invocation since New or
+// the last Reset, in order
+func (m *MockGit) Invocations(t *testing.T) [][]string {
+	t.Helper()
+
+	data, err := os.ReadFile(m.LogPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Failed to read invocation log: %v", err)
+	}
+
+	var invocations [][]string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var args []string
+		if err := json.Unmarshal([]byte(line), &args); err != nil {
+			t.Fatalf("Failed to parse invocation %q: %v", line, err)
+		}
+		invocations = append(invocations, args)
+	}
+	return invocations
+}
+
+// Reset forgets the recorded invocations
+func (m *MockGit) Reset(t *testing.T) {
+	t.Helper()
+	if err := os.Remove(m.LogPath); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to reset invocation log: %v", err)
 	}
 }
 
@@ -79,4 +132,5 @@ func (m *MockGit) Configure(t *testing.T, config Config) {
 func (m *MockGit) Cleanup() {
 	os.Setenv("PATH", m.OriginalPath)
 	os.Unsetenv("MOCK_GIT_CONFIG")
+	os.Unsetenv("MOCK_GIT_LOG")
 }
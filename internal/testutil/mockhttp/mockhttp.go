@@ -0,0 +1,217 @@
+// Package mockhttp serves dependency payloads from an httptest server so
+// download and extraction code can be tested without network access.
+package mockhttp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Entry is a file in a generated archive
+type Entry struct {
+	// Name is the path of the entry inside the archive
+	Name string
+	// Body is the file content
+	Body string
+	// Mode is the file mode; 0 means 0644
+	Mode os.FileMode
+	// Symlink makes the entry a symbolic link to this target
+	Symlink string
+	// ModTime is the entry's modification time; zero leaves it unset
+	ModTime time.Time
+}
+
+// mode returns the entry's file mode, applying the default
+func (e Entry) mode() os.FileMode {
+	if e.Mode == 0 {
+		return 0644
+	}
+	return e.Mode
+}
+
+// TarGz builds an in-memory tar.gz archive. Directories named with a trailing
+// slash are written as directory entries.
+func TarGz(t *testing.T, entries ...Entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(Tar(t, entries...)); err != nil {
+		t.Fatalf("Failed to write gzip body: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Tar builds an uncompressed in-memory tar archive, for tests that compress it
+// with something other than gzip
+func Tar(t *testing.T, entries ...Entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: int64(e.mode()), Size: int64(len(e.Body)), Typeflag: tar.TypeReg, ModTime: e.ModTime}
+		switch {
+		case e.Symlink != "":
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.Symlink
+			hdr.Size = 0
+		case strings.HasSuffix(e.Name, "/"):
+			hdr.Typeflag = tar.TypeDir
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.Body)); err != nil {
+				t.Fatalf("Failed to write tar body: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Zip builds an in-memory zip archive
+func Zip(t *testing.T, entries ...Entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate}
+		body := e.Body
+		if e.Symlink != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = e.Symlink
+		} else {
+			hdr.SetMode(e.mode())
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("Failed to write zip header: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("Failed to write zip body: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Server serves a fixed payload, or a fixed error status, for every request
+type Server struct {
+	*httptest.Server
+	requests atomic.Int64
+	header   atomic.Pointer[http.Header]
+	// interrupts is how many more responses are cut off after cutAfter bytes
+	interrupts atomic.Int64
+	cutAfter   atomic.Int64
+}
+
+// New starts a server that responds to every request with payload. It is
+// closed when the test ends.
+func New(t *testing.T, payload []byte) *Server {
+	t.Helper()
+	return newServer(t, http.StatusOK, payload)
+}
+
+// NewError starts a server that responds to every request with status
+func NewError(t *testing.T, status int) *Server {
+	t.Helper()
+	return newServer(t, status, []byte(http.StatusText(status)))
+}
+
+func newServer(t *testing.T, status int, payload []byte) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		header := r.Header.Clone()
+		s.header.Store(&header)
+		w.WriteHeader(status)
+		w.Write(payload)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NewRanged starts a server that serves payload with an ETag and honors
+// Range and If-Range requests, for testing resumed downloads
+func NewRanged(t *testing.T, payload []byte, etag string) *Server {
+	t.Helper()
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		header := r.Header.Clone()
+		s.header.Store(&header)
+		if s.interrupts.Add(-1) >= 0 {
+			w = &cutWriter{ResponseWriter: w, remaining: s.cutAfter.Load()}
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(payload))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Interrupt makes the next count responses of a NewRanged server drop the
+// connection after sending n bytes of the body
+func (s *Server) Interrupt(count int, n int64) {
+	s.cutAfter.Store(n)
+	s.interrupts.Store(int64(count))
+}
+
+// cutWriter aborts the response once it has written its remaining bytes
+type cutWriter struct {
+	http.ResponseWriter
+	remaining int64
+}
+
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > w.remaining {
+		p = p[:w.remaining]
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.remaining -= int64(n)
+	if w.remaining <= 0 {
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	return n, err
+}
+
+// URLFor returns the URL of a file on the server, e.g. for a dependency Source
+func (s *Server) URLFor(name string) string {
+	return s.URL + "/" + strings.TrimPrefix(name, "/")
+}
+
+// Requests returns how many requests the server has handled
+func (s *Server) Requests() int {
+	return int(s.requests.Load())
+}
+
+// LastHeader returns the headers of the most recent request, or nil before
+// the first one
+func (s *Server) LastHeader() http.Header {
+	if h := s.header.Load(); h != nil {
+		return *h
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package mockrunner provides a command runner that records commands and
+// returns canned results, for testing code that shells out through a Runner
+// interface such as ssh.Runner.
+package mockrunner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Response is the canned result of a command
+type Response struct {
+	// Output is the combined stdout and stderr the command produces
+	Output string
+	// ExitCode is the exit code to fail with; 0 succeeds
+	ExitCode int
+}
+
+// ExitError is returned for responses with a non-zero ExitCode; like
+// exec.ExitError it has an ExitCode method
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code the command failed with
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Runner records the commands it is asked to run. The response for a command
+// is looked up by its name and first argument (e.g. "ssh-add -l"), then by its
+// name alone; commands without a response succeed with no output.
+type Runner struct {
+	Responses map[string]Response
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+// Run records the command and returns its canned output
+func (r *Runner) Run(name string, args ...string) ([]byte, error) {
+	resp := r.record(name, args)
+	if resp.ExitCode != 0 {
+		return []byte(resp.Output), &ExitError{Code: resp.ExitCode}
+	}
+	return []byte(resp.Output), nil
+}
+
+// RunInteractive records the command like Run, discarding its output
+func (r *Runner) RunInteractive(name string, args ...string) error {
+	_, err := r.Run(name, args...)
+	return err
+}
+
+func (r *Runner) record(name string, args []string) Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, append([]string{name}, args...))
+
+	if len(args) > 0 {
+		if resp, ok := r.Responses[name+" "+args[0]]; ok {
+			return resp
+		}
+	}
+	return r.Responses[name]
+}
+
+// Calls returns each command run so far, with its name first
+func (r *Runner) Calls() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.calls...)
+}
+
+// Last returns the most recent command as a single string, or "" if none ran
+func (r *Runner) Last() string {
+	calls := r.Calls()
+	if len(calls) == 0 {
+		return ""
+	}
+	return strings.Join(calls[len(calls)-1], " ")
+}
@@ -0,0 +1,122 @@
+// Package textdiff produces unified line diffs without external tools
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edit is one line of a diff: ' ' for a line in both inputs, '-' for a line
+// only in the old input and '+' for one only in the new input
+type edit struct {
+	kind byte
+	text string
+}
+
+// Unified returns a unified diff of old and new, labelled with oldName and
+// newName, keeping context unchanged lines around each change. It returns an
+// empty string when the inputs are equal.
+func Unified(oldName, newName, old, new string, context int) string {
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var changes []int
+	for i, e := range edits {
+		if e.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// Line numbers in each input at the start of every edit
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if e.kind != '+' {
+			oldPos[i+1]++
+		}
+		if e.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for k := 0; k < len(changes); {
+		start := max(changes[k]-context, 0)
+		end := min(changes[k]+context+1, len(edits))
+		// Merge changes whose context overlaps into one hunk
+		for k++; k < len(changes) && changes[k]-context <= end; k++ {
+			end = min(changes[k]+context+1, len(edits))
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[end]-oldPos[start]),
+			hunkRange(newPos[start], newPos[end]-newPos[start]))
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", e.kind, e.text)
+		}
+	}
+	return b.String()
+}
+
+// hunkRange formats a hunk's line range; like diff, an empty range is given
+// by the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines, ignoring a trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a minimal line diff from the longest common subsequence
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
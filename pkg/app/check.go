@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/deps"
+)
+
+// Severity says how serious a problem found by CheckConfig is
+type Severity string
+
+const (
+	// SeverityWarning marks a problem that may be intentional or temporary,
+	// such as a source that can't be reached while offline
+	SeverityWarning Severity = "warning"
+	// SeverityError marks a problem that will make commands fail or clobber
+	// each other's files
+	SeverityError Severity = "error"
+)
+
+// Finding is a problem found by CheckConfig
+type Finding struct {
+	Severity Severity
+	Message  string
+}
+
+// SourceCheckTimeout bounds checking that one dependency source is reachable
+const SourceCheckTimeout = 15 * time.Second
+
+// CheckConfig checks a structurally valid configuration against the
+// filesystem and network: that the workspace exists and is writable, that
+// repositories live inside it without sharing a directory, and that
+// dependency sources can be reached with m.CheckSource.
+func CheckConfig(ctx context.Context, cfg *config.Config, m *deps.Manager) []Finding {
+	var findings []Finding
+	add := func(severity Severity, format string, args ...any) {
+		findings = append(findings, Finding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	workspace := filepath.Clean(cfg.WorkspacePath)
+	if info, err := os.Stat(workspace); err != nil {
+		if os.IsNotExist(err) {
+			add(SeverityWarning, "workspacePath %s does not exist yet; it is created on the first clone", workspace)
+		} else {
+			add(SeverityError, "workspacePath %s can't be read: %v", workspace, err)
+		}
+	} else if !info.IsDir() {
+		add(SeverityError, "workspacePath %s is not a directory", workspace)
+	} else if err := checkWritable(workspace); err != nil {
+		add(SeverityError, "workspacePath %s is not writable: %v", workspace, err)
+	}
+
+	owners := make(map[string]string, len(cfg.Repositories))
+	var paths []string
+	for _, repo := range cfg.Repositories {
+		path := filepath.Clean(repo.Path)
+		if !withinDir(workspace, path) {
+			add(SeverityWarning, "repository %s: path %s is outside workspacePath %s", repo.Name, path, workspace)
+		}
+		if other, ok := owners[path]; ok {
+			add(SeverityError, "repositories %s and %s both use path %s", other, repo.Name, path)
+			continue
+		}
+		owners[path] = repo.Name
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		for _, other := range paths {
+			if other != path && withinDir(path, other) {
+				add(SeverityWarning, "repository %s: path %s is inside repository %s", owners[other], other, owners[path])
+			}
+		}
+	}
+
+	for _, dep := range cfg.Dependencies {
+		if dep.Source == "" {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, SourceCheckTimeout)
+		err := m.CheckSource(checkCtx, dep)
+		cancel()
+
+		var statusErr *deps.SourceStatusError
+		switch {
+		case err == nil:
+		case errors.As(err, &statusErr):
+			add(SeverityError, "dependency %s: %v", dep.Name, err)
+		default:
+			add(SeverityWarning, "dependency %s: %v", dep.Name, err)
+		}
+	}
+	return findings
+}
+
+// checkWritable checks that files can be created in dir
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".dev-manager-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// withinDir reports whether path is dir or inside it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
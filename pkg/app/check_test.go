@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestCheckConfig(t *testing.T) {
+	workspace := t.TempDir()
+	source := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	missing := mockhttp.NewError(t, http.StatusNotFound)
+	headless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer headless.Close()
+
+	cfg := &config.Config{
+		WorkspacePath: workspace,
+		Repositories: []config.Repository{
+			{Name: "api", Path: filepath.Join(workspace, "api")},
+			{Name: "api-copy", Path: filepath.Join(workspace, "api")},
+			{Name: "nested", Path: filepath.Join(workspace, "api", "nested")},
+			{Name: "outside", Path: filepath.Join(t.TempDir(), "outside")},
+		},
+		Dependencies: []config.Dependency{
+			{Name: "ok", Source: source.URLFor("ok")},
+			{Name: "missing", Source: missing.URLFor("missing")},
+			{Name: "headless", Source: headless.URL + "/headless"},
+		},
+	}
+
+	findings := CheckConfig(context.Background(), cfg, DepsManager(cfg))
+
+	var got []Severity
+	for _, f := range findings {
+		got = append(got, f.Severity)
+	}
+	// The duplicate path and missing source are errors; the nested and
+	// outside paths warnings
+	want := []Severity{SeverityError, SeverityWarning, SeverityWarning, SeverityError}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckConfig() severities = %v, want %v", got, want)
+	}
+}
+
+func TestCheckConfig_Workspace(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		workspace string
+		want      Severity
+	}{
+		{name: "missing", workspace: filepath.Join(t.TempDir(), "missing"), want: SeverityWarning},
+		{name: "not a directory", workspace: file, want: SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{WorkspacePath: tt.workspace}
+			findings := CheckConfig(context.Background(), cfg, DepsManager(cfg))
+			if len(findings) != 1 || findings[0].Severity != tt.want {
+				t.Errorf("CheckConfig() = %+v, want one %s", findings, tt.want)
+			}
+		})
+	}
+
+	cfg := &config.Config{WorkspacePath: t.TempDir()}
+	if findings := CheckConfig(context.Background(), cfg, DepsManager(cfg)); len(findings) != 0 {
+		t.Errorf("CheckConfig() with a writable workspace = %+v, want nothing", findings)
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/deps"
+)
+
+// DepsManager returns a dependency manager installing into cfg's workspace
+func DepsManager(cfg *config.Config) *deps.Manager {
+	return deps.New(filepath.Join(cfg.WorkspacePath, "deps"))
+}
+
+// DepInstallResult is the outcome of syncing one dependency
+type DepInstallResult struct {
+	Dependency config.Dependency
+	// Status is StatusInstalled, StatusUpToDate when it was already
+	// installed, StatusPlanned in dry-run mode, StatusFailed, or
+	// StatusNotAttempted when an earlier failure stopped the sync
+	Status Status
+	Err    error
+	// Links are the links created in the bin directory with DepSyncOptions.Link
+	Links []string
+	// Duration is how long installing and linking took
+	Duration time.Duration
+}
+
+// DepSyncOptions controls SyncDependencies
+type DepSyncOptions struct {
+	// Link symlinks the binaries of installed dependencies into the
+	// manager's bin directory
+	Link bool
+	// Name, if set, syncs only the dependency with that name
+	Name string
+	// Force reinstalls dependencies that are already installed and, with
+	// Link, replaces links to other dependencies' binaries
+	Force bool
+	// Progress, if set, is called as each dependency is skipped or installed
+	Progress func(DepInstallResult)
+}
+
+// SyncDependencies installs the dependencies of cfg that aren't installed,
+// or whose source changed since they were, stopping at the first failure. The
+// results cover every selected dependency, including those the failure left
+// unattempted. With m.DryRun nothing is installed and the results list what
+// would be.
+func SyncDependencies(ctx context.Context, m *deps.Manager, cfg *config.Config, opts DepSyncOptions) ([]DepInstallResult, error) {
+	selected := cfg.Dependencies
+	if opts.Name != "" {
+		dep, ok := cfg.FindDependency(opts.Name)
+		if !ok {
+			return nil, fmt.Errorf("dependency %s not found in configuration", opts.Name)
+		}
+		selected = []config.Dependency{*dep}
+	}
+
+	results := make([]DepInstallResult, 0, len(selected))
+	for i, dep := range selected {
+		if err := ctx.Err(); err != nil {
+			for _, rest := range selected[i:] {
+				results = append(results, DepInstallResult{Dependency: rest, Status: StatusNotAttempted})
+			}
+			return results, err
+		}
+
+		result := syncDependency(ctx, m, dep, opts)
+		report(opts.Progress, result)
+		results = append(results, result)
+		if result.Err != nil {
+			for _, rest := range selected[i+1:] {
+				results = append(results, DepInstallResult{Dependency: rest, Status: StatusNotAttempted})
+			}
+			return results, result.Err
+		}
+	}
+	return results, nil
+}
+
+// syncDependency installs and links one dependency for SyncDependencies
+func syncDependency(ctx context.Context, m *deps.Manager, dep config.Dependency, opts DepSyncOptions) (result DepInstallResult) {
+	result.Dependency = dep
+	reinstall := opts.Force || m.NeedsReinstall(dep)
+	if m.IsInstalled(dep) && !reinstall {
+		result.Status = StatusUpToDate
+		return result
+	}
+
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+	if err := m.InstallContext(ctx, dep, reinstall); err != nil {
+		result.Status, result.Err = StatusFailed, fmt.Errorf("failed to install %s: %w", dep.Name, err)
+		return result
+	}
+	if m.DryRun {
+		result.Status = StatusPlanned
+		return result
+	}
+	if opts.Link {
+		links, err := m.Link(dep, m.BinDir(), opts.Force)
+		if err != nil {
+			result.Status, result.Err = StatusFailed, fmt.Errorf("failed to link %s: %w", dep.Name, err)
+			return result
+		}
+		result.Links = links
+	}
+	result.Status = StatusInstalled
+	return result
+}
+
+// InstallDependency installs the dependency of cfg with the given name. An
+// installation is only replaced when force is set or its source changed since
+// it was made; otherwise the error wraps deps.ErrAlreadyInstalled.
+func InstallDependency(ctx context.Context, m *deps.Manager, cfg *config.Config, name string, force bool) (config.Dependency, error) {
+	dep, ok := cfg.FindDependency(name)
+	if !ok {
+		return config.Dependency{}, fmt.Errorf("dependency %s not found in configuration", name)
+	}
+	if err := ctx.Err(); err != nil {
+		return *dep, err
+	}
+	if err := m.InstallContext(ctx, *dep, force || m.NeedsReinstall(*dep)); err != nil {
+		return *dep, fmt.Errorf("failed to install %s: %w", name, err)
+	}
+	return *dep, nil
+}
+
+// report passes result to progress, if set
+func report(progress func(DepInstallResult), result DepInstallResult) {
+	if progress != nil {
+		progress(result)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/deps"
+)
+
+func TestSyncDependencies(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+	cfg := &config.Config{
+		WorkspacePath: t.TempDir(),
+		Dependencies: []config.Dependency{
+			{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")},
+			{Name: "other", Version: "1.0.0", Source: server.URLFor("other.tar.gz")},
+		},
+	}
+	m := DepsManager(cfg)
+
+	if _, err := InstallDependency(context.Background(), m, cfg, "tool", false); err != nil {
+		t.Fatalf("InstallDependency() error = %v", err)
+	}
+	if _, err := InstallDependency(context.Background(), m, cfg, "tool", false); !errors.Is(err, deps.ErrAlreadyInstalled) {
+		t.Errorf("InstallDependency() again error = %v, want ErrAlreadyInstalled", err)
+	}
+	if _, err := InstallDependency(context.Background(), m, cfg, "tool", true); err != nil {
+		t.Errorf("InstallDependency(force) error = %v", err)
+	}
+	if _, err := InstallDependency(context.Background(), m, cfg, "missing", false); err == nil {
+		t.Error("InstallDependency() of an unknown dependency should fail")
+	}
+
+	results, err := SyncDependencies(context.Background(), m, cfg, DepSyncOptions{Link: true})
+	if err != nil {
+		t.Fatalf("SyncDependencies() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("SyncDependencies() returned %d results, want 2", len(results))
+	}
+	if results[0].Status != StatusUpToDate {
+		t.Error("already installed tool was not skipped")
+	}
+	if results[1].Status != StatusInstalled || !m.IsInstalled(cfg.Dependencies[1]) {
+		t.Error("other was not installed")
+	}
+	if len(results[1].Links) != 1 {
+		t.Errorf("other links = %v, want its binary linked", results[1].Links)
+	}
+
+	// A source change is picked up by the next sync
+	if err := m.Invalidate(cfg.Dependencies[0]); err != nil {
+		t.Fatal(err)
+	}
+	results, err = SyncDependencies(context.Background(), m, cfg, DepSyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncDependencies() error = %v", err)
+	}
+	if results[0].Status != StatusInstalled || results[1].Status != StatusUpToDate {
+		t.Errorf("SyncDependencies() after invalidating tool = %+v, want only tool reinstalled", results)
+	}
+}
+
+func TestSyncDependencies_Name(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+	cfg := &config.Config{
+		WorkspacePath: t.TempDir(),
+		Dependencies: []config.Dependency{
+			{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")},
+			{Name: "other", Version: "1.0.0", Source: server.URLFor("other.tar.gz")},
+		},
+	}
+	m := DepsManager(cfg)
+
+	results, err := SyncDependencies(context.Background(), m, cfg, DepSyncOptions{Name: "other"})
+	if err != nil {
+		t.Fatalf("SyncDependencies(other) error = %v", err)
+	}
+	if len(results) != 1 || results[0].Dependency.Name != "other" || m.IsInstalled(cfg.Dependencies[0]) {
+		t.Fatalf("SyncDependencies(other) = %+v, want only other installed", results)
+	}
+
+	if results, err = SyncDependencies(context.Background(), m, cfg, DepSyncOptions{Name: "other"}); err != nil || results[0].Status != StatusUpToDate {
+		t.Errorf("SyncDependencies(other) again = %+v, %v, want it skipped", results, err)
+	}
+	if results, err = SyncDependencies(context.Background(), m, cfg, DepSyncOptions{Name: "other", Force: true}); err != nil || results[0].Status != StatusInstalled {
+		t.Errorf("SyncDependencies(other, force) = %+v, %v, want it reinstalled", results, err)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("downloads = %d, want the forced reinstall served from the cache", server.Requests())
+	}
+
+	if _, err := SyncDependencies(context.Background(), m, cfg, DepSyncOptions{Name: "missing"}); err == nil {
+		t.Error("SyncDependencies() of an unknown dependency should fail")
+	}
+}
+
+func TestSyncDependencies_Failure(t *testing.T) {
+	good := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	bad := mockhttp.NewError(t, http.StatusNotFound)
+	cfg := &config.Config{
+		WorkspacePath: t.TempDir(),
+		Dependencies: []config.Dependency{
+			{Name: "first", Version: "1.0.0", Source: good.URLFor("first")},
+			{Name: "broken", Version: "1.0.0", Source: bad.URLFor("broken")},
+			{Name: "last", Version: "1.0.0", Source: good.URLFor("last")},
+		},
+	}
+
+	results, err := SyncDependencies(context.Background(), DepsManager(cfg), cfg, DepSyncOptions{})
+	if err == nil {
+		t.Fatal("SyncDependencies() succeeded with a missing source, want error")
+	}
+
+	want := []Status{StatusInstalled, StatusFailed, StatusNotAttempted}
+	if len(results) != len(want) {
+		t.Fatalf("SyncDependencies() returned %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("%s status = %s, want %s", result.Dependency.Name, result.Status, want[i])
+		}
+	}
+	if results[1].Err == nil {
+		t.Error("failed result has no error")
+	}
+	if results[0].Duration <= 0 {
+		t.Errorf("Duration of installing first = %v, want it timed", results[0].Duration)
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+)
+
+// DefaultHookTimeout bounds a repository hook that doesn't set its own timeout
+const DefaultHookTimeout = 10 * time.Minute
+
+// Hook names, as used in HookError
+const (
+	HookPostClone = "postClone"
+	HookPostSync  = "postSync"
+)
+
+// HookError reports a repository hook that failed or timed out, with the
+// output it produced
+type HookError struct {
+	Repo   string
+	Hook   string
+	Output string
+	Err    error
+}
+
+func (e *HookError) Error() string {
+	msg := fmt.Sprintf("%s hook failed: %v", e.Hook, e.Err)
+	if out := strings.TrimSpace(e.Output); out != "" {
+		msg += "\n" + out
+	}
+	return msg
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+// runHook runs one of a repository's hooks with sh in its directory. An empty
+// command does nothing.
+func runHook(ctx context.Context, repo config.Repository, hook, command string) error {
+	if command == "" {
+		return nil
+	}
+
+	timeout := repo.Hooks.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = repo.Path
+	// Don't wait on children that outlive a killed hook and still hold its output
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"DEV_MANAGER_REPO_NAME="+repo.Name,
+		"DEV_MANAGER_REPO_PATH="+repo.Path,
+		"DEV_MANAGER_HOOK="+hook,
+	)
+	slog.Info("running repository hook", "repo", repo.Name, "hook", hook, "command", command)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return &HookError{Repo: repo.Name, Hook: hook, Output: string(output), Err: err}
+	}
+	slog.Debug("repository hook output", "repo", repo.Name, "hook", hook, "output", string(output))
+	return nil
+}
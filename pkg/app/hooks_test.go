@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dev-manager/internal/testutil/mockgit"
+	"dev-manager/pkg/config"
+)
+
+func TestRunHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		timeout    time.Duration
+		wantErr    bool
+		wantOutput string
+	}{
+		{name: "no hook", command: ""},
+		{name: "success", command: "touch hook-ran"},
+		{name: "failure keeps output", command: "echo npm ERR; exit 3", wantErr: true, wantOutput: "npm ERR"},
+		{name: "timeout", command: "sleep 5", timeout: 50 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := config.Repository{Name: "app", Path: t.TempDir(), Hooks: config.Hooks{Timeout: tt.timeout}}
+
+			err := runHook(context.Background(), repo, HookPostSync, tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var hookErr *HookError
+				if !errors.As(err, &hookErr) || hookErr.Hook != HookPostSync || hookErr.Repo != "app" {
+					t.Fatalf("runHook() error = %#v, want a HookError for app's postSync hook", err)
+				}
+				if !strings.Contains(hookErr.Output, tt.wantOutput) {
+					t.Errorf("HookError.Output = %q, want it to contain %q", hookErr.Output, tt.wantOutput)
+				}
+			}
+			if tt.command == "touch hook-ran" {
+				if _, err := os.Stat(filepath.Join(repo.Path, "hook-ran")); err != nil {
+					t.Error("hook did not run in the repository directory")
+				}
+			}
+		})
+	}
+}
+
+func TestSyncRepoHooks(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	tests := []struct {
+		name    string
+		cloned  bool
+		opts    SyncOptions
+		wantErr bool
+		wantRan string
+	}{
+		{name: "update runs postSync", cloned: true, opts: SyncOptions{AllowHooks: true}, wantRan: HookPostSync},
+		{name: "clone runs postClone", opts: SyncOptions{AllowHooks: true}, wantRan: HookPostClone},
+		{name: "no hooks", cloned: true, opts: SyncOptions{NoHooks: true}},
+		{name: "update refused without trust", cloned: true, wantErr: true},
+		{name: "clone refused without trust", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			repo := config.Repository{
+				Name:   "app",
+				URL:    "https://github.com/test/app",
+				Branch: "main",
+				Path:   filepath.Join(dir, "app"),
+				Hooks: config.Hooks{
+					PostClone: "touch ../" + HookPostClone,
+					PostSync:  "touch ../" + HookPostSync,
+				},
+			}
+			if tt.cloned {
+				if err := os.MkdirAll(repo.Path, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mock.Reset(t)
+			err := SyncRepo(context.Background(), repo, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SyncRepo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(mock.Invocations(t)) != 0 {
+				t.Errorf("refused sync ran git: %v", mock.Invocations(t))
+			}
+
+			for _, hook := range []string{HookPostClone, HookPostSync} {
+				_, err := os.Stat(filepath.Join(dir, hook))
+				if ran := err == nil; ran != (hook == tt.wantRan) {
+					t.Errorf("%s hook ran = %v", hook, ran)
+				}
+			}
+		})
+	}
+}
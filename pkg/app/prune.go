@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/git"
+)
+
+// PruneOptions controls PruneRepos
+type PruneOptions struct {
+	// DryRun reports the unmanaged repositories without deleting any
+	DryRun bool
+	// Confirm is asked before deleting each repository, with whether it has
+	// uncommitted changes; nil deletes without asking
+	Confirm func(dir string, dirty bool) bool
+}
+
+// OrphanedRepos returns the git repositories directly under cfg's workspace
+// that aren't excluded and aren't the path of a repository in cfg or in any of
+// others, e.g. the global config while a project-local one is in use. Paths
+// are compared once ~ is expanded and symlinks are resolved, so a repository
+// configured under another spelling of its path still counts as managed.
+func OrphanedRepos(cfg *config.Config, others ...*config.Config) ([]string, error) {
+	workspace, err := config.ExpandPath(cfg.WorkspacePath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(workspace)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+
+	managed := make(map[string]bool)
+	for _, c := range append([]*config.Config{cfg}, others...) {
+		for _, repo := range c.Repositories {
+			managed[canonicalPath(repo.Path)] = true
+		}
+	}
+
+	var orphans []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(workspace, entry.Name())
+		if managed[canonicalPath(dir)] || cfg.IsExcluded(filepath.Join(cfg.WorkspacePath, entry.Name())) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			continue
+		}
+		orphans = append(orphans, dir)
+	}
+	return orphans, nil
+}
+
+// PruneRepos deletes the given repository directories, as found by
+// OrphanedRepos, and returns the ones it deleted. A dry run returns every
+// directory without asking or deleting anything.
+func PruneRepos(dirs []string, opts PruneOptions) ([]string, error) {
+	if opts.DryRun {
+		return dirs, nil
+	}
+
+	var removed []string
+	for _, dir := range dirs {
+		if opts.Confirm != nil {
+			clean, err := git.New(dir, "", "").IsClean()
+			if !opts.Confirm(dir, err == nil && !clean) {
+				continue
+			}
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to delete %s: %w", dir, err)
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// canonicalPath returns path with ~ expanded and symlinks resolved, as far
+// as they exist, for comparing paths spelled differently
+func canonicalPath(path string) string {
+	if expanded, err := config.ExpandPath(path); err == nil {
+		path = expanded
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dev-manager/pkg/config"
+)
+
+func TestOrphanedRepos(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workspace := filepath.Join(home, "ws")
+
+	for _, dir := range []string{"kept", "tilde", "linked", "global", "orphan", "excluded", "deps"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(workspace, "not-a-repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// linked is configured through a symlink to it from outside the workspace
+	link := filepath.Join(home, "link")
+	if err := os.Symlink(filepath.Join(workspace, "linked"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		WorkspacePath: "~/ws",
+		ExcludePaths:  []string{"excluded"},
+		Repositories: []config.Repository{
+			{Name: "kept", Path: filepath.Join(workspace, "kept")},
+			{Name: "tilde", Path: "~/ws/tilde/"},
+			{Name: "linked", Path: link},
+		},
+	}
+	global := &config.Config{
+		Repositories: []config.Repository{{Name: "global", Path: filepath.Join(workspace, "global")}},
+	}
+
+	got, err := OrphanedRepos(cfg, global)
+	if err != nil {
+		t.Fatalf("OrphanedRepos() error = %v", err)
+	}
+	if want := []string{filepath.Join(workspace, "orphan")}; !slices.Equal(got, want) {
+		t.Errorf("OrphanedRepos() = %v, want %v", got, want)
+	}
+
+	got, err = OrphanedRepos(cfg)
+	if err != nil {
+		t.Fatalf("OrphanedRepos() error = %v", err)
+	}
+	if want := []string{filepath.Join(workspace, "global"), filepath.Join(workspace, "orphan")}; !slices.Equal(got, want) {
+		t.Errorf("OrphanedRepos() without the global config = %v, want %v", got, want)
+	}
+}
+
+func TestPruneRepos(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        PruneOptions
+		wantRemoved []string
+	}{
+		{name: "dry run", opts: PruneOptions{DryRun: true}, wantRemoved: []string{"a", "b"}},
+		{name: "deletes", wantRemoved: []string{"a", "b"}},
+		{
+			name:        "declined",
+			opts:        PruneOptions{Confirm: func(dir string, dirty bool) bool { return filepath.Base(dir) == "b" }},
+			wantRemoved: []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			var dirs []string
+			for _, name := range []string{"a", "b"} {
+				dir := filepath.Join(workspace, name)
+				if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				dirs = append(dirs, dir)
+			}
+
+			removed, err := PruneRepos(dirs, tt.opts)
+			if err != nil {
+				t.Fatalf("PruneRepos() error = %v", err)
+			}
+			var names []string
+			for _, dir := range removed {
+				names = append(names, filepath.Base(dir))
+			}
+			if !slices.Equal(names, tt.wantRemoved) {
+				t.Errorf("PruneRepos() = %v, want %v", names, tt.wantRemoved)
+			}
+
+			for _, dir := range dirs {
+				_, err := os.Stat(dir)
+				deleted := os.IsNotExist(err)
+				if want := !tt.opts.DryRun && slices.Contains(removed, dir); deleted != want {
+					t.Errorf("%s deleted = %v, want %v", dir, deleted, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Package app implements dev-manager's workflows, such as syncing
+// repositories and installing dependencies, independently of the command
+// line, so they can be embedded in other Go programs
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/git"
+)
+
+// GitRepo creates a git repository handle from its configuration
+func GitRepo(repo config.Repository) *git.Repository {
+	r := git.New(repo.Path, repo.URL, repo.Branch)
+	r.UpstreamURL = repo.UpstreamURL
+	r.Ref = repo.Ref
+	r.Recurse = repo.Submodules
+	r.URLScheme = repo.URLScheme
+	if repo.Remote != "" {
+		r.Remote = repo.Remote
+	}
+	return r
+}
+
+// CloneRepo clones a repository and runs its postClone hook, unless
+// opts.NoHooks is set. A hook is only run with opts.AllowHooks; without it,
+// the clone is refused rather than skipping the hook silently.
+func CloneRepo(ctx context.Context, repo config.Repository, opts SyncOptions) error {
+	if err := checkHooksAllowed(repo, opts, repo.Hooks.PostClone); err != nil {
+		return err
+	}
+	if err := GitRepo(repo).CloneContext(ctx); err != nil {
+		return err
+	}
+	if opts.NoHooks {
+		return nil
+	}
+	return runHook(ctx, repo, HookPostClone, repo.Hooks.PostClone)
+}
+
+// SyncRepo pulls the latest changes for a repository, rebasing forks onto
+// their upstream, then runs its postSync hook. A repository that isn't
+// cloned yet is cloned instead, running its postClone hook. Hooks need
+// opts.AllowHooks as CloneRepo's do. Of opts, only NoHooks, AllowHooks and
+// PruneRemotes apply.
+func SyncRepo(ctx context.Context, repo config.Repository, opts SyncOptions) error {
+	if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+		return CloneRepo(ctx, repo, opts)
+	}
+	if err := checkHooksAllowed(repo, opts, repo.Hooks.PostSync); err != nil {
+		return err
+	}
+
+	r := GitRepo(repo)
+	r.Prune = opts.PruneRemotes
+	if err := r.UpdateContext(ctx); err != nil {
+		return err
+	}
+	// Pinned repositories stay at their ref rather than following upstream
+	if r.UpstreamURL != "" && r.Ref == "" {
+		if err := r.SyncUpstreamContext(ctx); err != nil {
+			return err
+		}
+	}
+	if opts.NoHooks {
+		return nil
+	}
+	return runHook(ctx, repo, HookPostSync, repo.Hooks.PostSync)
+}
+
+// checkHooksAllowed refuses to go on when command, a hook about to run, hasn't
+// been allowed. Hooks run arbitrary shell commands, and configs may come from
+// a cloned project or a shared file, so they have to be trusted explicitly.
+func checkHooksAllowed(repo config.Repository, opts SyncOptions, command string) error {
+	if command == "" || opts.NoHooks || opts.AllowHooks {
+		return nil
+	}
+	return fmt.Errorf("%s has a hook that runs %q; rerun with --allow-hooks to permit it or --no-hooks to skip it", repo.Name, command)
+}
+
+// SyncOptions controls SyncRepos and SyncAllRepos
+type SyncOptions struct {
+	// Jobs is how many repositories are synced concurrently, at least one
+	Jobs int
+	// Timeout bounds the sync of a single repository; zero means no limit
+	Timeout time.Duration
+	// IfStale makes SyncAllRepos skip repositories synced within their
+	// update frequency
+	IfStale bool
+	// NoHooks skips the repositories' postClone and postSync hooks
+	NoHooks bool
+	// AllowHooks permits running the hooks. They execute arbitrary shell
+	// commands, so repositories with hooks are refused without it.
+	AllowHooks bool
+	// PruneRemotes deletes remote-tracking refs of branches deleted upstream
+	// while fetching
+	PruneRemotes bool
+	// FailFast makes SyncRepos stop starting syncs once one has failed.
+	// Syncs already running are left to finish.
+	FailFast bool
+	// Progress, if set, is called as each repository finishes. Calls are
+	// serialized, but come from the syncing goroutines.
+	Progress func(RepoSyncResult)
+}
+
+// RepoSyncResult is the outcome of syncing one repository
+type RepoSyncResult struct {
+	Name string
+	// Status is StatusSynced, StatusFailed, or StatusNotAttempted when
+	// SyncOptions.FailFast stopped the batch first
+	Status Status
+	Err    error
+	// Duration is how long the sync took, hooks included
+	Duration time.Duration
+}
+
+// SelectRepos returns the indexes of cfg's repositories that are due for a
+// sync at now, along with those skipped because they were synced within their
+// update frequency. Without ifStale every repository is due.
+func SelectRepos(cfg *config.Config, ifStale bool, now time.Time) (due, skipped []int) {
+	for i, repo := range cfg.Repositories {
+		if ifStale && !cfg.IsStale(repo, now) {
+			skipped = append(skipped, i)
+			continue
+		}
+		due = append(due, i)
+	}
+	return due, skipped
+}
+
+// SyncRepos concurrently syncs the repositories of cfg at the given indexes
+// and sets LastSync on those that succeed. Results are in the order of
+// indexes; saving the updated config is left to the caller.
+func SyncRepos(ctx context.Context, cfg *config.Config, indexes []int, opts SyncOptions) []RepoSyncResult {
+	// Each worker writes only its own slot, so results need no locking
+	results := make([]RepoSyncResult, len(indexes))
+	work := make(chan int)
+	var progressMu sync.Mutex
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+
+	for w := 0; w < min(max(opts.Jobs, 1), len(indexes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				repo := cfg.Repositories[indexes[i]]
+				if opts.FailFast && failed.Load() {
+					results[i] = RepoSyncResult{Name: repo.Name, Status: StatusNotAttempted}
+					continue
+				}
+
+				start := time.Now()
+				repoCtx, cancel := withOptionalTimeout(ctx, opts.Timeout)
+				err := SyncRepo(repoCtx, repo, opts)
+				if repoCtx.Err() == context.DeadlineExceeded {
+					err = fmt.Errorf("timed out after %s", opts.Timeout)
+				}
+				cancel()
+				results[i] = RepoSyncResult{Name: repo.Name, Status: StatusSynced, Err: err, Duration: time.Since(start)}
+				if err != nil {
+					results[i].Status = StatusFailed
+					failed.Store(true)
+				}
+
+				if opts.Progress != nil {
+					progressMu.Lock()
+					opts.Progress(results[i])
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range indexes {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	now := time.Now()
+	for i, result := range results {
+		if result.Status == StatusSynced {
+			cfg.Repositories[indexes[i]].LastSync = now
+		}
+	}
+	return results
+}
+
+// SyncAllRepos syncs every repository of cfg that is due, see SelectRepos
+// and SyncRepos
+func SyncAllRepos(ctx context.Context, cfg *config.Config, opts SyncOptions) []RepoSyncResult {
+	due, _ := SelectRepos(cfg, opts.IfStale, time.Now())
+	return SyncRepos(ctx, cfg, due, opts)
+}
+
+// Orders SortRepos can sort repositories in
+const (
+	SortByName     = "name"
+	SortByLastSync = "lastSync"
+)
+
+// RepoFilter selects repositories, see FilterRepos
+type RepoFilter struct {
+	// Match keeps repositories whose name or URL contains it, ignoring case
+	Match string
+	// Dirty keeps only cloned repositories with uncommitted changes
+	Dirty bool
+}
+
+// FilterRepos returns the repositories matching f, in their original order.
+// Repositories that aren't cloned have no changes, so Dirty leaves them out,
+// as it does those whose state can't be read; the errors reading them are
+// returned joined alongside the matches.
+func FilterRepos(repos []config.Repository, f RepoFilter) ([]config.Repository, error) {
+	match := strings.ToLower(f.Match)
+	var matches []config.Repository
+	var errs []error
+	for _, repo := range repos {
+		if match != "" && !strings.Contains(strings.ToLower(repo.Name), match) &&
+			!strings.Contains(strings.ToLower(repo.URL), match) {
+			continue
+		}
+		if f.Dirty {
+			if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+				continue
+			}
+			clean, err := GitRepo(repo).IsClean()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", repo.Name, err))
+				continue
+			}
+			if clean {
+				continue
+			}
+		}
+		matches = append(matches, repo)
+	}
+	return matches, errors.Join(errs...)
+}
+
+// SortRepos sorts repositories in place by name, or by lastSync with the
+// longest unsynced first. An empty order keeps the configured order.
+func SortRepos(repos []config.Repository, by string) error {
+	switch by {
+	case "":
+	case SortByName:
+		slices.SortStableFunc(repos, func(a, b config.Repository) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	case SortByLastSync:
+		slices.SortStableFunc(repos, func(a, b config.Repository) int {
+			return cmp.Or(a.LastSync.Compare(b.LastSync), strings.Compare(a.Name, b.Name))
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q (want %s or %s)", by, SortByName, SortByLastSync)
+	}
+	return nil
+}
+
+// withOptionalTimeout bounds ctx by timeout, leaving it unbounded when timeout is zero
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
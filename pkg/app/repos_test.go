@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"dev-manager/internal/testutil/mockgit"
+	"dev-manager/pkg/config"
+)
+
+func TestSelectRepos(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{
+		UpdateFrequency: time.Hour,
+		Repositories: []config.Repository{
+			{Name: "fresh", LastSync: now.Add(-time.Minute)},
+			{Name: "stale", LastSync: now.Add(-2 * time.Hour)},
+			// Added moments ago, but not cloned yet
+			{Name: "added", LastSync: now.Add(-time.Minute), Path: filepath.Join(t.TempDir(), "added")},
+		},
+	}
+
+	due, skipped := SelectRepos(cfg, true, now)
+	if !slices.Equal(due, []int{1, 2}) || !slices.Equal(skipped, []int{0}) {
+		t.Errorf("SelectRepos(ifStale) = %v, %v, want [1 2], [0]", due, skipped)
+	}
+
+	due, skipped = SelectRepos(cfg, false, now)
+	if len(due) != 3 || len(skipped) != 0 {
+		t.Errorf("SelectRepos() = %v, %v, want every repository due", due, skipped)
+	}
+}
+
+func TestSyncAllRepos(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{
+		Commands: map[string]mockgit.Config{
+			"rebase": {ExitCode: 1, Error: "CONFLICT\n"},
+		},
+	})
+
+	workspace := t.TempDir()
+	// "existing" is already cloned, so it is fetched and rebased, which fails;
+	// "new" is cloned
+	if err := os.MkdirAll(filepath.Join(workspace, "existing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		WorkspacePath: workspace,
+		Repositories: []config.Repository{
+			{Name: "existing", URL: "https://github.com/test/existing", Branch: "main", Path: filepath.Join(workspace, "existing")},
+			{Name: "new", URL: "https://github.com/test/new", Branch: "main", Path: filepath.Join(workspace, "new")},
+		},
+	}
+
+	var progress []string
+	results := SyncAllRepos(context.Background(), cfg, SyncOptions{
+		Jobs:     2,
+		Progress: func(r RepoSyncResult) { progress = append(progress, r.Name) },
+	})
+
+	if len(results) != 2 || results[0].Name != "existing" || results[1].Name != "new" {
+		t.Fatalf("SyncAllRepos() results = %+v, want existing and new in order", results)
+	}
+	if results[0].Status != StatusFailed || results[0].Err == nil {
+		t.Errorf("result for existing = %+v, want it to fail on the rebase", results[0])
+	}
+	if results[1].Status != StatusSynced || results[1].Err != nil {
+		t.Errorf("result for new = %+v, want it synced", results[1])
+	}
+	if results[1].Duration <= 0 {
+		t.Errorf("Duration of syncing new = %v, want it timed", results[1].Duration)
+	}
+	if len(progress) != 2 {
+		t.Errorf("Progress called for %v, want both repositories", progress)
+	}
+
+	if !cfg.Repositories[0].LastSync.IsZero() {
+		t.Error("LastSync set on the repository that failed to sync")
+	}
+	if cfg.Repositories[1].LastSync.IsZero() {
+		t.Error("LastSync not set on the synced repository")
+	}
+}
+
+func TestSyncRepos_FailFast(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{
+		Commands: map[string]mockgit.Config{
+			"rebase": {ExitCode: 1, Error: "CONFLICT\n"},
+		},
+	})
+
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "existing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		WorkspacePath: workspace,
+		Repositories: []config.Repository{
+			{Name: "existing", URL: "https://github.com/test/existing", Branch: "main", Path: filepath.Join(workspace, "existing")},
+			{Name: "new", URL: "https://github.com/test/new", Branch: "main", Path: filepath.Join(workspace, "new")},
+		},
+	}
+
+	results := SyncRepos(context.Background(), cfg, []int{0, 1}, SyncOptions{Jobs: 1, FailFast: true})
+
+	if len(results) != 2 || results[0].Err == nil {
+		t.Fatalf("SyncRepos() results = %+v, want existing to fail", results)
+	}
+	if results[1].Status != StatusNotAttempted || results[1].Err != nil {
+		t.Errorf("result for new = %+v, want it skipped after the failure", results[1])
+	}
+	if !cfg.Repositories[1].LastSync.IsZero() {
+		t.Error("LastSync set on a repository that was never synced")
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "new")); !os.IsNotExist(err) {
+		t.Error("new was cloned after the batch stopped")
+	}
+}
+
+func TestFilterRepos(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{
+		Commands: map[string]mockgit.Config{
+			"status": {Output: " M main.go\n"},
+		},
+	})
+
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	repos := []config.Repository{
+		{Name: "api", URL: "https://github.com/org/api", Path: filepath.Join(workspace, "api")},
+		{Name: "web", URL: "https://github.com/org/frontend", Path: filepath.Join(workspace, "web")},
+		{Name: "api-docs", URL: "https://gitlab.com/org/docs", Path: filepath.Join(workspace, "api-docs")},
+	}
+
+	names := func(repos []config.Repository) []string {
+		var names []string
+		for _, r := range repos {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		filter RepoFilter
+		want   []string
+	}{
+		{name: "no filter", want: []string{"api", "web", "api-docs"}},
+		{name: "name", filter: RepoFilter{Match: "API"}, want: []string{"api", "api-docs"}},
+		{name: "url", filter: RepoFilter{Match: "frontend"}, want: []string{"web"}},
+		{name: "dirty skips repositories not cloned", filter: RepoFilter{Dirty: true}, want: []string{"api"}},
+		{name: "no match", filter: RepoFilter{Match: "nothing"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilterRepos(repos, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterRepos() error = %v", err)
+			}
+			if !slices.Equal(names(got), tt.want) {
+				t.Errorf("FilterRepos() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSortRepos(t *testing.T) {
+	now := time.Now()
+	repos := []config.Repository{
+		{Name: "b", LastSync: now},
+		{Name: "c", LastSync: now.Add(-time.Hour)},
+		{Name: "a"},
+	}
+
+	order := func() string {
+		var s string
+		for _, r := range repos {
+			s += r.Name
+		}
+		return s
+	}
+
+	if err := SortRepos(repos, ""); err != nil || order() != "bca" {
+		t.Errorf("SortRepos(\"\") = %s, %v, want configured order bca", order(), err)
+	}
+	if err := SortRepos(repos, SortByName); err != nil || order() != "abc" {
+		t.Errorf("SortRepos(name) = %s, %v, want abc", order(), err)
+	}
+	if err := SortRepos(repos, SortByLastSync); err != nil || order() != "acb" {
+		t.Errorf("SortRepos(lastSync) = %s, %v, want never synced first: acb", order(), err)
+	}
+	if err := SortRepos(repos, "size"); err == nil {
+		t.Error("SortRepos(size) should fail")
+	}
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// InterruptContext returns a context that is cancelled on the first SIGINT
+// or SIGTERM, so that an operation run with it stops and cleans up after
+// itself instead of the process being killed part way through. Only the first
+// signal is caught: a second one kills the process as usual, in case cleanup
+// hangs. The returned stop func restores the default handling and must be
+// called once the operation is done.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestInterruptContext(t *testing.T) {
+	t.Run("interrupt cancels", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Sending an interrupt is not supported on Windows")
+		}
+
+		ctx, stop := InterruptContext(context.Background())
+		defer stop()
+
+		proc, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := proc.Signal(os.Interrupt); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("context not cancelled after an interrupt")
+		}
+	})
+
+	t.Run("parent cancels", func(t *testing.T) {
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, stop := InterruptContext(parent)
+		defer stop()
+
+		cancel()
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("context not cancelled with its parent")
+		}
+	})
+
+	t.Run("stop cancels", func(t *testing.T) {
+		ctx, stop := InterruptContext(context.Background())
+		if ctx.Err() != nil {
+			t.Fatalf("context done before stop: %v", ctx.Err())
+		}
+
+		stop()
+		if ctx.Err() == nil {
+			t.Error("context not done after stop")
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package app
+
+// Status is the outcome of syncing one repository or dependency, for
+// callers that render results rather than just print them
+type Status string
+
+const (
+	// StatusSynced means a repository was pulled, or cloned
+	StatusSynced Status = "synced"
+	// StatusInstalled means a dependency was installed or reinstalled
+	StatusInstalled Status = "installed"
+	// StatusUpToDate means a dependency was already installed and left alone
+	StatusUpToDate Status = "up-to-date"
+	// StatusPlanned means a dependency would have been installed, but the
+	// manager is in dry-run mode
+	StatusPlanned Status = "planned"
+	// StatusFailed means the sync or install failed; the result's Err says why
+	StatusFailed Status = "failed"
+	// StatusNotAttempted means the item was never started because an earlier
+	// failure stopped the batch
+	StatusNotAttempted Status = "not-attempted"
+)
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"os"
+
+	"dev-manager/pkg/config"
+	"dev-manager/pkg/deps"
+)
+
+// RepoSummary counts a workspace's repositories by state, for an at a glance
+// view of the workspace
+type RepoSummary struct {
+	Total     int `json:"total"`
+	NotCloned int `json:"notCloned"`
+	// Dirty counts clones with uncommitted changes
+	Dirty int `json:"dirty"`
+	// Behind counts clones missing commits of their upstream branch, as of
+	// their last fetch
+	Behind int `json:"behind"`
+	// Unreadable counts clones whose state couldn't be read
+	Unreadable int `json:"unreadable"`
+}
+
+// SummarizeRepos counts cfg's repositories by state. It only reads the
+// clones; nothing is fetched, so Behind reflects the last sync.
+func SummarizeRepos(cfg *config.Config) RepoSummary {
+	s := RepoSummary{Total: len(cfg.Repositories)}
+	for _, repo := range cfg.Repositories {
+		if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+			s.NotCloned++
+			continue
+		}
+
+		r := GitRepo(repo)
+		clean, err := r.IsClean()
+		if err != nil {
+			s.Unreadable++
+			continue
+		}
+		if !clean {
+			s.Dirty++
+		}
+		// Branches without an upstream have nothing to be behind
+		if _, behind, err := r.AheadBehind(); err == nil && behind > 0 {
+			s.Behind++
+		}
+	}
+	return s
+}
+
+// DepSummary counts a workspace's dependencies by install state
+type DepSummary struct {
+	Total int `json:"total"`
+	// Installed counts dependencies installed from their configured source
+	Installed int `json:"installed"`
+	// Outdated counts dependencies installed at another version or from
+	// another source than configured, or without an install record, which
+	// deps verify --repair reinstalls
+	Outdated int `json:"outdated"`
+	Missing  int `json:"missing"`
+}
+
+// SummarizeDeps counts cfg's dependencies by their install state in m. Only
+// install records are compared with the configuration; unlike deps verify,
+// the installed files aren't checked.
+func SummarizeDeps(m *deps.Manager, cfg *config.Config) DepSummary {
+	s := DepSummary{Total: len(cfg.Dependencies)}
+	for _, dep := range cfg.Dependencies {
+		if !m.IsInstalled(dep) {
+			s.Missing++
+			continue
+		}
+		meta, err := m.ReadMetadata(dep)
+		if err != nil || meta.Version != dep.Version || meta.Source != dep.Source {
+			s.Outdated++
+			continue
+		}
+		s.Installed++
+	}
+	return s
+}
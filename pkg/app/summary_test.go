@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/internal/testutil/mockgit"
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestSummarizeRepos(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{
+		Commands: map[string]mockgit.Config{
+			"status":   {Output: " M main.go\n"},
+			"rev-list": {Output: "2\t0\n"},
+		},
+	})
+
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		WorkspacePath: workspace,
+		Repositories: []config.Repository{
+			{Name: "api", Path: filepath.Join(workspace, "api")},
+			{Name: "web", Path: filepath.Join(workspace, "web")},
+		},
+	}
+
+	want := RepoSummary{Total: 2, NotCloned: 1, Dirty: 1, Behind: 1}
+	if got := SummarizeRepos(cfg); got != want {
+		t.Errorf("SummarizeRepos() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeDeps(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+	cfg := &config.Config{
+		WorkspacePath: t.TempDir(),
+		Dependencies: []config.Dependency{
+			{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")},
+			{Name: "other", Version: "1.0.0", Source: server.URLFor("other.tar.gz")},
+			{Name: "missing", Version: "1.0.0", Source: server.URLFor("missing.tar.gz")},
+		},
+	}
+	m := DepsManager(cfg)
+	for _, name := range []string{"tool", "other"} {
+		if _, err := InstallDependency(context.Background(), m, cfg, name, false); err != nil {
+			t.Fatalf("InstallDependency(%s) error = %v", name, err)
+		}
+	}
+	cfg.Dependencies[1].Version = "2.0.0"
+
+	want := DepSummary{Total: 3, Installed: 1, Outdated: 1, Missing: 1}
+	if got := SummarizeDeps(m, cfg); got != want {
+		t.Errorf("SummarizeDeps() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"maps"
+	"slices"
+)
+
+// applyDefaults fills fields left unset on repositories and dependencies
+// from the defaults block
+func (c *Config) applyDefaults() {
+	d := c.Defaults
+
+	for i := range c.Repositories {
+		repo := &c.Repositories[i]
+		if repo.Branch == "" {
+			repo.Branch = d.Branch
+		}
+		if repo.UpdateFrequency == 0 {
+			repo.UpdateFrequency = d.UpdateFrequency
+		}
+		if len(repo.Tags) == 0 {
+			repo.Tags = slices.Clone(d.Tags)
+		}
+		if repo.Hooks.PostClone == "" {
+			repo.Hooks.PostClone = d.Hooks.PostClone
+		}
+		if repo.Hooks.PostSync == "" {
+			repo.Hooks.PostSync = d.Hooks.PostSync
+		}
+		if repo.Hooks.Timeout == 0 {
+			repo.Hooks.Timeout = d.Hooks.Timeout
+		}
+	}
+
+	for i := range c.Dependencies {
+		dep := &c.Dependencies[i]
+		if len(dep.Tags) == 0 {
+			dep.Tags = slices.Clone(d.Tags)
+		}
+	}
+}
+
+// stripDefaults clears fields that merely repeat the defaults block, so the
+// file only records per-item overrides
+func (c *Config) stripDefaults() {
+	d := c.Defaults
+
+	for i := range c.Repositories {
+		repo := &c.Repositories[i]
+		if d.Branch != "" && repo.Branch == d.Branch {
+			repo.Branch = ""
+		}
+		if d.UpdateFrequency != 0 && repo.UpdateFrequency == d.UpdateFrequency {
+			repo.UpdateFrequency = 0
+		}
+		if len(d.Tags) > 0 && slices.Equal(repo.Tags, d.Tags) {
+			repo.Tags = nil
+		}
+		if d.Hooks.PostClone != "" && repo.Hooks.PostClone == d.Hooks.PostClone {
+			repo.Hooks.PostClone = ""
+		}
+		if d.Hooks.PostSync != "" && repo.Hooks.PostSync == d.Hooks.PostSync {
+			repo.Hooks.PostSync = ""
+		}
+		if d.Hooks.Timeout != 0 && repo.Hooks.Timeout == d.Hooks.Timeout {
+			repo.Hooks.Timeout = 0
+		}
+	}
+
+	for i := range c.Dependencies {
+		dep := &c.Dependencies[i]
+		if len(d.Tags) > 0 && slices.Equal(dep.Tags, d.Tags) {
+			dep.Tags = nil
+		}
+	}
+}
+
+// clone returns a copy of the configuration whose items, including their
+// tags and headers, can be modified without affecting the original
+func (c *Config) clone() *Config {
+	cp := *c
+	cp.Defaults.Tags = slices.Clone(c.Defaults.Tags)
+	cp.ExcludePaths = slices.Clone(c.ExcludePaths)
+	cp.Repositories = slices.Clone(c.Repositories)
+	for i := range cp.Repositories {
+		cp.Repositories[i].Tags = slices.Clone(cp.Repositories[i].Tags)
+	}
+	cp.Tools = slices.Clone(c.Tools)
+	cp.Dependencies = slices.Clone(c.Dependencies)
+	for i := range cp.Dependencies {
+		cp.Dependencies[i].Tags = slices.Clone(cp.Dependencies[i].Tags)
+		cp.Dependencies[i].Headers = maps.Clone(cp.Dependencies[i].Headers)
+	}
+	return &cp
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const defaultsConfig = `defaults:
+  branch: develop
+  updateFrequency: 30m
+  tags: [work]
+repositories:
+  - name: inherits
+    url: https://github.com/org/inherits.git
+    path: /dev/inherits
+  - name: overrides
+    url: https://github.com/org/overrides.git
+    path: /dev/overrides
+    branch: main
+    updateFrequency: 4h
+    tags: [personal]
+dependencies:
+  - name: go
+    version: 1.22.0
+    source: https://go.dev/dl/go1.22.0.linux-amd64.tar.gz
+`
+
+func TestManager_LoadAppliesDefaults(t *testing.T) {
+	mgr := loadTestConfig(t, defaultsConfig)
+	cfg := mgr.GetConfig()
+
+	tests := []struct {
+		name          string
+		repo          Repository
+		wantBranch    string
+		wantFrequency time.Duration
+		wantTags      []string
+	}{
+		{
+			name:          "unset fields inherit defaults",
+			repo:          cfg.Repositories[0],
+			wantBranch:    "develop",
+			wantFrequency: 30 * time.Minute,
+			wantTags:      []string{"work"},
+		},
+		{
+			name:          "per-repository values override defaults",
+			repo:          cfg.Repositories[1],
+			wantBranch:    "main",
+			wantFrequency: 4 * time.Hour,
+			wantTags:      []string{"personal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.repo.Branch != tt.wantBranch {
+				t.Errorf("Branch = %q, want %q", tt.repo.Branch, tt.wantBranch)
+			}
+			if tt.repo.UpdateFrequency != tt.wantFrequency {
+				t.Errorf("UpdateFrequency = %s, want %s", tt.repo.UpdateFrequency, tt.wantFrequency)
+			}
+			if !slices.Equal(tt.repo.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", tt.repo.Tags, tt.wantTags)
+			}
+		})
+	}
+
+	if !slices.Equal(cfg.Dependencies[0].Tags, []string{"work"}) {
+		t.Errorf("dependency Tags = %v, want defaults", cfg.Dependencies[0].Tags)
+	}
+}
+
+func TestManager_SaveKeepsOnlyOverrides(t *testing.T) {
+	mgr := loadTestConfig(t, defaultsConfig)
+	if err := mgr.Save(); err != nil {
+		t.Fatalf("Manager.Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(mgr.Path())
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	saved := string(data)
+
+	if strings.Count(saved, "develop") != 1 {
+		t.Errorf("default branch should only appear in the defaults block:\n%s", saved)
+	}
+	if !strings.Contains(saved, "branch: main") {
+		t.Errorf("overridden branch missing from saved config:\n%s", saved)
+	}
+	if got := mgr.GetConfig().Repositories[0].Branch; got != "develop" {
+		t.Errorf("in-memory Branch after Save = %q, want merged value", got)
+	}
+}
+
+func TestManager_DefaultHooks(t *testing.T) {
+	mgr := loadTestConfig(t, `defaults:
+  hooks:
+    postSync: make setup
+    timeout: 2m
+repositories:
+  - name: inherits
+    url: https://github.com/org/inherits.git
+    path: /dev/inherits
+  - name: overrides
+    url: https://github.com/org/overrides.git
+    path: /dev/overrides
+    hooks:
+      postClone: npm install
+      postSync: npm ci
+`)
+	cfg := mgr.GetConfig()
+
+	want := []Hooks{
+		{PostSync: "make setup", Timeout: 2 * time.Minute},
+		{PostClone: "npm install", PostSync: "npm ci", Timeout: 2 * time.Minute},
+	}
+	for i, repo := range cfg.Repositories {
+		if repo.Hooks != want[i] {
+			t.Errorf("%s Hooks = %+v, want %+v", repo.Name, repo.Hooks, want[i])
+		}
+	}
+
+	if err := mgr.Save(); err != nil {
+		t.Fatalf("Manager.Save() error = %v", err)
+	}
+	data, err := os.ReadFile(mgr.Path())
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	saved := string(data)
+	if strings.Count(saved, "make setup") != 1 || strings.Count(saved, "timeout: 2m") != 1 {
+		t.Errorf("default hooks should only appear in the defaults block:\n%s", saved)
+	}
+	if !strings.Contains(saved, "postSync: npm ci") {
+		t.Errorf("overridden hook missing from saved config:\n%s", saved)
+	}
+}
+
+// loadTestConfig writes content to a temporary config file and loads it
+func loadTestConfig(t *testing.T, content string) *Manager {
+	t.Helper()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mgr, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.Load(); err != nil {
+		t.Fatalf("Manager.Load() error = %v", err)
+	}
+	return mgr
+}
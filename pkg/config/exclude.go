@@ -0,0 +1,35 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultExcludePaths are always excluded from workspace scans; deps holds
+// installed dependencies, which are managed separately
+var DefaultExcludePaths = []string{"deps"}
+
+// IsExcluded reports whether path, inside the workspace, matches one of the
+// ExcludePaths or DefaultExcludePaths. Patterns use filepath.Match syntax and
+// are matched against the path relative to WorkspacePath; a pattern matching
+// a directory also excludes everything under it. Paths outside the workspace
+// are never excluded.
+func (c *Config) IsExcluded(path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(c.WorkspacePath), filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	patterns := slices.Concat(DefaultExcludePaths, c.ExcludePaths)
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := range parts {
+		prefix := filepath.Join(parts[:i+1]...)
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(filepath.Clean(pattern), prefix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
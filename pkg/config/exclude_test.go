@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_IsExcluded(t *testing.T) {
+	workspace := filepath.Join("/home", "me", "dev")
+	cfg := &Config{
+		WorkspacePath: workspace,
+		ExcludePaths:  []string{"scratch-*", "vendor/old"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: filepath.Join(workspace, "deps"), want: true},
+		{path: filepath.Join(workspace, "deps", "go"), want: true},
+		{path: filepath.Join(workspace, "scratch-1"), want: true},
+		{path: filepath.Join(workspace, "vendor", "old", "lib"), want: true},
+		{path: filepath.Join(workspace, "vendor"), want: false},
+		{path: filepath.Join(workspace, "api"), want: false},
+		{path: workspace, want: false},
+		{path: filepath.Join("/home", "me", "deps"), want: false},
+	}
+	for _, tt := range tests {
+		if got := cfg.IsExcluded(tt.path); got != tt.want {
+			t.Errorf("IsExcluded(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_ValidateExcludePaths(t *testing.T) {
+	cfg := &Config{
+		WorkspacePath:   "/home/me/dev",
+		UpdateFrequency: time.Hour,
+		ExcludePaths:    []string{"scratch", "[bad"},
+	}
+	err := cfg.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Errors) != 1 {
+		t.Fatalf("Validate() = %v, want one error for the bad pattern", err)
+	}
+}
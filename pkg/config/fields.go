@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// scalarFields returns the top-level fields of Config that hold a single
+// value, keyed by their yaml name
+func scalarFields(c *Config) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		switch t.Field(i).Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+			fields[yamlFieldName(t.Field(i))] = v.Field(i)
+		}
+	}
+	return fields
+}
+
+// lookupField returns the scalar field with the given key
+func lookupField(c *Config, key string) (reflect.Value, error) {
+	fields := scalarFields(c)
+	if f, ok := fields[key]; ok {
+		return f, nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return reflect.Value{}, fmt.Errorf("unknown config key %q (supported: %s)", key, strings.Join(keys, ", "))
+}
+
+// Get returns the value of a top-level scalar field, e.g. "workspacePath"
+func (c *Config) Get(key string) (string, error) {
+	f, err := lookupField(c, key)
+	if err != nil {
+		return "", err
+	}
+	if f.Type() == durationType {
+		return time.Duration(f.Int()).String(), nil
+	}
+	return fmt.Sprint(f.Interface()), nil
+}
+
+// Set parses value into a top-level scalar field. Durations use
+// time.ParseDuration syntax, e.g. "2h30m".
+func (c *Config) Set(key, value string) error {
+	f, err := lookupField(c, key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration for %s: %w", key, err)
+		}
+		f.SetInt(int64(d))
+	case f.Kind() == reflect.String:
+		f.SetString(value)
+	case f.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean for %s: %w", key, err)
+		}
+		f.SetBool(b)
+	default:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number for %s: %w", key, err)
+		}
+		f.SetInt(n)
+	}
+	return nil
+}
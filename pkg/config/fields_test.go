@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_SetGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "string field",
+			key:   "workspacePath",
+			value: "/home/me/code",
+			want:  "/home/me/code",
+		},
+		{
+			name:  "duration field",
+			key:   "updateFrequency",
+			value: "90m",
+			want:  "1h30m0s",
+		},
+		{
+			name:    "invalid duration",
+			key:     "updateFrequency",
+			value:   "often",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			key:     "workspace",
+			value:   "/tmp",
+			wantErr: true,
+		},
+		{
+			name:    "non-scalar key",
+			key:     "repositories",
+			value:   "[]",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{WorkspacePath: "/dev", UpdateFrequency: time.Hour}
+
+			err := cfg.Set(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config.Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := cfg.Get(tt.key)
+			if err != nil {
+				t.Fatalf("Config.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Config.Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
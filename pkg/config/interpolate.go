@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// referencePattern matches ${file:/path/to/value} and ${env:VAR} references
+var referencePattern = regexp.MustCompile(`\$\{(file|env):([^}]*)\}`)
+
+// reference records the raw and resolved value of an interpolated field
+type reference struct {
+	raw      string
+	resolved string
+}
+
+// resolveReferences replaces ${file:...} and ${env:...} references in every
+// string field of the configuration and returns the raw values keyed by field path
+func resolveReferences(cfg *Config) (map[string]reference, error) {
+	refs := make(map[string]reference)
+	err := walkStrings(reflect.ValueOf(cfg), "", func(path string, v reflect.Value) error {
+		raw := v.String()
+		if !referencePattern.MatchString(raw) {
+			return nil
+		}
+
+		resolved, err := interpolate(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		v.SetString(resolved)
+		refs[path] = reference{raw: raw, resolved: resolved}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// restoreReferences puts raw references back into fields. A field is only
+// restored while it still holds the value recorded at load time, so edits
+// made after loading are kept as-is. It returns the references it restored;
+// those of removed items or edited fields no longer apply.
+func restoreReferences(cfg *Config, refs map[string]reference) map[string]reference {
+	if len(refs) == 0 {
+		return nil
+	}
+	restored := make(map[string]reference)
+	_ = walkStrings(reflect.ValueOf(cfg), "", func(path string, v reflect.Value) error {
+		if ref, ok := refs[path]; ok && v.String() == ref.resolved {
+			v.SetString(ref.raw)
+			restored[path] = ref
+		}
+		return nil
+	})
+	return restored
+}
+
+// interpolate expands every reference found in s
+func interpolate(s string) (string, error) {
+	var firstErr error
+	out := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := referencePattern.FindStringSubmatch(match)
+		value, err := lookupReference(parts[1], parts[2])
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// lookupReference returns the value a single reference points to
+func lookupReference(kind, target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("empty %s reference", kind)
+	}
+
+	switch kind {
+	case "file":
+		target, err := ExpandPath(target)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return "", fmt.Errorf("failed to read referenced file %s: %w", target, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "env":
+		value, ok := os.LookupEnv(target)
+		if !ok {
+			return "", fmt.Errorf("referenced environment variable %s is not set", target)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown reference type %q", kind)
+	}
+}
+
+// walkStrings calls fn for every settable string reachable from v, including
+// the values of string maps. Paths use the yaml field names and identify list
+// items by name where they have one, e.g. "repositories[api].url" or
+// "dependencies[tool].headers.Authorization", so that they still point at the
+// same item after others are removed or reordered.
+func walkStrings(v reflect.Value, path string, fn func(path string, v reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return walkStrings(v.Elem(), path, fn)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := yamlFieldName(field)
+			if name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			if err := walkStrings(v.Field(i), name, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), fmt.Sprintf("%s[%s]", path, itemKey(v.Index(i), i)), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, key := range keys {
+			// Map values aren't addressable, so hand fn a copy and store it back
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := fn(path+"."+key.String(), elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			return fn(path, v)
+		}
+	}
+	return nil
+}
+
+// itemKey identifies the list item v at index i: by its Name field when it
+// has a non-empty one, by index otherwise
+func itemKey(v reflect.Value, i int) string {
+	if v.Kind() == reflect.Struct {
+		if name := v.FieldByName("Name"); name.IsValid() && name.Kind() == reflect.String && name.String() != "" {
+			return name.String()
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+// yamlFieldName returns the name a struct field is serialized under
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
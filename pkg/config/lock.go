@@ -0,0 +1,133 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ErrConfigLocked is returned when another process holds the config file's
+// lock for longer than LockTimeout
+var ErrConfigLocked = errors.New("config file is locked by another process")
+
+// LockTimeout is how long Save, Update and Undo wait for another process to
+// release the config file's lock
+var LockTimeout = 10 * time.Second
+
+// staleLockAge is the age past which a lock file is considered left behind
+// by a crashed process. Locks are only held while the file is read or
+// written, so a live one is never this old.
+const staleLockAge = time.Minute
+
+// lockRetryInterval is how often a held lock is retried
+const lockRetryInterval = 20 * time.Millisecond
+
+// lockPath returns the path of the advisory lock file guarding the config
+func (m *Manager) lockPath() string {
+	return m.configPath + ".lock"
+}
+
+// lockFile takes the config file's advisory lock, creating the lock file
+// next to it, and returns a function releasing it. Without a config
+// directory there is nothing to guard, so no lock is taken.
+//
+// The lock file holds a token unique to this lock, so that releasing it, or
+// breaking it as stale, never removes a lock another process has taken since.
+func (m *Manager) lockFile() (func(), error) {
+	if _, err := os.Stat(filepath.Dir(m.configPath)); errors.Is(err, os.ErrNotExist) {
+		return func() {}, nil
+	}
+
+	path := m.lockPath()
+	token := lockToken()
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.WriteString(token)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to lock config: %w", err)
+			}
+			return func() { releaseLock(path, token) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock config: %w", err)
+		}
+
+		if breakStaleLock(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w (remove %s if no other dev-manager is running)", ErrConfigLocked, path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// lockToken returns a token identifying a lock: the process ID and a random
+// nonce, which tells apart locks taken by the same process too
+func lockToken() string {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	return strconv.Itoa(os.Getpid()) + " " + hex.EncodeToString(nonce)
+}
+
+// releaseLock removes the lock file if it still holds token
+func releaseLock(path, token string) {
+	if data, err := os.ReadFile(path); err == nil && string(data) == token {
+		os.Remove(path)
+	}
+}
+
+// breakStaleLock removes the lock file at path if it was left behind by a
+// crashed process, and reports whether it did. Breaking a lock is serialized
+// through a second lock file, and the stale token is checked again while
+// that is held, so two processes can't both break the same lock, nor can one
+// remove the lock the other took after breaking it.
+func breakStaleLock(path string) bool {
+	token, ok := staleLockToken(path)
+	if !ok {
+		return false
+	}
+
+	breaker := path + ".break"
+	f, err := os.OpenFile(breaker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		// Someone else is breaking the lock; a breaker left behind by a crash
+		// in between goes stale too
+		if info, err := os.Stat(breaker); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(breaker)
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(breaker)
+
+	if current, ok := staleLockToken(path); !ok || current != token {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// staleLockToken returns the token of the lock file at path if it is older
+// than staleLockAge
+func staleLockToken(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= staleLockAge {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loadedManager returns a manager that has loaded the config at path
+func loadedManager(t *testing.T, path string) *Manager {
+	t.Helper()
+	mgr, err := NewManager(path)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.Load(); err != nil {
+		t.Fatalf("Manager.Load() error = %v", err)
+	}
+	return mgr
+}
+
+func TestManager_SaveDetectsConcurrentChanges(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("version: 1\nworkspacePath: /dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon := loadedManager(t, cfgPath)
+	user := loadedManager(t, cfgPath)
+
+	user.GetConfig().Repositories = append(user.GetConfig().Repositories, Repository{Name: "new", Path: "/dev/new"})
+	if err := user.Save(); err != nil {
+		t.Fatalf("Manager.Save() error = %v", err)
+	}
+
+	// The daemon's view is now stale, so saving it would drop the new repository
+	daemon.GetConfig().UpdateFrequency = time.Hour
+	if err := daemon.SaveWithoutBackup(); !errors.Is(err, ErrConfigModified) {
+		t.Fatalf("stale Manager.Save() error = %v, want ErrConfigModified", err)
+	}
+
+	// Update applies the change on top of the latest file instead
+	err := daemon.Update(func(cfg *Config) error {
+		cfg.UpdateFrequency = time.Hour
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Manager.Update() error = %v", err)
+	}
+
+	cfg := loadedManager(t, cfgPath).GetConfig()
+	if len(cfg.Repositories) != 1 || cfg.UpdateFrequency != time.Hour {
+		t.Errorf("config after Update = %d repositories, updateFrequency %s; want both changes kept", len(cfg.Repositories), cfg.UpdateFrequency)
+	}
+
+	// The manager that saved last can keep saving
+	daemon.GetConfig().WorkspacePath = "/work"
+	if err := daemon.Save(); err != nil {
+		t.Errorf("Manager.Save() after Update error = %v", err)
+	}
+}
+
+func TestManager_ConcurrentUpdates(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("version: 1\nworkspacePath: /dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Separate managers stand in for separate processes
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mgr, _ := NewManager(cfgPath)
+			errs[i] = mgr.Update(func(cfg *Config) error {
+				cfg.Tools = append(cfg.Tools, ToolConfig{Name: string(rune('a' + i))})
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Manager.Update() #%d error = %v", i, err)
+		}
+	}
+	if got := len(loadedManager(t, cfgPath).GetConfig().Tools); got != writers {
+		t.Errorf("config has %d tools after %d concurrent updates, want no lost updates", got, writers)
+	}
+	if _, err := os.Stat(cfgPath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestManager_LockTimeoutAndStaleLocks(t *testing.T) {
+	oldTimeout := LockTimeout
+	LockTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = oldTimeout })
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	lockPath := cfgPath + ".lock"
+	if err := os.WriteFile(lockPath, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, _ := NewManager(cfgPath)
+	if err := mgr.Save(); !errors.Is(err, ErrConfigLocked) {
+		t.Fatalf("Manager.Save() with a held lock error = %v, want ErrConfigLocked", err)
+	}
+
+	// A lock left behind by a crashed process is taken over
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Save(); err != nil {
+		t.Fatalf("Manager.Save() with a stale lock error = %v", err)
+	}
+}
+
+func TestManager_LockOwnership(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	lockPath := cfgPath + ".lock"
+	mgr, _ := NewManager(cfgPath)
+
+	// Releasing a lock another process has taken over leaves it in place
+	unlock, err := mgr.lockFile()
+	if err != nil {
+		t.Fatalf("lockFile() error = %v", err)
+	}
+	if err := os.WriteFile(lockPath, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("release removed another process's lock: %v", err)
+	}
+
+	// A stale lock is only broken while it still holds the token seen as stale
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath+".break", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if breakStaleLock(lockPath) {
+		t.Error("breakStaleLock() = true while another process is breaking the lock")
+	}
+	os.Remove(lockPath + ".break")
+	if !breakStaleLock(lockPath) {
+		t.Error("breakStaleLock() = false for a stale lock")
+	}
+	if _, err := os.Stat(lockPath + ".break"); !os.IsNotExist(err) {
+		t.Errorf("breaker file left behind: %v", err)
+	}
+}
+
+func TestManager_LoadDoesNotLock(t *testing.T) {
+	oldTimeout := LockTimeout
+	LockTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = oldTimeout })
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("version: 1\nworkspacePath: /dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfgPath+".lock", []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading works while another process holds the lock to write
+	loadedManager(t, cfgPath)
+}
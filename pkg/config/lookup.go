@@ -0,0 +1,21 @@
+package config
+
+// FindDependency returns the configured dependency with the given name
+func (c *Config) FindDependency(name string) (*Dependency, bool) {
+	for i := range c.Dependencies {
+		if c.Dependencies[i].Name == name {
+			return &c.Dependencies[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindRepository returns the configured repository with the given name
+func (c *Config) FindRepository(name string) (*Repository, bool) {
+	for i := range c.Repositories {
+		if c.Repositories[i].Name == name {
+			return &c.Repositories[i], true
+		}
+	}
+	return nil, false
+}
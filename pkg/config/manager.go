@@ -1,26 +1,65 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Manager handles configuration operations
+var (
+	// ErrConfigNotFound is returned by Load when the config file doesn't exist.
+	// The manager still holds an empty config, so callers that can start from
+	// scratch may ignore it.
+	ErrConfigNotFound = errors.New("config file not found")
+	// ErrConfigParse is returned by Load when the config file isn't valid YAML
+	ErrConfigParse = errors.New("failed to parse config file")
+	// ErrNoBackup is returned by Undo when there is no backup to restore
+	ErrNoBackup = errors.New("no config backup to restore")
+	// ErrConfigModified is returned by Save when the config file changed
+	// since it was loaded, e.g. by another dev-manager process. Load it again
+	// and reapply the change, or use Update.
+	ErrConfigModified = errors.New("config file was modified since it was loaded")
+)
+
+// MaxBackups is how many previous versions of the config file Save keeps, as
+// config.yaml.1 (the most recent) through config.yaml.3
+const MaxBackups = 3
+
+// Manager handles configuration operations.
+//
+// A Manager's methods may be called from several goroutines. The config file
+// is locked while it is written and replaced atomically, so other processes
+// never see it half-written and reading it needs no lock. Save refuses to
+// overwrite changes another process made since Load; Update applies a change
+// to the latest file instead.
+//
+// The Config returned by GetConfig is shared, not copied: goroutines that
+// modify it, or read it while another calls Load or Update, must coordinate
+// among themselves. Snapshot returns a private copy.
 type Manager struct {
+	mu         sync.RWMutex
 	config     *Config
 	configPath string
+	refs       map[string]reference
+	// loaded is the file content as last read or written, nil when the file
+	// didn't exist, for Save to detect changes made by other processes
+	loaded []byte
 }
 
-// NewManager creates a new configuration manager
+// NewManager creates a new configuration manager. An empty configPath is
+// resolved with Discover.
 func NewManager(configPath string) (*Manager, error) {
 	if configPath == "" {
-		home, err := os.UserHomeDir()
+		path, err := Discover()
 		if err != nil {
 			return nil, err
 		}
-		configPath = filepath.Join(home, ".config", "dev-manager", "config.yaml")
+		configPath = path
 	}
 
 	return &Manager{
@@ -28,50 +67,332 @@ func NewManager(configPath string) (*Manager, error) {
 	}, nil
 }
 
-// Load reads the configuration file
+// Load reads the configuration file and resolves ${file:...} and ${env:...}
+// references in string fields. Files written with an older schema version are
+// migrated and rewritten, keeping the original as a .bak file.
+//
+// A missing file yields an error matching ErrConfigNotFound and malformed
+// YAML one matching ErrConfigParse.
 func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only rewriting a migrated file needs the lock, so that reading works
+	// in a read-only config directory too
+	if err := m.load(false); !errors.Is(err, errNeedsLock) {
+		return err
+	}
+
+	unlock, err := m.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return m.load(true)
+}
+
+// errNeedsLock is returned by load when the file must be migrated, but the
+// file lock, which writing it requires, isn't held
+var errNeedsLock = errors.New("config file lock required")
+
+// load reads the config file; m.mu must be held, and the file lock too
+// when locked is set
+func (m *Manager) load(locked bool) error {
+	m.refs = nil
+	m.loaded = nil
+
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			m.config = &Config{}
-			return nil
+		if errors.Is(err, os.ErrNotExist) {
+			m.config = &Config{Version: CurrentVersion}
+			return fmt.Errorf("%w: %s", ErrConfigNotFound, m.configPath)
 		}
 		return err
 	}
+	m.loaded = data
 
 	m.config = &Config{}
-	return yaml.Unmarshal(data, m.config)
+	if err := yaml.Unmarshal(data, m.config); err != nil {
+		return fmt.Errorf("%w %s: %w", ErrConfigParse, m.configPath, err)
+	}
+
+	migrated, err := migrate(m.config)
+	if err != nil {
+		return err
+	}
+	if migrated {
+		if !locked {
+			return errNeedsLock
+		}
+		if err := m.writeMigrated(data); err != nil {
+			return err
+		}
+	}
+
+	refs, err := resolveReferences(m.config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config references: %w", err)
+	}
+	m.refs = refs
+
+	m.config.applyDefaults()
+
+	return nil
 }
 
-// Save writes the configuration to file
-func (m *Manager) Save() error {
-	if m.config == nil {
-		m.config = &Config{}
+// Raw reads the configuration file as written, without migrating it,
+// resolving references or applying the defaults block
+func (m *Manager) Raw() (*Config, error) {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, m.configPath)
+		}
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrConfigParse, m.configPath, err)
 	}
+	return &cfg, nil
+}
+
+// Save writes the configuration to file, keeping the previous version as a
+// backup that Undo can restore
+func (m *Manager) Save() error {
+	return m.save(true)
+}
+
+// SaveWithoutBackup writes the configuration without rotating the backups. It
+// is meant for bookkeeping such as sync timestamps, which would otherwise push
+// the user's own changes out of the backups.
+func (m *Manager) SaveWithoutBackup() error {
+	return m.save(false)
+}
+
+func (m *Manager) save(keepBackup bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	dir := filepath.Dir(m.configPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
+	unlock, err := m.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return m.write(keepBackup)
+}
+
+// write writes the configuration unless the file changed since it was last
+// read or written; m.mu and the file lock must be held
+func (m *Manager) write(keepBackup bool) error {
+	if m.config == nil {
+		m.config = &Config{Version: CurrentVersion}
+	}
+
+	// Write references back in their raw form so resolved secrets never hit the
+	// file, and leave values inherited from the defaults block implicit
+	view := m.config.clone()
+	m.refs = restoreReferences(view, m.refs)
+	view.stripDefaults()
+
+	data, err := yaml.Marshal(view)
+	if err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(m.configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	switch {
+	case err == nil && bytes.Equal(current, data):
+		// Nothing changed, so don't push a real backup out of rotation
+		m.loaded = current
+		return nil
+	case !bytes.Equal(current, m.loaded) || (err == nil) != (m.loaded != nil):
+		return fmt.Errorf("%w: %s", ErrConfigModified, m.configPath)
+	case err == nil && keepBackup:
+		if err := m.backup(current); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(m.configPath, data); err != nil {
+		return err
+	}
+	m.loaded = data
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data by renaming a
+// temporary file over it, so readers see either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Update loads the latest config file, applies fn to it and saves the
+// result, holding the file lock throughout so no other process can change
+// the file in between. It is meant for bookkeeping, such as recording sync
+// times, made after a long operation during which the file may have been
+// edited, and doesn't rotate the backups. Afterwards GetConfig returns the
+// updated config.
+func (m *Manager) Update(fn func(*Config) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.load(true); err != nil {
+		return err
+	}
+	if err := fn(m.config); err != nil {
+		return err
+	}
+	return m.write(false)
+}
+
+// BackupPath returns the path of the nth most recent backup, starting at 1
+func (m *Manager) BackupPath(n int) string {
+	return fmt.Sprintf("%s.%d", m.configPath, n)
+}
+
+// backup rotates the existing backups and stores data as the most recent one
+func (m *Manager) backup(data []byte) error {
+	for n := MaxBackups - 1; n >= 1; n-- {
+		if err := os.Rename(m.BackupPath(n), m.BackupPath(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate config backups: %w", err)
+		}
+	}
+	if err := os.WriteFile(m.BackupPath(1), data, 0644); err != nil {
+		return fmt.Errorf("failed to back up config: %w", err)
+	}
+	return nil
+}
+
+// Undo restores the most recent backup over the config file, making the next
+// older backup the most recent. Call Load afterwards to use the restored
+// config.
+func (m *Manager) Undo() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlock, err := m.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(m.BackupPath(1))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoBackup
+		}
+		return err
+	}
+
+	if err := writeFileAtomic(m.configPath, data); err != nil {
+		return fmt.Errorf("failed to restore config: %w", err)
+	}
+	if err := os.Remove(m.BackupPath(1)); err != nil {
+		return fmt.Errorf("failed to remove restored backup: %w", err)
+	}
+	for n := 2; n <= MaxBackups; n++ {
+		if err := os.Rename(m.BackupPath(n), m.BackupPath(n-1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate config backups: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeMigrated backs up the original file contents and writes the migrated
+// config, which still holds its raw references and implicit defaults
+func (m *Manager) writeMigrated(original []byte) error {
+	if err := os.WriteFile(m.configPath+".bak", original, 0644); err != nil {
+		return fmt.Errorf("failed to back up config before migration: %w", err)
+	}
+
 	data, err := yaml.Marshal(m.config)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(m.configPath, data, 0644)
+	if err := writeFileAtomic(m.configPath, data); err != nil {
+		return fmt.Errorf("failed to write migrated config: %w", err)
+	}
+	m.loaded = data
+	return nil
 }
 
-// GetConfig returns the current configuration
+// GetConfig returns the current configuration, with references resolved and
+// the defaults block applied to every repository and dependency. Changes to
+// it are written by the next Save.
 func (m *Manager) GetConfig() *Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.config == nil {
-		m.config = &Config{}
+		m.config = &Config{Version: CurrentVersion}
 	}
 	return m.config
 }
 
+// Snapshot returns a copy of the current configuration that can be read and
+// modified without affecting the manager or racing with it
+func (m *Manager) Snapshot() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return &Config{Version: CurrentVersion}
+	}
+	return m.config.clone()
+}
+
+// Unresolved returns a copy of the current configuration with ${file:...} and
+// ${env:...} references in their raw form, for showing the configuration
+// without the secrets they resolve to
+func (m *Manager) Unresolved() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return &Config{Version: CurrentVersion}
+	}
+	view := m.config.clone()
+	restoreReferences(view, m.refs)
+	return view
+}
+
 // SetConfig updates the current configuration
 func (m *Manager) SetConfig(cfg *Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.config = cfg
 }
 
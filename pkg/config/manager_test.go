@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManager_SaveLoadRoundTrip(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	mgr, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	want := Repository{
+		Name:        "fork",
+		URL:         "git@github.com:me/project.git",
+		UpstreamURL: "https://github.com/org/project.git",
+		Branch:      "main",
+		Path:        "/dev/fork",
+	}
+	mgr.GetConfig().Repositories = []Repository{want}
+	if err := mgr.Save(); err != nil {
+		t.Fatalf("Manager.Save() error = %v", err)
+	}
+
+	loaded, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Manager.Load() error = %v", err)
+	}
+
+	repos := loaded.GetConfig().Repositories
+	if len(repos) != 1 {
+		t.Fatalf("loaded %d repositories, want 1", len(repos))
+	}
+	if repos[0].URL != want.URL || repos[0].UpstreamURL != want.UpstreamURL {
+		t.Errorf("loaded URLs = (%q, %q), want (%q, %q)", repos[0].URL, repos[0].UpstreamURL, want.URL, want.UpstreamURL)
+	}
+}
+
+func TestManager_LoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string // nil leaves the file missing
+		wantErr error
+	}{
+		{name: "missing file", wantErr: ErrConfigNotFound},
+		{name: "malformed yaml", content: ptr("version: 1\nrepositories: [\n"), wantErr: ErrConfigParse},
+		{name: "valid file", content: ptr("version: 1\nworkspacePath: /dev\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.content != nil {
+				if err := os.WriteFile(cfgPath, []byte(*tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mgr, err := NewManager(cfgPath)
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+
+			err = mgr.Load()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Manager.Load() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Manager.Load() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(tt.wantErr, ErrConfigNotFound) && mgr.GetConfig().Version != CurrentVersion {
+				t.Errorf("missing file left config version %d, want %d", mgr.GetConfig().Version, CurrentVersion)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestManager_Raw(t *testing.T) {
+	t.Setenv("DEV_MANAGER_TEST_WORKSPACE", "/home/me/dev")
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "version: 1\nworkspacePath: ${env:DEV_MANAGER_TEST_WORKSPACE}\ndefaults:\n  branch: develop\nrepositories:\n  - name: app\n    url: git@github.com:org/app.git\n    path: /dev/app\n"
+	if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.Load(); err != nil {
+		t.Fatalf("Manager.Load() error = %v", err)
+	}
+	raw, err := mgr.Raw()
+	if err != nil {
+		t.Fatalf("Manager.Raw() error = %v", err)
+	}
+
+	if raw.WorkspacePath != "${env:DEV_MANAGER_TEST_WORKSPACE}" {
+		t.Errorf("raw workspacePath = %q, want the unresolved reference", raw.WorkspacePath)
+	}
+	if raw.Repositories[0].Branch != "" {
+		t.Errorf("raw branch = %q, want it left to the defaults block", raw.Repositories[0].Branch)
+	}
+	cfg := mgr.GetConfig()
+	if cfg.WorkspacePath != "/home/me/dev" || cfg.Repositories[0].Branch != "develop" {
+		t.Errorf("effective config = (%q, %q), want (/home/me/dev, develop)", cfg.WorkspacePath, cfg.Repositories[0].Branch)
+	}
+}
+
+func TestManager_SaveBackupsAndUndo(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	mgr, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if err := mgr.Undo(); !errors.Is(err, ErrNoBackup) {
+		t.Fatalf("Manager.Undo() without backups error = %v, want ErrNoBackup", err)
+	}
+
+	// Save five versions; the first write has nothing to back up
+	for _, ws := range []string{"/v1", "/v2", "/v3", "/v4", "/v5"} {
+		mgr.GetConfig().WorkspacePath = ws
+		if err := mgr.Save(); err != nil {
+			t.Fatalf("Manager.Save() error = %v", err)
+		}
+	}
+	// Saving an unchanged config doesn't rotate the backups
+	if err := mgr.Save(); err != nil {
+		t.Fatalf("Manager.Save() error = %v", err)
+	}
+	if _, err := os.Stat(mgr.BackupPath(MaxBackups + 1)); !os.IsNotExist(err) {
+		t.Errorf("Save kept more than %d backups", MaxBackups)
+	}
+
+	// Undo walks back through the kept versions, then runs out
+	for _, want := range []string{"/v4", "/v3", "/v2"} {
+		if err := mgr.Undo(); err != nil {
+			t.Fatalf("Manager.Undo() error = %v", err)
+		}
+		if err := mgr.Load(); err != nil {
+			t.Fatalf("Manager.Load() error = %v", err)
+		}
+		if got := mgr.GetConfig().WorkspacePath; got != want {
+			t.Errorf("after Undo workspacePath = %q, want %q", got, want)
+		}
+	}
+	if err := mgr.Undo(); !errors.Is(err, ErrNoBackup) {
+		t.Errorf("Manager.Undo() after exhausting backups error = %v, want ErrNoBackup", err)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// CurrentVersion is the config schema version this release writes
+const CurrentVersion = 1
+
+// DefaultUpdateFrequency is used when a config doesn't set updateFrequency
+const DefaultUpdateFrequency = 2 * time.Hour
+
+// migrations upgrade a raw, unresolved config; migrations[v] takes a config
+// from version v-1 to v. Add an entry and bump CurrentVersion for each
+// schema change.
+var migrations = map[int]func(*Config) error{
+	1: migrateV1,
+}
+
+// migrateV1 fills in fields that configs written before versioning could
+// leave empty and cleans up paths
+func migrateV1(c *Config) error {
+	if c.UpdateFrequency <= 0 {
+		c.UpdateFrequency = DefaultUpdateFrequency
+	}
+
+	cleanPath(&c.WorkspacePath)
+	for i := range c.Repositories {
+		cleanPath(&c.Repositories[i].Path)
+	}
+	for i := range c.Tools {
+		cleanPath(&c.Tools[i].ConfigPath)
+		cleanPath(&c.Tools[i].BackupPath)
+		cleanPath(&c.Tools[i].Source)
+	}
+	return nil
+}
+
+// cleanPath normalizes a path in place, leaving empty values and values
+// containing references untouched
+func cleanPath(path *string) {
+	if *path == "" || referencePattern.MatchString(*path) {
+		return
+	}
+	*path = filepath.Clean(*path)
+}
+
+// migrate upgrades c to CurrentVersion and reports whether any migration ran
+func migrate(c *Config) (bool, error) {
+	if c.Version > CurrentVersion {
+		return false, fmt.Errorf("config version %d is newer than the supported version %d; upgrade dev-manager", c.Version, CurrentVersion)
+	}
+
+	migrated := false
+	for v := c.Version + 1; v <= CurrentVersion; v++ {
+		fn, ok := migrations[v]
+		if !ok {
+			return false, fmt.Errorf("no migration to config version %d", v)
+		}
+		if err := fn(c); err != nil {
+			return false, fmt.Errorf("failed to migrate config to version %d: %w", v, err)
+		}
+		c.Version = v
+		migrated = true
+	}
+	return migrated, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const unversionedConfig = `workspacePath: /dev/workspace/
+repositories:
+  - name: app
+    url: https://github.com/org/app.git
+    branch: main
+    path: /dev/workspace//app
+    lastSync: 0001-01-01T00:00:00Z
+`
+
+func TestManager_LoadMigrates(t *testing.T) {
+	mgr := loadTestConfig(t, unversionedConfig)
+	cfg := mgr.GetConfig()
+
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+	if cfg.UpdateFrequency != DefaultUpdateFrequency {
+		t.Errorf("UpdateFrequency = %s, want %s", cfg.UpdateFrequency, DefaultUpdateFrequency)
+	}
+	if cfg.WorkspacePath != "/dev/workspace" || cfg.Repositories[0].Path != "/dev/workspace/app" {
+		t.Errorf("paths not normalized: %q, %q", cfg.WorkspacePath, cfg.Repositories[0].Path)
+	}
+
+	backup, err := os.ReadFile(mgr.Path() + ".bak")
+	if err != nil || string(backup) != unversionedConfig {
+		t.Errorf("backup = %q, %v; want the original file", backup, err)
+	}
+
+	rewritten, err := os.ReadFile(mgr.Path())
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if !strings.Contains(string(rewritten), "version: 1") {
+		t.Errorf("migrated config not written:\n%s", rewritten)
+	}
+
+	// Loading the migrated file again must not migrate or back up again
+	os.Remove(mgr.Path() + ".bak")
+	if err := mgr.Load(); err != nil {
+		t.Fatalf("Manager.Load() error = %v", err)
+	}
+	if _, err := os.Stat(mgr.Path() + ".bak"); !os.IsNotExist(err) {
+		t.Error("current config was migrated again")
+	}
+}
+
+func TestManager_LoadRejectsNewerVersion(t *testing.T) {
+	cfgPath := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(cfgPath, []byte("version: 99\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mgr, err := NewManager(cfgPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.Load(); err == nil {
+		t.Error("Manager.Load() expected error for a newer config version")
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EnvConfig names the environment variable that selects the config file
+	EnvConfig = "DEV_MANAGER_CONFIG"
+	// LocalConfigName is the file name of a project-local config
+	LocalConfigName = ".dev-manager.yaml"
+)
+
+// Discover returns the config file to use when none is given explicitly.
+// In order of precedence it is:
+//
+//  1. the path in $DEV_MANAGER_CONFIG
+//  2. the nearest .dev-manager.yaml in the current directory or its parents
+//  3. ~/.config/dev-manager/config.yaml
+func Discover() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return discover(os.Getenv(EnvConfig), cwd, home)
+}
+
+// discover implements Discover for a given environment value, working
+// directory and home directory
+func discover(env, dir, home string) (string, error) {
+	if env != "" {
+		return ExpandPath(env)
+	}
+
+	for {
+		path := filepath.Join(dir, LocalConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return GlobalConfigPath(home), nil
+}
+
+// GlobalConfigPath returns the per-user config file under home
+func GlobalConfigPath(home string) string {
+	return filepath.Join(home, ".config", "dev-manager", "config.yaml")
+}
+
+// ExpandPath replaces a leading ~ in path with the user's home directory
+func ExpandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	project := filepath.Join(root, "work", "project")
+	nested := filepath.Join(project, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(project, LocalConfigName)
+	if err := os.WriteFile(local, []byte("version: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		dir  string
+		want string
+	}{
+		{name: "env wins over local config", env: "/etc/dm.yaml", dir: nested, want: "/etc/dm.yaml"},
+		{name: "local config in current directory", dir: project, want: local},
+		{name: "local config in parent directory", dir: nested, want: local},
+		{name: "falls back to global config", dir: filepath.Join(root, "work"), want: GlobalConfigPath(home)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discover(tt.env, tt.dir, home)
+			if err != nil {
+				t.Fatalf("discover() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("discover() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// SyncInterval returns how often a repository should be synced: its own
+// updateFrequency when set, otherwise the global one
+func (c *Config) SyncInterval(repo Repository) time.Duration {
+	if repo.UpdateFrequency > 0 {
+		return repo.UpdateFrequency
+	}
+	return c.UpdateFrequency
+}
+
+// IsStale reports whether a repository is due for a sync at now. Repositories
+// that have never been synced, aren't cloned yet, or have no interval
+// configured are always stale.
+func (c *Config) IsStale(repo Repository, now time.Time) bool {
+	interval := c.SyncInterval(repo)
+	if repo.LastSync.IsZero() || interval <= 0 || !isCloned(repo) {
+		return true
+	}
+	return !repo.LastSync.Add(interval).After(now)
+}
+
+// isCloned reports whether a repository's directory exists. Repositories
+// without a path are treated as cloned, leaving them to LastSync.
+func isCloned(repo Repository) bool {
+	if repo.Path == "" {
+		return true
+	}
+	path, err := ExpandPath(repo.Path)
+	if err != nil {
+		return true
+	}
+	_, err = os.Stat(path)
+	return !os.IsNotExist(err)
+}
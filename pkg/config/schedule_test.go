@@ -0,0 +1,62 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_IsStale(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	cfg := &Config{UpdateFrequency: 2 * time.Hour}
+
+	tests := []struct {
+		name string
+		repo Repository
+		want bool
+	}{
+		{
+			name: "never synced",
+			repo: Repository{},
+			want: true,
+		},
+		{
+			name: "synced within the global interval",
+			repo: Repository{LastSync: now.Add(-time.Hour)},
+			want: false,
+		},
+		{
+			name: "synced before the global interval",
+			repo: Repository{LastSync: now.Add(-3 * time.Hour)},
+			want: true,
+		},
+		{
+			name: "exactly one interval ago",
+			repo: Repository{LastSync: now.Add(-2 * time.Hour)},
+			want: true,
+		},
+		{
+			name: "repository interval overrides the global one",
+			repo: Repository{LastSync: now.Add(-3 * time.Hour), UpdateFrequency: 24 * time.Hour},
+			want: false,
+		},
+		{
+			name: "synced recently but not cloned",
+			repo: Repository{LastSync: now.Add(-time.Hour), Path: filepath.Join(t.TempDir(), "missing")},
+			want: true,
+		},
+		{
+			name: "synced recently and cloned",
+			repo: Repository{LastSync: now.Add(-time.Hour), Path: t.TempDir()},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.IsStale(tt.repo, now); got != tt.want {
+				t.Errorf("Config.IsStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sections selects the parts of a configuration Export and Import handle
+type Sections struct {
+	Repositories bool
+	Dependencies bool
+	Tools        bool
+}
+
+// AllSections selects every shareable part of a configuration
+var AllSections = Sections{Repositories: true, Dependencies: true, Tools: true}
+
+// Export returns the selected sections of the loaded configuration in a form
+// that can be shared with others. Machine-specific values are left out:
+// repository paths and sync times, dependency install paths, and tool paths
+// under the home directory are rewritten with ~. Credentials are left out
+// too: references such as ${env:...} are kept unresolved, dependency headers
+// are dropped and user info is removed from http(s) URLs.
+func (m *Manager) Export(s Sections) *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	view := m.config.clone()
+	restoreReferences(view, m.refs)
+	home, _ := os.UserHomeDir()
+
+	shared := &Config{Version: CurrentVersion}
+	if s.Repositories {
+		for _, repo := range view.Repositories {
+			repo.Path = ""
+			repo.LastSync = time.Time{}
+			repo.URL = stripCredentials(repo.URL)
+			repo.UpstreamURL = stripCredentials(repo.UpstreamURL)
+			shared.Repositories = append(shared.Repositories, repo)
+		}
+	}
+	if s.Dependencies {
+		for _, dep := range view.Dependencies {
+			dep.Path = ""
+			dep.Headers = nil
+			dep.Source = stripCredentials(dep.Source)
+			shared.Dependencies = append(shared.Dependencies, dep)
+		}
+	}
+	if s.Tools {
+		for _, tool := range view.Tools {
+			tool.ConfigPath = homeRelative(tool.ConfigPath, home)
+			tool.BackupPath = homeRelative(tool.BackupPath, home)
+			tool.Source = homeRelative(tool.Source, home)
+			shared.Tools = append(shared.Tools, tool)
+		}
+	}
+	return shared
+}
+
+// stripCredentials removes the user info, such as a token, from http(s)
+// URLs. Other URLs, including ssh ones whose user is part of the address,
+// are returned unchanged.
+func stripCredentials(url string) string {
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return url
+	}
+	authority, path, hasPath := strings.Cut(rest, "/")
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		authority = authority[i+1:]
+	}
+	if !hasPath {
+		return scheme + "://" + authority
+	}
+	return scheme + "://" + authority + "/" + path
+}
+
+// homeRelative rewrites a path under home to start with ~
+func homeRelative(path, home string) string {
+	if home == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && filepath.IsAbs(path) && !strings.HasPrefix(rel, "..") {
+		if rel == "." {
+			return "~"
+		}
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// ImportReport describes what Import did with each shared item, named like
+// "repository web"
+type ImportReport struct {
+	Added []string
+	// Skipped items were already configured the same way
+	Skipped []string
+	// Conflicts are items configured differently under the same name, which
+	// are left as they are
+	Conflicts []string
+	// Hooks describes the hooks of added repositories, e.g.
+	// "repository web: postClone runs npm install", which run shell commands
+	// and so deserve a look before the import is saved
+	Hooks []string
+}
+
+// Import merges the selected sections of shared, as written by Export, into
+// c. Repositories are placed in c's workspace. Items whose name is already
+// taken are never overwritten: they are skipped when configured the same
+// way and reported as conflicts otherwise. Names that would place an item
+// outside the workspace, such as "../x", are rejected.
+func (c *Config) Import(shared *Config, s Sections) (ImportReport, error) {
+	var report ImportReport
+	if _, err := migrate(shared); err != nil {
+		return report, err
+	}
+
+	if s.Repositories {
+		for _, repo := range shared.Repositories {
+			if repo.Name == "" || repo.URL == "" {
+				return report, fmt.Errorf("shared repository %q is missing its name or url", repo.Name)
+			}
+			if !validShareName(repo.Name) {
+				return report, fmt.Errorf("shared repository %q has an invalid name", repo.Name)
+			}
+			repo.Path = filepath.Join(c.WorkspacePath, repo.Name)
+			repo.LastSync = time.Time{}
+			if repo.Branch == "" {
+				repo.Branch = c.Defaults.Branch
+			}
+			if repo.Branch == "" {
+				repo.Branch = "main"
+			}
+
+			label := "repository " + repo.Name
+			existing, ok := c.FindRepository(repo.Name)
+			switch {
+			case !ok:
+				c.Repositories = append(c.Repositories, repo)
+				report.Added = append(report.Added, label)
+				if repo.Hooks.PostClone != "" {
+					report.Hooks = append(report.Hooks, fmt.Sprintf("%s: postClone runs %s", label, repo.Hooks.PostClone))
+				}
+				if repo.Hooks.PostSync != "" {
+					report.Hooks = append(report.Hooks, fmt.Sprintf("%s: postSync runs %s", label, repo.Hooks.PostSync))
+				}
+			case sameRepository(*existing, repo):
+				report.Skipped = append(report.Skipped, label)
+			default:
+				report.Conflicts = append(report.Conflicts, label)
+			}
+		}
+	}
+
+	if s.Dependencies {
+		for _, dep := range shared.Dependencies {
+			if dep.Name == "" {
+				return report, fmt.Errorf("shared dependency is missing its name")
+			}
+			if !validShareName(dep.Name) {
+				return report, fmt.Errorf("shared dependency %q has an invalid name", dep.Name)
+			}
+			dep.Path = ""
+
+			label := "dependency " + dep.Name
+			existing, ok := c.FindDependency(dep.Name)
+			switch {
+			case !ok:
+				c.Dependencies = append(c.Dependencies, dep)
+				report.Added = append(report.Added, label)
+			case existing.Version == dep.Version && existing.Source == dep.Source && existing.Checksum == dep.Checksum &&
+				existing.InstallScript == dep.InstallScript:
+				report.Skipped = append(report.Skipped, label)
+			default:
+				report.Conflicts = append(report.Conflicts, label)
+			}
+		}
+	}
+
+	if s.Tools {
+		for _, tool := range shared.Tools {
+			if tool.Name == "" {
+				return report, fmt.Errorf("shared tool is missing its name")
+			}
+
+			label := "tool " + tool.Name
+			i := slices.IndexFunc(c.Tools, func(t ToolConfig) bool { return t.Name == tool.Name })
+			switch {
+			case i < 0:
+				c.Tools = append(c.Tools, tool)
+				report.Added = append(report.Added, label)
+			case sameTool(c.Tools[i], tool):
+				report.Skipped = append(report.Skipped, label)
+			default:
+				report.Conflicts = append(report.Conflicts, label)
+			}
+		}
+	}
+	return report, nil
+}
+
+// validShareName reports whether name can be used as a directory name
+// without escaping the directory it is joined to
+func validShareName(name string) bool {
+	return !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..") && name != "."
+}
+
+// sameTool reports whether two tools use the same paths, counting ~ and the
+// home directory it stands for as equal
+func sameTool(a, b ToolConfig) bool {
+	same := func(x, y string) bool {
+		x, _ = ExpandPath(x)
+		y, _ = ExpandPath(y)
+		return x == y
+	}
+	return same(a.ConfigPath, b.ConfigPath) && same(a.BackupPath, b.BackupPath) && same(a.Source, b.Source)
+}
+
+// sameRepository reports whether two repositories are set up the same way,
+// including their hooks, ignoring where they are checked out and when they
+// were last synced
+func sameRepository(a, b Repository) bool {
+	return a.URL == b.URL && a.UpstreamURL == b.UpstreamURL && a.Branch == b.Branch &&
+		a.Remote == b.Remote && a.Ref == b.Ref && a.Submodules == b.Submodules && a.URLScheme == b.URLScheme &&
+		a.Hooks == b.Hooks
+}
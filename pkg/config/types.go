@@ -2,41 +2,94 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
 // Repository represents a Git repository to be managed
 type Repository struct {
-	Name     string    `yaml:"name"`
-	URL      string    `yaml:"url"`
-	Branch   string    `yaml:"branch"`
-	Path     string    `yaml:"path"`
-	LastSync time.Time `yaml:"lastSync"`
+	Name            string        `yaml:"name" json:"name"`
+	URL             string        `yaml:"url" json:"url"`
+	UpstreamURL     string        `yaml:"upstreamURL,omitempty" json:"upstreamURL,omitempty"` // Repository the URL is a fork of
+	Branch          string        `yaml:"branch,omitempty" json:"branch,omitempty"`
+	Remote          string        `yaml:"remote,omitempty" json:"remote,omitempty"`         // Remote to clone as and sync with; origin by default
+	Ref             string        `yaml:"ref,omitempty" json:"ref,omitempty"`               // Tag or commit to pin the checkout to
+	Submodules      bool          `yaml:"submodules,omitempty" json:"submodules,omitempty"` // Clone and update submodules recursively
+	URLScheme       string        `yaml:"urlScheme,omitempty" json:"urlScheme,omitempty"`   // Clone over https or ssh regardless of URL; as-is by default
+	Path            string        `yaml:"path" json:"path"`
+	UpdateFrequency time.Duration `yaml:"updateFrequency,omitempty" json:"updateFrequency,omitempty"` // Overrides the global sync interval
+	Tags            []string      `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Hooks           Hooks         `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	LastSync        time.Time     `yaml:"lastSync" json:"lastSync"`
+}
+
+// Hooks are shell commands run in a repository's directory after dev-manager
+// clones or syncs it
+type Hooks struct {
+	PostClone string `yaml:"postClone,omitempty" json:"postClone,omitempty"` // Run once the repository has been cloned
+	PostSync  string `yaml:"postSync,omitempty" json:"postSync,omitempty"`   // Run after each successful update of an existing clone
+	// Timeout bounds each hook; a default applies when unset
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 }
 
 // ToolConfig represents configuration for development tools
 type ToolConfig struct {
-	Name       string `yaml:"name"`
-	ConfigPath string `yaml:"configPath"`
-	BackupPath string `yaml:"backupPath"`
+	Name       string `yaml:"name" json:"name"`
+	Source     string `yaml:"source,omitempty" json:"source,omitempty"` // Managed config, e.g. in a dotfiles repository
+	ConfigPath string `yaml:"configPath" json:"configPath"`
+	BackupPath string `yaml:"backupPath" json:"backupPath"`
 }
 
 // Dependency represents a development dependency
 type Dependency struct {
-	Name    string `yaml:"name"`
-	Version string `yaml:"version"`
-	Source  string `yaml:"source"` // URL or source location
-	Path    string `yaml:"path"`   // Installation path
+	Name    string `yaml:"name" json:"name"`
+	Version string `yaml:"version" json:"version"`
+	Source  string `yaml:"source" json:"source"` // URL or source location
+	// Checksum is the expected sha256 of the downloaded source, optionally
+	// prefixed with "sha256:"
+	Checksum string `yaml:"checksum,omitempty" json:"checksum,omitempty"`
+	Path     string `yaml:"path" json:"path"` // Installation path
+	// InstallScript is a path, relative to the extracted archive, of a script
+	// run after extraction to finish the installation. It runs arbitrary code
+	// with the user's privileges, so it is only executed when explicitly allowed.
+	InstallScript string   `yaml:"installScript,omitempty" json:"installScript,omitempty"`
+	Tags          []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Headers are sent with the download request, e.g. an Authorization
+	// header for release assets behind a token. Use ${env:...} or
+	// ${file:...} references to keep secrets out of the file.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+}
+
+// Defaults holds values applied to repositories and dependencies that don't set them
+type Defaults struct {
+	Branch          string        `yaml:"branch,omitempty" json:"branch,omitempty"`
+	UpdateFrequency time.Duration `yaml:"updateFrequency,omitempty" json:"updateFrequency,omitempty"`
+	Tags            []string      `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Hooks apply field by field, so a repository can override just one hook
+	Hooks Hooks `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// LLM configures the prompts git-ops sends to the LLM
+type LLM struct {
+	// CommitPromptPath and ReviewPromptPath are text/template files replacing
+	// the built-in commit message and PR review prompts. Relative paths are
+	// relative to the config file.
+	CommitPromptPath string `yaml:"commitPromptPath,omitempty" json:"commitPromptPath,omitempty"`
+	ReviewPromptPath string `yaml:"reviewPromptPath,omitempty" json:"reviewPromptPath,omitempty"`
 }
 
 // Config represents the main configuration structure
 type Config struct {
-	Repositories    []Repository  `yaml:"repositories"`
-	Tools           []ToolConfig  `yaml:"tools"`
-	Dependencies    []Dependency  `yaml:"dependencies"`
-	UpdateFrequency time.Duration `yaml:"updateFrequency"`
-	WorkspacePath   string        `yaml:"workspacePath"`
+	Version         int           `yaml:"version" json:"version"` // Schema version, see CurrentVersion
+	Defaults        Defaults      `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	Repositories    []Repository  `yaml:"repositories" json:"repositories"`
+	Tools           []ToolConfig  `yaml:"tools" json:"tools"`
+	Dependencies    []Dependency  `yaml:"dependencies" json:"dependencies"`
+	UpdateFrequency time.Duration `yaml:"updateFrequency" json:"updateFrequency"`
+	WorkspacePath   string        `yaml:"workspacePath" json:"workspacePath"`
+	ExcludePaths    []string      `yaml:"excludePaths,omitempty" json:"excludePaths,omitempty"` // Globs relative to WorkspacePath that scans leave alone
+	LLM             LLM           `yaml:"llm,omitempty" json:"llm,omitempty"`
 }
 
 // ValidationError represents a collection of configuration validation errors
@@ -69,6 +122,12 @@ func (c *Config) Validate() error {
 		errors = append(errors, "updateFrequency must be positive")
 	}
 
+	for _, pattern := range c.ExcludePaths {
+		if _, err := filepath.Match(pattern, ""); err != nil || filepath.IsAbs(pattern) {
+			errors = append(errors, fmt.Sprintf("invalid excludePaths pattern %q (want a glob relative to workspacePath)", pattern))
+		}
+	}
+
 	// Validate repositories
 	for i, repo := range c.Repositories {
 		repoErrors := []string{}
@@ -84,6 +143,16 @@ func (c *Config) Validate() error {
 		if repo.Branch == "" {
 			repoErrors = append(repoErrors, "missing branch")
 		}
+		switch repo.URLScheme {
+		case "", "as-is", "https", "ssh":
+		default:
+			repoErrors = append(repoErrors, fmt.Sprintf("invalid urlScheme %q (want https, ssh or as-is)", repo.URLScheme))
+		}
+		if strings.ContainsAny(repo.Remote, " /:") || strings.HasPrefix(repo.Remote, "-") {
+			repoErrors = append(repoErrors, fmt.Sprintf("invalid remote %q", repo.Remote))
+		} else if repo.Remote == "upstream" && repo.UpstreamURL != "" {
+			repoErrors = append(repoErrors, `remote "upstream" is reserved for the fork's upstreamURL`)
+		}
 		if len(repoErrors) > 0 {
 			errors = append(errors, fmt.Sprintf("repository[%d] (%s): %s", i, repo.Name, strings.Join(repoErrors, ", ")))
 		}
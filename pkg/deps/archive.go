@@ -0,0 +1,249 @@
+package deps
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveFormat is how a downloaded source is unpacked
+type archiveFormat string
+
+const (
+	formatBinary archiveFormat = "binary"
+	formatTar    archiveFormat = "tar"
+	formatTarGz  archiveFormat = "tar.gz"
+	formatTarXz  archiveFormat = "tar.xz"
+	formatTarBz2 archiveFormat = "tar.bz2"
+	formatZip    archiveFormat = "zip"
+)
+
+// sniffLen is how much of a source is read to detect its format; it covers
+// the magic at offset 257 of a tar header
+const sniffLen = 512
+
+// suffixFormats maps source file name suffixes to the format they suggest
+var suffixFormats = []struct {
+	suffix string
+	format archiveFormat
+}{
+	{".tar.gz", formatTarGz},
+	{".tgz", formatTarGz},
+	{".tar.xz", formatTarXz},
+	{".txz", formatTarXz},
+	{".tar.bz2", formatTarBz2},
+	{".tbz2", formatTarBz2},
+	{".zip", formatZip},
+	{".tar", formatTar},
+}
+
+// detectFormat works out a source's format from the magic bytes at the start
+// of its content. The source URL's suffix is only a hint: it is needed for
+// old tar archives, which have no magic, and it catches sources named like an
+// archive whose content is something else, such as an HTML error page, which
+// would otherwise be installed as a binary.
+func detectFormat(header []byte, source string) (archiveFormat, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return formatTarGz, nil
+	case bytes.HasPrefix(header, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return formatTarXz, nil
+	case bytes.HasPrefix(header, []byte("BZh")) && len(header) > 3 && header[3] >= '1' && header[3] <= '9':
+		return formatTarBz2, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return formatZip, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return formatTar, nil
+	}
+
+	switch hint := suffixFormat(source); hint {
+	case formatBinary, formatTar:
+		return hint, nil
+	default:
+		return "", fmt.Errorf("source is named like a %s archive but its content is not one", hint)
+	}
+}
+
+// suffixFormat returns the format a source URL's file name suggests, ignoring
+// any query string
+func suffixFormat(source string) archiveFormat {
+	name := source
+	if u, err := url.Parse(source); err == nil {
+		name = u.Path
+	}
+	name = strings.ToLower(name)
+	for _, s := range suffixFormats {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.format
+		}
+	}
+	return formatBinary
+}
+
+// extract unpacks a downloaded source into dest according to its detected
+// format. Sources that aren't archives are copied to dest as a binary called
+// name.
+func extract(f *os.File, name, source, dest string) error {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read download: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read download: %w", err)
+	}
+
+	format, err := detectFormat(header[:n], source)
+	if err != nil {
+		return err
+	}
+	slog.Debug("detected source format", "source", source, "format", string(format))
+
+	switch format {
+	case formatTarGz:
+		err = extractTarGz(f, dest)
+	case formatTarXz:
+		err = extractTarXz(f, dest)
+	case formatTarBz2:
+		err = extractTar(bzip2.NewReader(f), dest)
+	case formatTar:
+		err = extractTar(f, dest)
+	case formatZip:
+		err = extractZip(f, dest)
+	default:
+		if err := copyBinary(f, filepath.Join(dest, name)); err != nil {
+			return fmt.Errorf("failed to copy file: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", format, err)
+	}
+	return nil
+}
+
+// copyBinary writes a source that isn't an archive to path as an executable
+func copyBinary(r io.Reader, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// extractZip unpacks a zip archive into dest with the same safeguards as
+// extractTar
+func extractZip(f *os.File, dest string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return err
+	}
+
+	var dirs []*zip.File
+	for _, zf := range zr.File {
+		target, err := extractTarget(realDest, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		slog.Debug("extracting entry", "name", zf.Name, "mode", mode.String())
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, zf)
+		case mode&os.ModeSymlink != 0:
+			linkname, err := readZipEntry(zf)
+			if err != nil {
+				return err
+			}
+			// Links may point anywhere inside the archive but not out of it
+			if !linkWithin(realDest, target, linkname) {
+				return fmt.Errorf("archive entry %s links outside the extraction directory: %s", zf.Name, linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(linkname, target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			// Opening an existing link would write to wherever it points
+			if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("archive entry %s would overwrite a symlink", zf.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeZipEntry(zf, target); err != nil {
+				return err
+			}
+			if err := setModTime(target, zf.Modified); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Directory times are applied last, since extracting their contents bumps them
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target := filepath.Join(realDest, dirs[i].Name)
+		if err := setModTime(target, dirs[i].Modified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readZipEntry returns the content of a small zip entry, such as a symlink's
+// target
+func readZipEntry(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// writeZipEntry extracts a regular zip entry to target with its permissions
+func writeZipEntry(zf *zip.File, target string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, zf.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,120 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tarball := mockhttp.Tar(t, mockhttp.Entry{Name: "tool", Body: "x"})
+	tests := []struct {
+		name    string
+		header  []byte
+		source  string
+		want    archiveFormat
+		wantErr bool
+	}{
+		{name: "gzip", header: mockhttp.TarGz(t), source: "https://example.com/download?id=1", want: formatTarGz},
+		{name: "xz", header: []byte("\xfd7zXZ\x00rest"), source: "https://example.com/tool", want: formatTarXz},
+		{name: "bzip2", header: []byte("BZh91AY&SY"), source: "https://example.com/tool", want: formatTarBz2},
+		{name: "zip", header: mockhttp.Zip(t, mockhttp.Entry{Name: "tool"}), source: "https://example.com/asset/42", want: formatZip},
+		{name: "tar", header: tarball[:sniffLen], source: "https://example.com/tool", want: formatTar},
+		{name: "content wins over suffix", header: mockhttp.TarGz(t), source: "https://example.com/tool.zip", want: formatTarGz},
+		{name: "binary", header: []byte("\x7fELF\x02\x01\x01"), source: "https://example.com/tool", want: formatBinary},
+		{name: "suffix behind query", header: []byte("#!/bin/sh\n"), source: "https://example.com/tool?name=x.tar.gz", want: formatBinary},
+		{name: "tar without magic", header: []byte("tool\x00\x00"), source: "https://example.com/old.tar", want: formatTar},
+		{name: "error page named like an archive", header: []byte("<html>"), source: "https://example.com/tool.TGZ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat(tt.header, tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []mockhttp.Entry
+		want    []string // files expected under dest afterwards
+		wantErr bool
+	}{
+		{
+			name:    "regular files",
+			entries: []mockhttp.Entry{{Name: "bin/"}, {Name: "bin/tool", Body: "x", Mode: 0755}},
+			want:    []string{"bin/tool"},
+		},
+		{
+			name:    "parent traversal",
+			entries: []mockhttp.Entry{{Name: "../evil", Body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink inside archive",
+			entries: []mockhttp.Entry{{Name: "lib/tool", Body: "x"}, {Name: "bin/tool", Symlink: "../lib/tool"}},
+			want:    []string{"lib/tool", "bin/tool"},
+		},
+		{
+			name:    "symlink escaping dest",
+			entries: []mockhttp.Entry{{Name: "bin/up", Symlink: "../../.."}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink escaping dest through an earlier link",
+			entries: []mockhttp.Entry{{Name: "y", Symlink: "."}, {Name: "z", Symlink: "y/.."}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink through an earlier link",
+			entries: []mockhttp.Entry{{Name: "lib/tool", Body: "x"}, {Name: "y", Symlink: "lib"}, {Name: "bin/tool", Symlink: "../y/tool"}},
+			want:    []string{"lib/tool", "bin/tool"},
+		},
+		{
+			name:    "file written over symlink",
+			entries: []mockhttp.Entry{{Name: "real", Body: "x"}, {Name: "link", Symlink: "real"}, {Name: "link", Body: "y"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(root, "archive.zip")
+			if err := os.WriteFile(archive, mockhttp.Zip(t, tt.entries...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			err = extractZip(f, dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractZip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, name := range tt.want {
+				if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+					t.Errorf("expected %s to be extracted: %v", name, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+				t.Errorf("archive wrote outside the extraction directory")
+			}
+		})
+	}
+}
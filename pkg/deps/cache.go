@@ -0,0 +1,487 @@
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+)
+
+// CacheDirName is the directory under InstallDir holding downloaded archives
+const CacheDirName = ".cache"
+
+// ChecksumMismatchError is returned when a download doesn't match the
+// dependency's configured checksum
+type ChecksumMismatchError struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256:%s, got sha256:%s", e.Name, e.Expected, e.Actual)
+}
+
+// CacheDir returns the directory downloaded archives are cached in
+func (m *Manager) CacheDir() string {
+	return filepath.Join(m.InstallDir, CacheDirName)
+}
+
+// CleanCache deletes all cached downloads
+func (m *Manager) CleanCache() error {
+	if err := os.RemoveAll(m.CacheDir()); err != nil {
+		return fmt.Errorf("failed to remove download cache: %w", err)
+	}
+	return nil
+}
+
+// cachePath returns where a dependency's download is cached. The key covers
+// the checksum too, so changing it never reuses an archive fetched under the
+// old one.
+func (m *Manager) cachePath(dep config.Dependency) string {
+	sum := sha256.Sum256([]byte(dep.Source + "\n" + dep.Checksum))
+	return filepath.Join(m.CacheDir(), hex.EncodeToString(sum[:]))
+}
+
+// partialSuffix marks an interrupted download kept in the cache so the next
+// attempt can resume it
+const partialSuffix = ".partial"
+
+// validatorSuffix marks the file next to a partial download holding the ETag
+// or Last-Modified it was downloaded under
+const validatorSuffix = ".validator"
+
+// downloadAttempts is how many times fetch tries a download that was
+// interrupted but can be resumed
+const downloadAttempts = 3
+
+// claimPartial returns a download file of its own for this process, with
+// the interrupted download kept at partial moved into it, if there is one,
+// along with the validator to resume it under. Renaming claims the partial
+// download atomically, so concurrent installs of the same dependency never
+// write to the same file: all but one start from scratch.
+func claimPartial(partial string) (*os.File, string, error) {
+	f, err := os.CreateTemp(filepath.Dir(partial), filepath.Base(partial)+".*")
+	if err != nil {
+		return nil, "", err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	if err := os.Rename(partial, f.Name()); err != nil {
+		return f, "", nil
+	}
+
+	// The file just created was replaced, so open the claimed one instead
+	f.Close()
+	if f, err = os.OpenFile(f.Name(), os.O_RDWR, 0); err != nil {
+		return nil, "", err
+	}
+	var validator string
+	if data, err := os.ReadFile(partial + validatorSuffix); err == nil {
+		validator = strings.TrimSpace(string(data))
+	}
+	os.Remove(partial + validatorSuffix)
+	return f, validator, nil
+}
+
+// fetch returns the downloaded payload for a dependency, reusing the cached
+// copy when it is present and still matches the configured checksum. The
+// returned cleanup func must be called once the payload has been read.
+//
+// Interrupted downloads from servers that accept range requests are resumed,
+// both right away and, since the partial download is kept in the cache, by
+// later installs.
+func (m *Manager) fetch(ctx context.Context, dep config.Dependency) (*os.File, func(), error) {
+	var f *os.File
+	var partial, validator string
+	if m.NoCache {
+		var err error
+		if f, err = os.CreateTemp("", "download-*"); err != nil {
+			return nil, nil, fmt.Errorf("failed to create download file: %w", err)
+		}
+	} else {
+		path := m.cachePath(dep)
+		if f, err := os.Open(path); err == nil {
+			if err := verifyChecksum(f, dep); err == nil {
+				slog.Debug("using cached download", "dependency", dep.Name, "path", path)
+				return f, func() { f.Close() }, nil
+			}
+			// A corrupt or outdated entry is simply downloaded again
+			slog.Debug("discarding invalid cached download", "dependency", dep.Name, "path", path, "error", err)
+			f.Close()
+			os.Remove(path)
+		}
+
+		if err := os.MkdirAll(m.CacheDir(), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		partial = path + partialSuffix
+		var err error
+		if f, validator, err = claimPartial(partial); err != nil {
+			return nil, nil, fmt.Errorf("failed to create download file: %w", err)
+		}
+	}
+	discard := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	resumed := false
+	for attempt := 1; ; attempt++ {
+		if info, err := f.Stat(); err == nil && validator != "" && info.Size() > 0 {
+			resumed = true
+		}
+		var err error
+		validator, err = m.download(ctx, dep, f, validator)
+		if err == nil {
+			break
+		}
+		if validator == "" {
+			discard()
+			return nil, nil, err
+		}
+		if attempt == downloadAttempts || ctx.Err() != nil {
+			if partial == "" {
+				discard()
+				return nil, nil, err
+			}
+			// Keep what was downloaded for the next install to resume
+			f.Close()
+			if os.WriteFile(partial+validatorSuffix, []byte(validator+"\n"), 0644) != nil || os.Rename(f.Name(), partial) != nil {
+				os.Remove(f.Name())
+			}
+			return nil, nil, err
+		}
+		slog.Info("retrying interrupted download", "dependency", dep.Name, "attempt", attempt+1, "error", err)
+	}
+
+	if err := verifyChecksum(f, dep); err != nil {
+		if !resumed {
+			discard()
+			return nil, nil, err
+		}
+		// The parts may come from different versions of the source; start over
+		slog.Info("resumed download failed verification, downloading again", "dependency", dep.Name, "error", err)
+		if err := f.Truncate(0); err != nil {
+			discard()
+			return nil, nil, err
+		}
+		if _, err := m.download(ctx, dep, f, ""); err != nil {
+			discard()
+			return nil, nil, err
+		}
+		if err := verifyChecksum(f, dep); err != nil {
+			discard()
+			return nil, nil, err
+		}
+	}
+
+	if m.NoCache {
+		return f, discard, nil
+	}
+	// Only complete, verified downloads are moved into place
+	if err := os.Rename(f.Name(), m.cachePath(dep)); err != nil {
+		discard()
+		return nil, nil, fmt.Errorf("failed to cache download: %w", err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// DownloadTooLargeError is returned when a source is bigger than the
+// manager's MaxDownloadBytes
+type DownloadTooLargeError struct {
+	Name  string
+	URL   string
+	Limit int64
+}
+
+func (e *DownloadTooLargeError) Error() string {
+	return fmt.Sprintf("failed to download %s: %s is larger than the %d byte limit", e.Name, e.URL, e.Limit)
+}
+
+// DefaultMaxDownloadBytes is the download size cap New gives a Manager
+const DefaultMaxDownloadBytes = 4 << 30
+
+// maxRedirects is how many redirects NewHTTPClient follows per download
+const maxRedirects = 10
+
+// DownloadTimeout bounds a whole download made with NewHTTPClient, including
+// reading the body
+const DownloadTimeout = 30 * time.Minute
+
+// NewHTTPClient returns the client New gives a Manager. It uses the proxy
+// named by HTTP_PROXY, HTTPS_PROXY and NO_PROXY, and times out connections
+// and servers that stop responding instead of hanging.
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: DownloadTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: time.Minute,
+			IdleConnTimeout:       90 * time.Second,
+			ForceAttemptHTTP2:     true,
+		},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// checkRedirect bounds how many redirects a download follows and refuses to
+// be redirected from https to plain http
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if prev := via[len(via)-1]; prev.URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from %s to insecure %s", prev.URL.Redacted(), req.URL.Redacted())
+	}
+	slog.Debug("following redirect", "from", via[len(via)-1].URL.Redacted(), "to", req.URL.Redacted())
+	return nil
+}
+
+// SourceStatusError is returned by CheckSource when a dependency's source
+// answers with a non-2xx status
+type SourceStatusError struct {
+	Name       string
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *SourceStatusError) Error() string {
+	return fmt.Sprintf("source of %s: %s returned %s", e.Name, e.URL, e.Status)
+}
+
+// CheckSource checks that a dependency's source is reachable with a HEAD
+// request, sending its Headers, without downloading it. Hosts that reject
+// HEAD, as S3 and GitHub release assets do with signed URLs, or don't
+// implement it are asked for the first byte of the source with a ranged GET
+// instead.
+func (m *Manager) CheckSource(ctx context.Context, dep config.Dependency) error {
+	resp, err := m.requestSource(ctx, http.MethodHead, dep)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		slog.Debug("source rejected HEAD, retrying with GET", "dependency", dep.Name, "status", resp.Status)
+		if resp, err = m.requestSource(ctx, http.MethodGet, dep); err != nil {
+			return err
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &SourceStatusError{Name: dep.Name, URL: resp.Request.URL.Redacted(), StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// requestSource sends a bodiless request for a dependency's source, asking
+// GETs for the first byte only, and closes the response body
+func (m *Manager) requestSource(ctx context.Context, method string, dep config.Dependency) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, dep.Source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source for %s: %w", dep.Name, err)
+	}
+	for name, value := range dep.Headers {
+		req.Header.Set(name, value)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("source of %s is unreachable: %w", dep.Name, err)
+	}
+	resp.Body.Close()
+
+	slog.Debug("source check response", "dependency", dep.Name, "method", method, "status", resp.Status, "url", resp.Request.URL.Redacted())
+	return resp, nil
+}
+
+// download writes a dependency's source to f, sending its Headers. When f
+// already holds the start of the source and validator is the ETag or
+// Last-Modified it was downloaded under, only the rest is requested with a
+// range request and appended; if the server sends the whole source instead,
+// because it ignores ranges or the source changed, f is rewritten.
+//
+// It returns the validator of the response when the server accepts range
+// requests, so that a failed download can be resumed from what was written
+// to f, and "" otherwise.
+func (m *Manager) download(ctx context.Context, dep config.Dependency, f *os.File, validator string) (string, error) {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep.Name, err)
+	}
+	if validator == "" {
+		offset = 0
+	}
+
+	slog.Info("downloading dependency", "dependency", dep.Name, "url", dep.Source)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dep.Source, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep.Name, err)
+	}
+	for name, value := range dep.Headers {
+		req.Header.Set(name, value)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Nothing new was written, so what f holds can still be resumed
+		return validator, fmt.Errorf("failed to download %s: %w", dep.Name, err)
+	}
+	defer resp.Body.Close()
+
+	slog.Debug("download response", "dependency", dep.Name, "status", resp.Status, "url", resp.Request.URL.Redacted(),
+		"contentType", resp.Header.Get("Content-Type"), "contentLength", resp.ContentLength)
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return "", fmt.Errorf("failed to download %s: %s resumed at the wrong offset", dep.Name, resp.Request.URL.Redacted())
+		}
+		slog.Info("resuming download", "dependency", dep.Name, "offset", offset)
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// f may already hold the whole source, when the download was cut
+		// off right after its last byte
+		if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok && size == offset {
+			slog.Debug("download already complete", "dependency", dep.Name, "bytes", offset)
+			return validator, nil
+		}
+		slog.Info("cannot resume download, downloading again", "dependency", dep.Name, "offset", offset)
+		if err := f.Truncate(0); err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", dep.Name, err)
+		}
+		return m.download(ctx, dep, f, "")
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", dep.Name, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("failed to download %s: %w", dep.Name, err)
+		}
+	default:
+		return "", fmt.Errorf("failed to download %s: %s returned %s", dep.Name, resp.Request.URL.Redacted(), resp.Status)
+	}
+
+	validator = ""
+	if resp.StatusCode == http.StatusPartialContent || resp.Header.Get("Accept-Ranges") == "bytes" {
+		validator = resumeValidator(resp.Header)
+	}
+
+	// Read one byte past the limit to tell a source of exactly the limit
+	// from a bigger one
+	limit := m.MaxDownloadBytes
+	body := io.Reader(resp.Body)
+	if limit > 0 {
+		if resp.ContentLength >= 0 && offset+resp.ContentLength > limit {
+			return "", &DownloadTooLargeError{Name: dep.Name, URL: resp.Request.URL.Redacted(), Limit: limit}
+		}
+		body = io.LimitReader(resp.Body, limit-offset+1)
+	}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return validator, fmt.Errorf("failed to download %s: %w", dep.Name, err)
+	}
+	if limit > 0 && offset+n > limit {
+		return "", &DownloadTooLargeError{Name: dep.Name, URL: resp.Request.URL.Redacted(), Limit: limit}
+	}
+	slog.Debug("download complete", "dependency", dep.Name, "bytes", offset+n)
+	return validator, nil
+}
+
+// resumeValidator returns the response header a partial download can be
+// resumed with through If-Range: a strong ETag, or else Last-Modified
+func resumeValidator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header such as "bytes 100-199/200"
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// contentRangeSize returns the complete length of a Content-Range header
+// such as "bytes */200" or "bytes 100-199/200"
+func contentRangeSize(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	_, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n, err == nil
+}
+
+// verifyChecksum checks f against the dependency's checksum, if it has one,
+// and rewinds f so it can be read from the start
+func verifyChecksum(f *os.File, dep config.Dependency) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if dep.Checksum == "" {
+		return nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to checksum %s: %w", dep.Name, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	expected := strings.ToLower(strings.TrimPrefix(dep.Checksum, "sha256:"))
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return &ChecksumMismatchError{Name: dep.Name, Expected: expected, Actual: actual}
+	}
+	return nil
+}
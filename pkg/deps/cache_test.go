@@ -0,0 +1,468 @@
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestManager_InstallCache(t *testing.T) {
+	payload := []byte("#!/bin/sh\n")
+	sum := sha256.Sum256(payload)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name         string
+		checksum     string
+		noCache      bool
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "reinstall reuses cache", wantRequests: 1},
+		{name: "reinstall with checksum reuses cache", checksum: "sha256:" + checksum, wantRequests: 1},
+		{name: "no cache downloads every time", noCache: true, wantRequests: 2},
+		{name: "checksum mismatch", checksum: "deadbeef", wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockhttp.New(t, payload)
+			mgr := New(t.TempDir())
+			mgr.NoCache = tt.noCache
+			dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool"), Checksum: tt.checksum}
+
+			err := mgr.Install(dep, true)
+			if tt.wantErr {
+				var mismatch *ChecksumMismatchError
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("Manager.Install() error = %v, want ChecksumMismatchError", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Manager.Install() error = %v", err)
+				}
+				if err := mgr.Install(dep, true); err != nil {
+					t.Fatalf("second Manager.Install() error = %v", err)
+				}
+			}
+
+			if got := server.Requests(); got != tt.wantRequests {
+				t.Errorf("downloads = %d, want %d", got, tt.wantRequests)
+			}
+
+			entries, _ := os.ReadDir(mgr.CacheDir())
+			if cached := len(entries) > 0; cached != (!tt.noCache && !tt.wantErr) {
+				t.Errorf("cache populated = %v, cache entries = %d", cached, len(entries))
+			}
+		})
+	}
+}
+
+func TestManager_InstallDoesNotCacheErrors(t *testing.T) {
+	server := mockhttp.NewError(t, http.StatusNotFound)
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool")}
+
+	if err := mgr.Install(dep, false); err == nil {
+		t.Fatal("Manager.Install() succeeded for a 404 response, want error")
+	}
+	if entries, _ := os.ReadDir(mgr.CacheDir()); len(entries) != 0 {
+		t.Errorf("failed download left %d cache entries", len(entries))
+	}
+}
+
+func TestManager_CleanCache(t *testing.T) {
+	server := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool")}
+
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if err := mgr.CleanCache(); err != nil {
+		t.Fatalf("Manager.CleanCache() error = %v", err)
+	}
+	if _, err := os.Stat(mgr.CacheDir()); !os.IsNotExist(err) {
+		t.Errorf("cache directory still exists after CleanCache()")
+	}
+	if err := mgr.Install(dep, true); err != nil {
+		t.Fatalf("Manager.Install() after CleanCache() error = %v", err)
+	}
+	if got := server.Requests(); got != 2 {
+		t.Errorf("downloads = %d, want 2", got)
+	}
+}
+
+// countingTransport counts the requests made through it
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestManager_InstallClientAndHeaders(t *testing.T) {
+	server := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	transport := &countingTransport{}
+
+	mgr := New(t.TempDir())
+	mgr.Client = &http.Client{Transport: transport}
+	dep := config.Dependency{
+		Name:    "tool",
+		Source:  server.URLFor("tool"),
+		Headers: map[string]string{"Authorization": "Bearer secret", "Accept": "application/octet-stream"},
+	}
+
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if transport.requests != 1 {
+		t.Errorf("requests through the custom client = %d, want 1", transport.requests)
+	}
+	for name, want := range dep.Headers {
+		if got := server.LastHeader().Get(name); got != want {
+			t.Errorf("request header %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestManager_InstallSizeLimit(t *testing.T) {
+	payload := []byte("#!/bin/sh\necho tool\n")
+	server := mockhttp.New(t, payload)
+	dep := config.Dependency{Name: "tool", Source: server.URLFor("tool")}
+
+	mgr := New(t.TempDir())
+	mgr.MaxDownloadBytes = int64(len(payload))
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() at exactly the limit error = %v", err)
+	}
+
+	mgr = New(t.TempDir())
+	mgr.MaxDownloadBytes = int64(len(payload)) - 1
+	var tooLarge *DownloadTooLargeError
+	if err := mgr.Install(dep, false); !errors.As(err, &tooLarge) {
+		t.Fatalf("Manager.Install() over the limit error = %v, want DownloadTooLargeError", err)
+	}
+	if mgr.IsInstalled(dep) {
+		t.Error("oversized download was installed")
+	}
+}
+
+func TestManager_InstallFollowsRedirects(t *testing.T) {
+	server := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	redirect := httptest.NewServer(http.RedirectHandler(server.URLFor("assets/tool"), http.StatusFound))
+	defer redirect.Close()
+
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Source: redirect.URL + "/download?id=123"}
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("requests to the redirect target = %d, want 1", server.Requests())
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	request := func(rawURL string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	if err := checkRedirect(request("https://cdn.example.com/a"), []*http.Request{request("https://example.com/a")}); err != nil {
+		t.Errorf("checkRedirect() https to https error = %v", err)
+	}
+	if err := checkRedirect(request("http://cdn.example.com/a"), []*http.Request{request("https://example.com/a")}); err == nil {
+		t.Error("checkRedirect() allowed a redirect from https to http")
+	}
+	var via []*http.Request
+	for range maxRedirects {
+		via = append(via, request("https://example.com/a"))
+	}
+	if err := checkRedirect(request("https://example.com/b"), via); err == nil {
+		t.Errorf("checkRedirect() allowed more than %d redirects", maxRedirects)
+	}
+}
+
+func TestManager_CheckSource(t *testing.T) {
+	server := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	mgr := New(t.TempDir())
+	dep := config.Dependency{
+		Name:    "tool",
+		Source:  server.URLFor("tool"),
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}
+
+	if err := mgr.CheckSource(context.Background(), dep); err != nil {
+		t.Fatalf("Manager.CheckSource() error = %v", err)
+	}
+	if got := server.LastHeader().Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("request header Authorization = %q, want the dependency's header", got)
+	}
+	if mgr.IsInstalled(dep) {
+		t.Error("CheckSource installed the dependency")
+	}
+
+	missing := mockhttp.NewError(t, http.StatusNotFound)
+	var statusErr *SourceStatusError
+	err := mgr.CheckSource(context.Background(), config.Dependency{Name: "tool", Source: missing.URLFor("tool")})
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Manager.CheckSource() error = %v, want a 404 SourceStatusError", err)
+	}
+}
+
+func TestManager_CheckSourceWithoutHead(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusMethodNotAllowed} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(status)
+					return
+				}
+				ranges = append(ranges, r.Header.Get("Range"))
+				w.Header().Set("Content-Range", "bytes 0-0/10")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte("#"))
+			}))
+			defer server.Close()
+
+			mgr := New(t.TempDir())
+			if err := mgr.CheckSource(context.Background(), config.Dependency{Name: "tool", Source: server.URL + "/tool"}); err != nil {
+				t.Fatalf("Manager.CheckSource() error = %v", err)
+			}
+			if len(ranges) != 1 || ranges[0] != "bytes=0-0" {
+				t.Errorf("GET ranges = %q, want a single bytes=0-0", ranges)
+			}
+		})
+	}
+
+	// A source that refuses GET too is still reported
+	forbidden := mockhttp.NewError(t, http.StatusForbidden)
+	var statusErr *SourceStatusError
+	err := New(t.TempDir()).CheckSource(context.Background(), config.Dependency{Name: "tool", Source: forbidden.URLFor("tool")})
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Manager.CheckSource() error = %v, want a 403 SourceStatusError", err)
+	}
+	if forbidden.Requests() != 2 {
+		t.Errorf("requests = %d, want HEAD and then GET", forbidden.Requests())
+	}
+}
+
+func TestManager_InstallResumesDownload(t *testing.T) {
+	payload := []byte("#!/bin/sh\n" + strings.Repeat("# padding\n", 1000))
+	sum := sha256.Sum256(payload)
+	dep := config.Dependency{Name: "tool", Checksum: hex.EncodeToString(sum[:])}
+
+	t.Run("retries right away", func(t *testing.T) {
+		server := mockhttp.NewRanged(t, payload, `"v1"`)
+		server.Interrupt(1, 1000)
+		dep := dep
+		dep.Source = server.URLFor("tool")
+
+		mgr := New(t.TempDir())
+		if err := mgr.Install(dep, false); err != nil {
+			t.Fatalf("Manager.Install() error = %v", err)
+		}
+		if server.Requests() != 2 {
+			t.Errorf("requests = %d, want 2", server.Requests())
+		}
+		if got := server.LastHeader().Get("Range"); got != "bytes=1000-" {
+			t.Errorf("Range of the retry = %q, want bytes=1000-", got)
+		}
+	})
+
+	t.Run("partial download already complete", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			partial      []byte
+			wantRequests int
+		}{
+			// The server answers 416 with the source's size; a matching
+			// partial is used as it is, any other is downloaded again
+			{name: "whole source", partial: payload, wantRequests: 1},
+			{name: "longer than the source", partial: append(slices.Clone(payload), "extra"...), wantRequests: 2},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := mockhttp.NewRanged(t, payload, `"v1"`)
+				dep := dep
+				dep.Source = server.URLFor("tool")
+
+				mgr := New(t.TempDir())
+				partial := mgr.cachePath(dep) + partialSuffix
+				if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(partial, tt.partial, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(partial+validatorSuffix, []byte(`"v1"`+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := mgr.Install(dep, false); err != nil {
+					t.Fatalf("Manager.Install() error = %v", err)
+				}
+				if server.Requests() != tt.wantRequests {
+					t.Errorf("requests = %d, want %d", server.Requests(), tt.wantRequests)
+				}
+			})
+		}
+	})
+
+	t.Run("resumes on the next install", func(t *testing.T) {
+		server := mockhttp.NewRanged(t, payload, `"v1"`)
+		server.Interrupt(downloadAttempts, 1000)
+		dep := dep
+		dep.Source = server.URLFor("tool")
+
+		mgr := New(t.TempDir())
+		if err := mgr.Install(dep, false); err == nil {
+			t.Fatal("Manager.Install() succeeded though every attempt was interrupted")
+		}
+		if _, err := os.Stat(mgr.cachePath(dep) + partialSuffix); err != nil {
+			t.Fatalf("partial download not kept: %v", err)
+		}
+
+		if err := mgr.Install(dep, false); err != nil {
+			t.Fatalf("second Manager.Install() error = %v", err)
+		}
+		want := fmt.Sprintf("bytes=%d-", downloadAttempts*1000)
+		if got := server.LastHeader().Get("Range"); got != want {
+			t.Errorf("Range of the resumed download = %q, want %s", got, want)
+		}
+		if _, err := os.Stat(mgr.cachePath(dep) + partialSuffix); !os.IsNotExist(err) {
+			t.Error("partial download left behind after completing")
+		}
+	})
+
+	t.Run("downloads afresh when the source changed", func(t *testing.T) {
+		server := mockhttp.NewRanged(t, payload, `"v2"`)
+		dep := dep
+		dep.Source = server.URLFor("tool")
+
+		mgr := New(t.TempDir())
+		partial := mgr.cachePath(dep) + partialSuffix
+		if err := os.MkdirAll(mgr.CacheDir(), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(partial, []byte("stale bytes"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(partial+validatorSuffix, []byte(`"v1"`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := mgr.Install(dep, false); err != nil {
+			t.Fatalf("Manager.Install() error = %v", err)
+		}
+		if got := server.LastHeader().Get("If-Range"); got != `"v1"` {
+			t.Errorf("If-Range = %q, want the saved validator", got)
+		}
+	})
+}
+
+func TestClaimPartial(t *testing.T) {
+	partial := filepath.Join(t.TempDir(), "tool"+partialSuffix)
+	if err := os.WriteFile(partial, []byte("first half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partial+validatorSuffix, []byte(`"v1"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two installs of the same dependency: only the first resumes the
+	// partial download, the other starts over in a file of its own
+	first, validator, err := claimPartial(partial)
+	if err != nil {
+		t.Fatalf("claimPartial() error = %v", err)
+	}
+	defer first.Close()
+	second, secondValidator, err := claimPartial(partial)
+	if err != nil {
+		t.Fatalf("second claimPartial() error = %v", err)
+	}
+	defer second.Close()
+
+	if data, _ := io.ReadAll(first); string(data) != "first half" || validator != `"v1"` {
+		t.Errorf("first claim = %q under %q, want the partial download under \"v1\"", data, validator)
+	}
+	if info, _ := second.Stat(); info.Size() != 0 || secondValidator != "" {
+		t.Errorf("second claim = %d bytes under %q, want an empty file", info.Size(), secondValidator)
+	}
+	if first.Name() == second.Name() || first.Name() == partial || second.Name() == partial {
+		t.Errorf("claims share files: %s, %s", first.Name(), second.Name())
+	}
+}
+
+// cancellingBody cancels a context once the first bytes have been read and
+// fails later reads, as the body of a cancelled request does
+type cancellingBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b cancellingBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.cancel()
+	return n, err
+}
+
+// cancellingTransport interrupts a download part way, as Ctrl-C would
+type cancellingTransport struct {
+	cancel context.CancelFunc
+}
+
+func (c cancellingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		resp.Body = cancellingBody{ReadCloser: resp.Body, ctx: req.Context(), cancel: c.cancel}
+	}
+	return resp, err
+}
+
+func TestManager_InstallContextCancelled(t *testing.T) {
+	payload := []byte("#!/bin/sh\n" + strings.Repeat("# padding\n", 100000))
+	server := mockhttp.NewRanged(t, payload, `"v1"`)
+	dep := config.Dependency{Name: "tool", Source: server.URLFor("tool")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := New(t.TempDir())
+	mgr.Client = &http.Client{Transport: cancellingTransport{cancel: cancel}}
+	if err := mgr.InstallContext(ctx, dep, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Manager.InstallContext() error = %v, want context.Canceled", err)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("requests = %d, want 1: a cancelled download isn't retried", server.Requests())
+	}
+	if mgr.IsInstalled(dep) {
+		t.Error("cancelled install left the dependency installed")
+	}
+	if _, err := os.Stat(mgr.cachePath(dep) + partialSuffix); err != nil {
+		t.Errorf("partial download not kept for resuming: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package deps
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"dev-manager/pkg/config"
+)
+
+// Info describes the on-disk state of a dependency
+type Info struct {
+	Installed bool `json:"installed"`
+	// Path is where the dependency is, or would be, installed
+	Path string `json:"installPath"`
+	// Metadata is the install record, nil when there is none
+	Metadata *Metadata `json:"metadata,omitempty"`
+	// Size is the total size in bytes of the installed files
+	Size int64 `json:"size"`
+	// Links are the symlinks in BinDir that point at the dependency's executables
+	Links []string `json:"links,omitempty"`
+}
+
+// Info gathers what is installed for a dependency
+func (m *Manager) Info(dep config.Dependency) (*Info, error) {
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Path: depPath}
+	if _, err := os.Stat(depPath); err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+		return nil, err
+	}
+	info.Installed = true
+
+	if meta, err := m.ReadMetadata(dep); err == nil {
+		info.Metadata = meta
+	}
+
+	if info.Size, err = dirSize(depPath); err != nil {
+		return nil, err
+	}
+
+	if info.Links, err = m.links(depPath); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// links returns the symlinks in BinDir pointing at executables under depPath
+func (m *Manager) links(depPath string) ([]string, error) {
+	absPath, err := filepath.Abs(depPath)
+	if err != nil {
+		return nil, err
+	}
+	executables, err := findExecutables(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, exe := range executables {
+		link := filepath.Join(m.BinDir(), filepath.Base(exe))
+		if target, err := os.Readlink(link); err == nil && target == exe {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+// dirSize sums the sizes of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,53 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/pkg/config"
+)
+
+func TestManager_Info(t *testing.T) {
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: "https://example.com/tool"}
+
+	info, err := mgr.Info(dep)
+	if err != nil {
+		t.Fatalf("Manager.Info() error = %v", err)
+	}
+	if info.Installed {
+		t.Fatalf("Manager.Info() reports a missing dependency as installed")
+	}
+
+	depPath := filepath.Join(mgr.InstallDir, dep.Name)
+	if err := os.MkdirAll(filepath.Join(depPath, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(depPath, "bin", "tool"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMetadata(depPath, dep); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Link(dep, mgr.BinDir(), false); err != nil {
+		t.Fatalf("Manager.Link() error = %v", err)
+	}
+
+	info, err = mgr.Info(dep)
+	if err != nil {
+		t.Fatalf("Manager.Info() error = %v", err)
+	}
+	if !info.Installed || info.Path != depPath {
+		t.Errorf("Manager.Info() = installed %v at %s, want installed at %s", info.Installed, info.Path, depPath)
+	}
+	if info.Metadata == nil || info.Metadata.Version != dep.Version {
+		t.Errorf("Manager.Info() metadata = %+v, want version %s", info.Metadata, dep.Version)
+	}
+	if info.Size < int64(len("#!/bin/sh\n")) {
+		t.Errorf("Manager.Info() size = %d, want at least the binary's size", info.Size)
+	}
+	if want := filepath.Join(mgr.BinDir(), "tool"); len(info.Links) != 1 || info.Links[0] != want {
+		t.Errorf("Manager.Info() links = %v, want [%s]", info.Links, want)
+	}
+}
@@ -0,0 +1,266 @@
+package deps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dev-manager/pkg/config"
+)
+
+// BinDirName is the directory under InstallDir that binaries are linked into
+const BinDirName = "bin"
+
+// BinDir returns the default directory installed binaries are linked into
+func (m *Manager) BinDir() string {
+	return filepath.Join(m.InstallDir, BinDirName)
+}
+
+// LinkConflictError reports a link in the bin directory that already points
+// into the install directory of another dependency
+type LinkConflictError struct {
+	Link  string
+	Name  string
+	Owner string
+}
+
+func (e *LinkConflictError) Error() string {
+	return fmt.Sprintf("%s already links to %s, not %s; rerun with --force to replace it", e.Link, e.Owner, e.Name)
+}
+
+// Link symlinks the executables of an installed dependency into binDir and
+// returns the paths of the created links. Links to another dependency's
+// executables are only replaced when force is set; otherwise nothing is linked
+// and the error wraps a LinkConflictError for each of them.
+func (m *Manager) Link(dep config.Dependency, binDir string, force bool) ([]string, error) {
+	depPath, err := filepath.Abs(filepath.Join(m.InstallDir, dep.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve install path: %w", err)
+	}
+
+	if _, err := os.Stat(depPath); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", dep.Name, err)
+	}
+
+	executables, err := findExecutables(depPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find executables for %s: %w", dep.Name, err)
+	}
+	if len(executables) == 0 {
+		return nil, fmt.Errorf("no executables found in %s", depPath)
+	}
+
+	if !force {
+		var conflicts []error
+		for _, exe := range executables {
+			link := filepath.Join(binDir, filepath.Base(exe))
+			if owner := m.linkOwner(link); owner != "" && owner != dep.Name {
+				conflicts = append(conflicts, &LinkConflictError{Link: link, Name: dep.Name, Owner: owner})
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, errors.Join(conflicts...)
+		}
+	}
+
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	var links []string
+	for _, exe := range executables {
+		link := filepath.Join(binDir, filepath.Base(exe))
+
+		// Replace stale links, but never clobber a real file
+		if info, err := os.Lstat(link); err == nil {
+			if info.Mode()&os.ModeSymlink == 0 {
+				return links, fmt.Errorf("%s already exists and is not a symlink", link)
+			}
+			if err := os.Remove(link); err != nil {
+				return links, fmt.Errorf("failed to replace %s: %w", link, err)
+			}
+		}
+
+		if err := os.Symlink(exe, link); err != nil {
+			return links, fmt.Errorf("failed to link %s: %w", exe, err)
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+// linkOwner returns the name of the installed dependency the symlink at link
+// points into, or "" when it is not such a link. Links whose target is gone
+// belong to no one, so that they are replaced like any other stale link.
+func (m *Manager) linkOwner(link string) string {
+	target, err := linkTarget(link)
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(target); err != nil {
+		return ""
+	}
+	installDir, err := filepath.Abs(m.InstallDir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(installDir, target)
+	if err != nil || !withinDir(installDir, target) {
+		return ""
+	}
+	owner, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	if owner == "." || slices.Contains(reservedNames, owner) {
+		return ""
+	}
+	return owner
+}
+
+// linkTarget returns the absolute path the symlink at link points to
+func linkTarget(link string) (string, error) {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return filepath.Abs(target)
+}
+
+// findExecutables looks for executables in a top-level bin directory or one
+// nested a level down (e.g. go/bin), falling back to the dependency's root
+// for plain binary downloads
+func findExecutables(depPath string) ([]string, error) {
+	binDirs := []string{filepath.Join(depPath, "bin")}
+	nested, err := filepath.Glob(filepath.Join(depPath, "*", "bin"))
+	if err != nil {
+		return nil, err
+	}
+	binDirs = append(binDirs, nested...)
+
+	var executables []string
+	for _, dir := range binDirs {
+		found, err := executablesIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		executables = append(executables, found...)
+	}
+	if len(executables) > 0 {
+		return executables, nil
+	}
+
+	return executablesIn(depPath)
+}
+
+// executablesIn returns the executable files directly inside dir
+func executablesIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var executables []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// Stat follows symlinks, so links shipped in bin dirs (e.g. npm) count too
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info.Mode().Perm()&0111 != 0 {
+			executables = append(executables, path)
+		}
+	}
+	return executables, nil
+}
+
+// Unlink removes the symlinks in binDir that point into an installed
+// dependency and returns their paths. Links to anything else, including other
+// dependencies, are left alone.
+func (m *Manager) Unlink(dep config.Dependency, binDir string) ([]string, error) {
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return nil, err
+	}
+	if depPath, err = filepath.Abs(depPath); err != nil {
+		return nil, fmt.Errorf("failed to resolve install path: %w", err)
+	}
+
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read bin directory: %w", err)
+	}
+
+	var removed []string
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		link := filepath.Join(binDir, entry.Name())
+		target, err := linkTarget(link)
+		if err != nil || target == depPath || !withinDir(depPath, target) {
+			continue
+		}
+
+		if err := os.Remove(link); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", link, err)
+		}
+		removed = append(removed, link)
+	}
+	return removed, nil
+}
+
+// Rename moves an installed dependency to the install directory of newName,
+// updating its install record and re-creating any links to its binaries in
+// BinDir under the new location. It returns the re-created links. Renaming a
+// dependency that isn't installed does nothing.
+func (m *Manager) Rename(dep config.Dependency, newName string) ([]string, error) {
+	oldPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return nil, err
+	}
+	newPath, err := m.installPath(newName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.IsInstalled(dep) {
+		return nil, nil
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return nil, fmt.Errorf("cannot rename %s: %s already exists", dep.Name, newPath)
+	}
+
+	unlinked, err := m.Unlink(dep, m.BinDir())
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return nil, fmt.Errorf("failed to rename %s: %w", dep.Name, err)
+	}
+
+	renamed := dep
+	renamed.Name = newName
+	if meta, err := m.ReadMetadata(renamed); err == nil {
+		meta.Name = newName
+		if err := saveMetadata(newPath, *meta); err != nil {
+			return nil, fmt.Errorf("failed to update install record: %w", err)
+		}
+	}
+
+	if len(unlinked) == 0 {
+		return nil, nil
+	}
+	return m.Link(renamed, m.BinDir(), false)
+}
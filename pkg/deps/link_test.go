@@ -0,0 +1,222 @@
+package deps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestManager_Link(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]os.FileMode
+		wantLinks []string
+		wantErr   bool
+	}{
+		{
+			name: "nested bin directory",
+			files: map[string]os.FileMode{
+				"go/bin/go":     0755,
+				"go/bin/gofmt":  0755,
+				"go/README.md":  0644,
+				"go/src/tool.s": 0644,
+			},
+			wantLinks: []string{"go", "gofmt"},
+		},
+		{
+			name: "top-level bin directory",
+			files: map[string]os.FileMode{
+				"bin/tool":  0755,
+				"LICENSE":   0644,
+				"bin/notes": 0644,
+			},
+			wantLinks: []string{"tool"},
+		},
+		{
+			name: "plain binary",
+			files: map[string]os.FileMode{
+				"tool": 0755,
+			},
+			wantLinks: []string{"tool"},
+		},
+		{
+			name: "no executables",
+			files: map[string]os.FileMode{
+				"README.md": 0644,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := New(t.TempDir())
+			dep := config.Dependency{Name: "tool"}
+
+			depPath := filepath.Join(mgr.InstallDir, dep.Name)
+			for name, mode := range tt.files {
+				path := filepath.Join(depPath, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create directory: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(name), mode); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			links, err := mgr.Link(dep, mgr.BinDir(), false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Manager.Link() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(links) != len(tt.wantLinks) {
+				t.Fatalf("Manager.Link() created %v, want %v", links, tt.wantLinks)
+			}
+
+			for _, name := range tt.wantLinks {
+				link := filepath.Join(mgr.BinDir(), name)
+				target, err := os.Readlink(link)
+				if err != nil {
+					t.Errorf("expected symlink %s: %v", link, err)
+					continue
+				}
+				if _, err := os.Stat(target); err != nil {
+					t.Errorf("symlink %s points to missing target %s", link, target)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_LinkConflict(t *testing.T) {
+	mgr := New(t.TempDir())
+	older := config.Dependency{Name: "go1.21"}
+	newer := config.Dependency{Name: "go1.22"}
+	for _, dep := range []config.Dependency{older, newer} {
+		exe := filepath.Join(mgr.InstallDir, dep.Name, "go", "bin", "go")
+		if err := os.MkdirAll(filepath.Dir(exe), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(mgr.BinDir(), "go")
+	pointsInto := func(dep config.Dependency) bool {
+		target, err := os.Readlink(link)
+		return err == nil && withinDir(filepath.Join(mgr.InstallDir, dep.Name), target)
+	}
+
+	if _, err := mgr.Link(older, mgr.BinDir(), false); err != nil {
+		t.Fatalf("Manager.Link(%s) error = %v", older.Name, err)
+	}
+
+	// Another dependency's link is reported, and left alone, unless forced
+	links, err := mgr.Link(newer, mgr.BinDir(), false)
+	var conflict *LinkConflictError
+	if !errors.As(err, &conflict) || conflict.Link != link || conflict.Owner != older.Name {
+		t.Fatalf("Manager.Link(%s) error = %v, want a conflict with %s over %s", newer.Name, err, older.Name, link)
+	}
+	if len(links) != 0 || !pointsInto(older) {
+		t.Errorf("Manager.Link(%s) replaced %s despite the conflict", newer.Name, link)
+	}
+
+	// A dependency's own links are replaced freely
+	if _, err := mgr.Link(older, mgr.BinDir(), false); err != nil {
+		t.Fatalf("Manager.Link(%s) relinking error = %v", older.Name, err)
+	}
+
+	if _, err := mgr.Link(newer, mgr.BinDir(), true); err != nil {
+		t.Fatalf("Manager.Link(%s, force) error = %v", newer.Name, err)
+	}
+	if !pointsInto(newer) {
+		t.Errorf("Manager.Link(%s, force) didn't replace %s", newer.Name, link)
+	}
+
+	// A link whose dependency is gone is stale, not a conflict
+	if err := os.RemoveAll(filepath.Join(mgr.InstallDir, newer.Name)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Link(older, mgr.BinDir(), false); err != nil {
+		t.Fatalf("Manager.Link(%s) over a stale link error = %v", older.Name, err)
+	}
+	if !pointsInto(older) {
+		t.Errorf("Manager.Link(%s) didn't replace the stale link %s", older.Name, link)
+	}
+}
+
+func TestManager_Unlink(t *testing.T) {
+	mgr := New(t.TempDir())
+	tool := config.Dependency{Name: "tool"}
+	other := config.Dependency{Name: "tool2"}
+
+	for _, dep := range []config.Dependency{tool, other} {
+		exe := filepath.Join(mgr.InstallDir, dep.Name, "bin", dep.Name)
+		if err := os.MkdirAll(filepath.Dir(exe), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mgr.Link(dep, mgr.BinDir(), false); err != nil {
+			t.Fatalf("Manager.Link(%s) error = %v", dep.Name, err)
+		}
+	}
+	// tool2's link shares tool's name as a prefix; it and links to files
+	// outside the install directory must survive
+	unrelated := filepath.Join(mgr.BinDir(), "sh")
+	if err := os.Symlink("/bin/sh", unrelated); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := mgr.Unlink(tool, mgr.BinDir())
+	if err != nil {
+		t.Fatalf("Manager.Unlink() error = %v", err)
+	}
+	if want := filepath.Join(mgr.BinDir(), "tool"); len(removed) != 1 || removed[0] != want {
+		t.Errorf("Manager.Unlink() removed %v, want [%s]", removed, want)
+	}
+	for _, keep := range []string{filepath.Join(mgr.BinDir(), "tool2"), unrelated} {
+		if _, err := os.Lstat(keep); err != nil {
+			t.Errorf("Manager.Unlink() removed %s", keep)
+		}
+	}
+}
+
+func TestManager_Rename(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")}
+
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if _, err := mgr.Link(dep, mgr.BinDir(), false); err != nil {
+		t.Fatalf("Manager.Link() error = %v", err)
+	}
+
+	links, err := mgr.Rename(dep, "newtool")
+	if err != nil {
+		t.Fatalf("Manager.Rename() error = %v", err)
+	}
+
+	renamed := dep
+	renamed.Name = "newtool"
+	if mgr.IsInstalled(dep) || !mgr.IsInstalled(renamed) {
+		t.Fatalf("install directory was not moved to %s", renamed.Name)
+	}
+	if v, err := mgr.Verify(renamed); err != nil || v.Status != StatusOK {
+		t.Errorf("Manager.Verify() after rename = %+v, %v, want OK", v, err)
+	}
+
+	link := filepath.Join(mgr.BinDir(), "tool")
+	if len(links) != 1 || links[0] != link {
+		t.Fatalf("Manager.Rename() links = %v, want [%s]", links, link)
+	}
+	if _, err := os.Stat(link); err != nil {
+		t.Errorf("link %s does not resolve after rename: %v", link, err)
+	}
+}
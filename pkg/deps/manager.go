@@ -3,47 +3,101 @@ package deps
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"dev-manager/pkg/config"
 )
 
+// ErrAlreadyInstalled is returned by Install when the dependency is already
+// installed and force is not set
+var ErrAlreadyInstalled = errors.New("already installed")
+
 // Manager handles dependency operations
 type Manager struct {
 	InstallDir string
+	// AllowInstallScripts permits running a dependency's InstallScript.
+	// Scripts are not sandboxed: they run as the current user with full access
+	// to the filesystem and network, so only enable this for trusted sources.
+	AllowInstallScripts bool
+	// NoCache downloads sources afresh instead of reusing, or adding to, the
+	// download cache
+	NoCache bool
+	// DryRun makes Install stop after its pre-install checks, without
+	// downloading or changing anything on disk
+	DryRun bool
+	// Client downloads dependency sources. Replace it to customize proxies,
+	// TLS or transport behavior; nil uses http.DefaultClient.
+	Client *http.Client
+	// MaxDownloadBytes caps the size of a downloaded source, so a wrong URL
+	// can't fill the disk; 0 means no limit
+	MaxDownloadBytes int64
 }
 
-// New creates a new dependency manager
+// New creates a new dependency manager that downloads with NewHTTPClient,
+// capping downloads at DefaultMaxDownloadBytes
 func New(installDir string) *Manager {
 	return &Manager{
-		InstallDir: installDir,
+		InstallDir:       installDir,
+		Client:           NewHTTPClient(),
+		MaxDownloadBytes: DefaultMaxDownloadBytes,
 	}
 }
 
 // Install installs a dependency
 func (m *Manager) Install(dep config.Dependency, force bool) error {
-	// Create installation directory if it doesn't exist
-	if err := os.MkdirAll(m.InstallDir, 0755); err != nil {
-		return fmt.Errorf("failed to create install directory: %w", err)
+	return m.InstallContext(context.Background(), dep, force)
+}
+
+// InstallContext installs a dependency, giving up once ctx is done. The
+// download stops as soon as ctx is cancelled and every later step checks it
+// first; an install cut short that way, or failing part way through, is
+// rolled back so that nothing half-installed is left at the dependency's
+// path, and an install it was replacing is put back.
+func (m *Manager) InstallContext(ctx context.Context, dep config.Dependency, force bool) (err error) {
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return err
 	}
 
 	// Check if already installed
-	depPath := filepath.Join(m.InstallDir, dep.Name)
 	if _, err := os.Stat(depPath); err == nil && !force {
-		return fmt.Errorf("%s is already installed at %s", dep.Name, depPath)
+		return fmt.Errorf("%s is %w at %s", dep.Name, ErrAlreadyInstalled, depPath)
+	}
+
+	if dep.InstallScript != "" && !m.AllowInstallScripts {
+		return fmt.Errorf("%s requires running install script %s; rerun with --allow-install-scripts to permit it", dep.Name, dep.InstallScript)
 	}
 
-	// Download the dependency
-	resp, err := http.Get(dep.Source)
+	if m.DryRun {
+		return nil
+	}
+
+	// Create installation directory if it doesn't exist
+	if err := os.MkdirAll(m.InstallDir, 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+
+	// Download the dependency, or reuse a cached download
+	payload, cleanup, err := m.fetch(ctx, dep)
 	if err != nil {
-		return fmt.Errorf("failed to download %s: %w", dep.Name, err)
+		return err
+	}
+	defer cleanup()
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
 	// Create temporary directory for extraction
 	tmpDir, err := os.MkdirTemp("", "dev-manager-*")
@@ -52,32 +106,38 @@ func (m *Manager) Install(dep config.Dependency, force bool) error {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Handle different file types
-	switch {
-	case strings.HasSuffix(dep.Source, ".tar.gz"):
-		if err := extractTarGz(resp.Body, tmpDir); err != nil {
-			return fmt.Errorf("failed to extract tar.gz: %w", err)
-		}
-	case strings.HasSuffix(dep.Source, ".zip"):
-		// TODO: Implement zip extraction
-		return fmt.Errorf("zip extraction not implemented yet")
-	default:
-		// Assume it's a binary, just copy it
-		out, err := os.Create(filepath.Join(tmpDir, dep.Name))
-		if err != nil {
-			return fmt.Errorf("failed to create output file: %w", err)
-		}
-		defer out.Close()
-
-		if _, err := io.Copy(out, resp.Body); err != nil {
-			return fmt.Errorf("failed to copy file: %w", err)
-		}
+	// Unpack archives, telling them apart by content rather than URL
+	slog.Debug("extracting dependency", "dependency", dep.Name, "source", dep.Source, "dest", tmpDir)
+	if err := extract(payload, dep.Name, dep.Source, tmpDir); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	// Move to final location
-	if err := os.RemoveAll(depPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove existing installation: %w", err)
+	// Move to final location. An existing install is set aside rather than
+	// removed, so that a failure from here on removes whatever was moved into
+	// place and puts the previous install back. It is only deleted once the
+	// new one is complete.
+	previous, err := setAside(depPath, m.InstallDir)
+	if err != nil {
+		return fmt.Errorf("failed to move existing installation aside: %w", err)
 	}
+	defer func() {
+		if err != nil {
+			slog.Debug("rolling back install", "dependency", dep.Name, "path", depPath, "error", err)
+			os.RemoveAll(depPath)
+			if previous != "" {
+				if rerr := os.Rename(previous, depPath); rerr != nil {
+					slog.Warn("failed to restore previous install", "dependency", dep.Name, "path", depPath, "error", rerr)
+					return
+				}
+			}
+		}
+		if previous != "" {
+			os.RemoveAll(filepath.Dir(previous))
+		}
+	}()
 
 	if err := os.Rename(tmpDir, depPath); err != nil {
 		return fmt.Errorf("failed to move to final location: %w", err)
@@ -88,12 +148,97 @@ func (m *Manager) Install(dep config.Dependency, force bool) error {
 		return fmt.Errorf("failed to make executable: %w", err)
 	}
 
+	// Run the dependency's own installer
+	if dep.InstallScript != "" {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := runInstallScript(ctx, dep, depPath); err != nil {
+			return err
+		}
+	}
+
+	if err := writeMetadata(depPath, dep); err != nil {
+		return fmt.Errorf("failed to record install: %w", err)
+	}
+
+	slog.Info("installed dependency", "dependency", dep.Name, "version", dep.Version, "path", depPath)
+
 	return nil
 }
 
+// setAside moves the install at depPath into a hidden temporary directory in
+// installDir, returning where it now is, or "" if nothing is installed there.
+// The caller removes the temporary directory, the parent of the returned
+// path, once the install is no longer needed.
+func setAside(depPath, installDir string) (string, error) {
+	if _, err := os.Lstat(depPath); os.IsNotExist(err) {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp(installDir, "."+filepath.Base(depPath)+".previous-")
+	if err != nil {
+		return "", err
+	}
+	previous := filepath.Join(dir, "install")
+	if err := os.Rename(depPath, previous); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return previous, nil
+}
+
+// UnsafePathError is returned when a dependency name would resolve to a path
+// outside the install directory, or to one of the directories dev-manager
+// keeps there for all dependencies
+type UnsafePathError struct {
+	Name string
+	Path string
+	// Reserved is set when the name is that of a shared directory
+	Reserved bool
+}
+
+func (e *UnsafePathError) Error() string {
+	if e.Reserved {
+		return fmt.Sprintf("refusing to touch %s: dependency name %q is reserved for dev-manager's own files", e.Path, e.Name)
+	}
+	return fmt.Sprintf("refusing to touch %s: dependency name %q resolves outside the install directory", e.Path, e.Name)
+}
+
+// reservedNames are the directories in InstallDir shared by all dependencies
+var reservedNames = []string{BinDirName, CacheDirName}
+
+// installPath returns the directory a dependency is installed to, rejecting
+// names that would escape InstallDir or clash with its shared directories
+func (m *Manager) installPath(name string) (string, error) {
+	depPath := filepath.Join(m.InstallDir, name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", &UnsafePathError{Name: name, Path: depPath}
+	}
+	if slices.Contains(reservedNames, name) {
+		return "", &UnsafePathError{Name: name, Path: depPath, Reserved: true}
+	}
+	if rel, err := filepath.Rel(m.InstallDir, depPath); err != nil || rel != name {
+		return "", &UnsafePathError{Name: name, Path: depPath}
+	}
+	return depPath, nil
+}
+
+// IsInstalled reports whether a dependency has an install directory
+func (m *Manager) IsInstalled(dep config.Dependency) bool {
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(depPath)
+	return err == nil
+}
+
 // Remove removes a dependency
 func (m *Manager) Remove(dep config.Dependency) error {
-	depPath := filepath.Join(m.InstallDir, dep.Name)
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return err
+	}
 	if err := os.RemoveAll(depPath); err != nil {
 		return fmt.Errorf("failed to remove %s: %w", dep.Name, err)
 	}
@@ -109,7 +254,55 @@ func extractTarGz(r io.Reader, dest string) error {
 	}
 	defer gzr.Close()
 
-	tr := tar.NewReader(gzr)
+	return extractTar(gzr, dest)
+}
+
+// extractTarXz decompresses through the xz binary, since the standard library
+// has no xz reader
+func extractTarXz(r io.Reader, dest string) error {
+	xzPath, err := exec.LookPath("xz")
+	if err != nil {
+		return fmt.Errorf("xz is required to extract .tar.xz archives but was not found on PATH")
+	}
+
+	cmd := exec.Command(xzPath, "--decompress", "--stdout")
+	cmd.Stdin = r
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start xz: %w", err)
+	}
+
+	extractErr := extractTar(out, dest)
+	if extractErr != nil {
+		// Unblock xz if extraction stopped before reading all of its output
+		io.Copy(io.Discard, out)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("xz failed: %s, %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return extractErr
+}
+
+// extractTar unpacks an uncompressed tar stream into dest
+func extractTar(r io.Reader, dest string) error {
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return err
+	}
+
+	// Directory times are applied last, since extracting their contents bumps them
+	type dirTime struct {
+		path    string
+		modTime time.Time
+	}
+	var dirTimes []dirTime
+
+	tr := tar.NewReader(r)
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -119,13 +312,48 @@ func extractTarGz(r io.Reader, dest string) error {
 			return err
 		}
 
-		target := filepath.Join(dest, header.Name)
+		target, err := extractTarget(realDest, header.Name)
+		if err != nil {
+			return err
+		}
+		slog.Debug("extracting entry", "name", header.Name, "type", string(header.Typeflag))
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(target, 0755); err != nil {
 				return err
 			}
+			dirTimes = append(dirTimes, dirTime{target, header.ModTime})
+		case tar.TypeSymlink:
+			// Links may point anywhere inside the archive but not out of it
+			if !linkWithin(realDest, target, header.Linkname) {
+				return fmt.Errorf("archive entry %s links outside the extraction directory: %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// Hard link names are relative to the archive root
+			source, err := extractTarget(realDest, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
 		case tar.TypeReg:
+			// Opening an existing link would write to wherever it points
+			if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("archive entry %s would overwrite a symlink", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
 			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
 			if err != nil {
 				return err
@@ -135,8 +363,147 @@ func extractTarGz(r io.Reader, dest string) error {
 				return err
 			}
 			f.Close()
+			if err := setModTime(target, header.ModTime); err != nil {
+				return err
+			}
 		}
 	}
+
+	for i := len(dirTimes) - 1; i >= 0; i-- {
+		if err := setModTime(dirTimes[i].path, dirTimes[i].modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setModTime applies an archive entry's modification time to an extracted
+// file, leaving the extraction time in place when the archive has none
+func setModTime(path string, modTime time.Time) error {
+	if modTime.IsZero() || modTime.Unix() == 0 {
+		return nil
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// extractTarget resolves an archive entry name to its path under dest,
+// rejecting names that would land outside dest either directly ("../x") or by
+// passing through a symlink extracted earlier from the same archive.
+// dest must not contain symlinks itself.
+func extractTarget(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if !withinDir(dest, target) {
+		return "", fmt.Errorf("archive entry %s escapes the extraction directory", name)
+	}
+
+	parent, err := resolveExisting(filepath.Dir(target))
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(dest, parent) {
+		return "", fmt.Errorf("archive entry %s escapes the extraction directory through a symlink", name)
+	}
+	return filepath.Join(parent, filepath.Base(target)), nil
+}
+
+// resolveExisting evaluates symlinks in the longest existing prefix of path,
+// leaving the components that don't exist yet as they are
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	existing := path
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{resolved}, missing...)...), nil
+}
+
+// linkWithin reports whether a symlink at target pointing to linkname stays
+// inside dir. The link is resolved through the links already extracted, so
+// that e.g. "z -> y/.." with "y -> ." is seen to point to dir's parent, where
+// joining the names would say dir.
+func linkWithin(dir, target, linkname string) bool {
+	if filepath.IsAbs(linkname) {
+		return false
+	}
+	resolved, err := resolveExisting(filepath.Dir(target))
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			// resolved holds no links, so its parent is the real one
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		if _, err := os.Lstat(next); err != nil {
+			resolved = next
+			continue
+		}
+		if resolved, err = filepath.EvalSymlinks(next); err != nil {
+			return false
+		}
+	}
+	return withinDir(dir, resolved)
+}
+
+// withinDir reports whether path is dir or lies beneath it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// runInstallScript executes a dependency's install script from its install
+// directory, exposing the install location and platform through the environment
+func runInstallScript(ctx context.Context, dep config.Dependency, depPath string) error {
+	absPath, err := filepath.Abs(depPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install path: %w", err)
+	}
+
+	script := filepath.Join(absPath, dep.InstallScript)
+	if !withinDir(absPath, script) {
+		return fmt.Errorf("install script %s is outside the install directory", dep.InstallScript)
+	}
+
+	if err := os.Chmod(script, 0755); err != nil {
+		return fmt.Errorf("install script %s not found: %w", dep.InstallScript, err)
+	}
+
+	cmd := exec.CommandContext(ctx, script)
+	// Don't wait on children of a cancelled script that hold its output open
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Dir = absPath
+	cmd.Env = append(os.Environ(),
+		"DEV_MANAGER_INSTALL_DIR="+absPath,
+		"DEV_MANAGER_DEP_NAME="+dep.Name,
+		"DEV_MANAGER_DEP_VERSION="+dep.Version,
+		"DEV_MANAGER_OS="+runtime.GOOS,
+		"DEV_MANAGER_ARCH="+runtime.GOARCH,
+	)
+	slog.Info("running install script", "dependency", dep.Name, "script", dep.InstallScript)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("install script %s failed: %s, %w", dep.InstallScript, string(output), err)
+	}
+	slog.Debug("install script output", "dependency", dep.Name, "output", string(output))
+
 	return nil
 }
 
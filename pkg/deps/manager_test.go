@@ -0,0 +1,469 @@
+package deps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestManager_InstallScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Install script tests are not supported on Windows")
+	}
+
+	tests := []struct {
+		name          string
+		script        string
+		allow         bool
+		wantErr       bool
+		wantInstalled bool
+	}{
+		{
+			name:          "script succeeds",
+			script:        "#!/bin/sh\necho \"$DEV_MANAGER_DEP_NAME\" > \"$DEV_MANAGER_INSTALL_DIR/installed\"\n",
+			allow:         true,
+			wantInstalled: true,
+		},
+		{
+			name:    "script fails",
+			script:  "#!/bin/sh\necho boom >&2\nexit 1\n",
+			allow:   true,
+			wantErr: true,
+		},
+		{
+			name:    "scripts not allowed",
+			script:  "#!/bin/sh\nexit 0\n",
+			allow:   false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockhttp.New(t, mockhttp.TarGz(t,
+				mockhttp.Entry{Name: "install.sh", Body: tt.script, Mode: 0755},
+				mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755},
+			))
+
+			mgr := New(t.TempDir())
+			mgr.AllowInstallScripts = tt.allow
+			dep := config.Dependency{
+				Name:          "tool",
+				Version:       "1.0.0",
+				Source:        server.URLFor("tool.tar.gz"),
+				InstallScript: "install.sh",
+			}
+
+			err := mgr.Install(dep, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Manager.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			depPath := filepath.Join(mgr.InstallDir, dep.Name)
+			if _, err := os.Stat(depPath); (err == nil) != tt.wantInstalled {
+				t.Errorf("install directory exists = %v, want %v", err == nil, tt.wantInstalled)
+			}
+			if tt.wantInstalled {
+				data, err := os.ReadFile(filepath.Join(depPath, "installed"))
+				if err != nil || string(data) != "tool\n" {
+					t.Errorf("install script output = %q, %v; want %q", data, err, "tool\n")
+				}
+			}
+		})
+	}
+}
+
+func TestManager_InstallScriptCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Install script tests are not supported on Windows")
+	}
+
+	server := mockhttp.New(t, mockhttp.TarGz(t,
+		mockhttp.Entry{Name: "install.sh", Body: "#!/bin/sh\nexec sleep 10\n", Mode: 0755},
+	))
+	mgr := New(t.TempDir())
+	mgr.AllowInstallScripts = true
+	dep := config.Dependency{Name: "tool", Source: server.URLFor("tool.tar.gz"), InstallScript: "install.sh"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := mgr.InstallContext(ctx, dep, false); err == nil {
+		t.Fatal("Manager.InstallContext() succeeded though the install was cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled install took %s, want the script stopped", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.InstallDir, dep.Name)); !os.IsNotExist(err) {
+		t.Errorf("cancelled install left %s behind", dep.Name)
+	}
+}
+
+func TestManager_InstallRestoresPrevious(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Install script tests are not supported on Windows")
+	}
+
+	mgr := New(t.TempDir())
+	mgr.AllowInstallScripts = true
+	working := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "v1", Mode: 0755}))
+	if err := mgr.Install(config.Dependency{Name: "tool", Version: "1.0.0", Source: working.URLFor("tool.tar.gz")}, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+
+	broken := mockhttp.New(t, mockhttp.TarGz(t,
+		mockhttp.Entry{Name: "install.sh", Body: "#!/bin/sh\nexit 1\n", Mode: 0755},
+		mockhttp.Entry{Name: "bin/tool", Body: "v2", Mode: 0755},
+	))
+	dep := config.Dependency{Name: "tool", Version: "2.0.0", Source: broken.URLFor("tool.tar.gz"), InstallScript: "install.sh"}
+	if err := mgr.Install(dep, true); err == nil {
+		t.Fatal("Manager.Install() succeeded though the install script failed")
+	}
+
+	// The working install is back in place and nothing is left set aside
+	data, err := os.ReadFile(filepath.Join(mgr.InstallDir, "tool", "bin", "tool"))
+	if err != nil || string(data) != "v1" {
+		t.Errorf("installed tool = %q, %v; want the previous install %q", data, err, "v1")
+	}
+	entries, err := os.ReadDir(mgr.InstallDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".previous-") {
+			t.Errorf("previous install left set aside at %s", entry.Name())
+		}
+	}
+}
+
+func TestManager_Install(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   func(t *testing.T) []byte
+		file      string
+		installed bool // install once before the test
+		force     bool
+		wantFile  string // file expected inside the install directory
+		wantErr   bool
+	}{
+		{
+			name: "tar.gz archive",
+			payload: func(t *testing.T) []byte {
+				return mockhttp.TarGz(t,
+					mockhttp.Entry{Name: "tool/"},
+					mockhttp.Entry{Name: "tool/bin/tool", Body: "#!/bin/sh\n", Mode: 0755},
+				)
+			},
+			file:     "tool.tar.gz",
+			wantFile: "tool/bin/tool",
+		},
+		{
+			name: "tar.xz archive",
+			payload: func(t *testing.T) []byte {
+				return compressWith(t, "xz", mockhttp.Tar(t, mockhttp.Entry{Name: "tool/bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+			},
+			file:     "tool.tar.xz",
+			wantFile: "tool/bin/tool",
+		},
+		{
+			name: "tar.bz2 archive",
+			payload: func(t *testing.T) []byte {
+				return compressWith(t, "bzip2", mockhttp.Tar(t, mockhttp.Entry{Name: "tool/bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+			},
+			file:     "tool.tar.bz2",
+			wantFile: "tool/bin/tool",
+		},
+		{
+			name: "tar.gz archive without a suffix",
+			payload: func(t *testing.T) []byte {
+				return mockhttp.TarGz(t, mockhttp.Entry{Name: "tool/bin/tool", Body: "#!/bin/sh\n", Mode: 0755})
+			},
+			file:     "download?id=123",
+			wantFile: "tool/bin/tool",
+		},
+		{
+			name: "zip archive",
+			payload: func(t *testing.T) []byte {
+				return mockhttp.Zip(t,
+					mockhttp.Entry{Name: "tool/"},
+					mockhttp.Entry{Name: "tool/bin/tool", Body: "#!/bin/sh\n", Mode: 0755},
+				)
+			},
+			file:     "tool.zip",
+			wantFile: "tool/bin/tool",
+		},
+		{
+			name:    "archive name with other content",
+			payload: func(t *testing.T) []byte { return []byte("<html>Not Found</html>") },
+			file:    "tool.tar.gz",
+			wantErr: true,
+		},
+		{
+			name:     "plain binary",
+			payload:  func(t *testing.T) []byte { return []byte("#!/bin/sh\n") },
+			file:     "tool",
+			wantFile: "tool",
+		},
+		{
+			name:      "already installed",
+			payload:   func(t *testing.T) []byte { return []byte("#!/bin/sh\n") },
+			file:      "tool",
+			installed: true,
+			wantErr:   true,
+		},
+		{
+			name:      "already installed with force",
+			payload:   func(t *testing.T) []byte { return []byte("#!/bin/sh\n") },
+			file:      "tool",
+			installed: true,
+			force:     true,
+			wantFile:  "tool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockhttp.New(t, tt.payload(t))
+			mgr := New(t.TempDir())
+			dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor(tt.file)}
+
+			if tt.installed {
+				if err := mgr.Install(dep, false); err != nil {
+					t.Fatalf("initial Manager.Install() error = %v", err)
+				}
+			}
+
+			err := mgr.Install(dep, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Manager.Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantFile != "" {
+				info, err := os.Stat(filepath.Join(mgr.InstallDir, dep.Name, tt.wantFile))
+				if err != nil {
+					t.Fatalf("installed file missing: %v", err)
+				}
+				if info.Mode()&0111 == 0 {
+					t.Errorf("installed file %s is not executable", tt.wantFile)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_Remove(t *testing.T) {
+	tests := []struct {
+		name       string
+		depName    string
+		wantUnsafe bool
+	}{
+		{name: "installed dependency", depName: "tool"},
+		{name: "parent directory", depName: "..", wantUnsafe: true},
+		{name: "nested path", depName: "../workspace", wantUnsafe: true},
+		{name: "path separator", depName: "tool/bin", wantUnsafe: true},
+		{name: "empty name", depName: "", wantUnsafe: true},
+		{name: "bin directory", depName: BinDirName, wantUnsafe: true},
+		{name: "cache directory", depName: CacheDirName, wantUnsafe: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			mgr := New(filepath.Join(root, "deps"))
+			for _, dir := range []string{filepath.Join("tool", "bin"), BinDirName, CacheDirName} {
+				if err := os.MkdirAll(filepath.Join(mgr.InstallDir, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := mgr.Remove(config.Dependency{Name: tt.depName})
+
+			var unsafe *UnsafePathError
+			if got := errors.As(err, &unsafe); got != tt.wantUnsafe {
+				t.Fatalf("Manager.Remove() error = %v, want UnsafePathError %v", err, tt.wantUnsafe)
+			}
+			if tt.wantUnsafe {
+				if _, err := os.Stat(mgr.InstallDir); err != nil {
+					t.Errorf("install directory was removed: %v", err)
+				}
+				for _, dir := range []string{BinDirName, CacheDirName} {
+					if _, err := os.Stat(filepath.Join(mgr.InstallDir, dir)); err != nil {
+						t.Errorf("shared %s directory was removed: %v", dir, err)
+					}
+				}
+				return
+			}
+			if _, err := os.Stat(filepath.Join(mgr.InstallDir, tt.depName)); !os.IsNotExist(err) {
+				t.Errorf("dependency directory still exists after Remove()")
+			}
+		})
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []mockhttp.Entry
+		want    []string // files expected under dest afterwards
+		wantErr bool
+	}{
+		{
+			name:    "regular files",
+			entries: []mockhttp.Entry{{Name: "bin/"}, {Name: "bin/tool", Body: "x"}},
+			want:    []string{"bin/tool"},
+		},
+		{
+			name:    "parent traversal",
+			entries: []mockhttp.Entry{{Name: "../evil", Body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "nested traversal",
+			entries: []mockhttp.Entry{{Name: "bin/../../evil", Body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink inside archive",
+			entries: []mockhttp.Entry{{Name: "lib/tool", Body: "x"}, {Name: "bin/tool", Symlink: "../lib/tool"}},
+			want:    []string{"lib/tool", "bin/tool"},
+		},
+		{
+			name:    "absolute symlink",
+			entries: []mockhttp.Entry{{Name: "passwd", Symlink: "/etc/passwd"}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink escaping dest",
+			entries: []mockhttp.Entry{{Name: "bin/up", Symlink: "../../.."}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink escaping dest through an earlier link",
+			entries: []mockhttp.Entry{{Name: "y", Symlink: "."}, {Name: "z", Symlink: "y/.."}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink through an earlier link",
+			entries: []mockhttp.Entry{{Name: "lib/tool", Body: "x"}, {Name: "y", Symlink: "lib"}, {Name: "bin/tool", Symlink: "../y/tool"}},
+			want:    []string{"lib/tool", "bin/tool"},
+		},
+		{
+			name:    "file written over symlink",
+			entries: []mockhttp.Entry{{Name: "real", Body: "x"}, {Name: "link", Symlink: "real"}, {Name: "link", Body: "y"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractTarGz(bytes.NewReader(mockhttp.TarGz(t, tt.entries...)), dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, name := range tt.want {
+				if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+					t.Errorf("expected %s to be extracted: %v", name, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+				t.Errorf("archive wrote outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestManager_InstallPreservesSymlinksAndTimes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlink tests are not supported on Windows")
+	}
+
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := mockhttp.New(t, mockhttp.TarGz(t,
+		mockhttp.Entry{Name: "node/lib/", ModTime: modTime},
+		mockhttp.Entry{Name: "node/lib/npm-cli.js", Body: "console.log('npm')\n", Mode: 0755, ModTime: modTime},
+		mockhttp.Entry{Name: "node/bin/npm", Symlink: "../lib/npm-cli.js"},
+	))
+
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "node", Version: "20.0.0", Source: server.URLFor("node.tar.gz")}
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+
+	root := filepath.Join(mgr.InstallDir, dep.Name, "node")
+	link := filepath.Join(root, "bin", "npm")
+	if target, err := os.Readlink(link); err != nil || target != "../lib/npm-cli.js" {
+		t.Fatalf("Readlink(%s) = %q, %v; want ../lib/npm-cli.js", link, target, err)
+	}
+	content, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("symlink does not resolve after install: %v", err)
+	}
+	if string(content) != "console.log('npm')\n" {
+		t.Errorf("symlink resolved to %q", content)
+	}
+
+	for _, path := range []string{filepath.Join(root, "lib"), filepath.Join(root, "lib", "npm-cli.js")} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("%s mod time = %v, want %v", path, info.ModTime(), modTime)
+		}
+	}
+}
+
+// compressWith compresses data with an external tool, skipping the test when
+// the tool isn't installed
+func compressWith(t *testing.T, tool string, data []byte) []byte {
+	t.Helper()
+
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skipf("%s not available", tool)
+	}
+	cmd := exec.Command(tool, "--compress", "--stdout")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s failed: %v", tool, err)
+	}
+	return out
+}
+
+func TestManager_InstallDryRun(t *testing.T) {
+	server := mockhttp.New(t, []byte("#!/bin/sh\n"))
+	mgr := New(filepath.Join(t.TempDir(), "deps"))
+	mgr.DryRun = true
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool")}
+
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if got := server.Requests(); got != 0 {
+		t.Errorf("dry run made %d download requests", got)
+	}
+	if _, err := os.Stat(mgr.InstallDir); !os.IsNotExist(err) {
+		t.Errorf("dry run created the install directory")
+	}
+
+	dep.InstallScript = "install.sh"
+	if err := mgr.Install(dep, false); err == nil {
+		t.Error("dry run with a disallowed install script succeeded, want error")
+	}
+}
@@ -0,0 +1,110 @@
+package deps
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// knownSources builds the download URL of a well-known tool for a version,
+// GOOS and GOARCH
+var knownSources = map[string]func(version, goos, goarch string) (string, error){
+	"go":     goSource,
+	"node":   nodeSource,
+	"python": pythonSource,
+}
+
+// KnownTools returns the names of the tools InferSource knows, sorted
+func KnownTools() []string {
+	names := make([]string, 0, len(knownSources))
+	for name := range knownSources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// InferSource returns the official download URL of a well-known tool's
+// version for an OS and architecture, in GOOS and GOARCH terms
+func InferSource(name, version, goos, goarch string) (string, error) {
+	build, ok := knownSources[name]
+	if !ok {
+		return "", fmt.Errorf("no download source is known for %q (known tools: %s); pass --source", name, strings.Join(KnownTools(), ", "))
+	}
+	if version == "" {
+		return "", fmt.Errorf("a version is needed to work out the source of %s; pass --version or --source", name)
+	}
+	source, err := build(version, goos, goarch)
+	if err != nil {
+		return "", fmt.Errorf("cannot work out the source of %s %s: %w; pass --source", name, version, err)
+	}
+	return source, nil
+}
+
+// goSource returns a Go release from go.dev
+func goSource(version, goos, goarch string) (string, error) {
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "go"), "v")
+	arch := goarch
+	switch goarch {
+	case "amd64", "arm64", "386":
+	case "arm":
+		arch = "armv6l"
+	default:
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	ext := "tar.gz"
+	switch goos {
+	case "linux", "darwin", "freebsd":
+	case "windows":
+		ext = "zip"
+	default:
+		return "", fmt.Errorf("unsupported OS %s", goos)
+	}
+	return fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.%s", version, goos, arch, ext), nil
+}
+
+// nodeSource returns a Node.js release from nodejs.org
+func nodeSource(version, goos, goarch string) (string, error) {
+	version = strings.TrimPrefix(version, "v")
+	arch, ok := map[string]string{"amd64": "x64", "arm64": "arm64", "386": "x86", "arm": "armv7l"}[goarch]
+	if !ok {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	platform, ext := goos, "tar.gz"
+	switch goos {
+	case "linux", "darwin":
+	case "windows":
+		platform, ext = "win", "zip"
+	default:
+		return "", fmt.Errorf("unsupported OS %s", goos)
+	}
+	return fmt.Sprintf("https://nodejs.org/dist/v%s/node-v%s-%s-%s.%s", version, version, platform, arch, ext), nil
+}
+
+// pythonSource returns a relocatable CPython build from the
+// python-build-standalone project, since python.org only publishes installers.
+// Its releases are named by date, so the version must carry the release it
+// comes from, e.g. 3.12.3+20240415.
+func pythonSource(version, goos, goarch string) (string, error) {
+	pyVersion, release, ok := strings.Cut(version, "+")
+	if !ok || release == "" {
+		return "", fmt.Errorf("python versions must name a python-build-standalone release, e.g. 3.12.3+20240415")
+	}
+	arch, ok := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[goarch]
+	if !ok {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	var platform string
+	switch goos {
+	case "linux":
+		platform = "unknown-linux-gnu"
+	case "darwin":
+		platform = "apple-darwin"
+	case "windows":
+		platform = "pc-windows-msvc"
+	default:
+		return "", fmt.Errorf("unsupported OS %s", goos)
+	}
+	return fmt.Sprintf("https://github.com/astral-sh/python-build-standalone/releases/download/%s/cpython-%s+%s-%s-%s-install_only.tar.gz",
+		release, pyVersion, release, arch, platform), nil
+}
@@ -0,0 +1,52 @@
+package deps
+
+import "testing"
+
+func TestInferSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		version string
+		goos    string
+		goarch  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "go", tool: "go", version: "1.22.0", goos: "linux", goarch: "amd64",
+			want: "https://go.dev/dl/go1.22.0.linux-amd64.tar.gz",
+		},
+		{
+			name: "go on windows with v prefix", tool: "go", version: "v1.22.0", goos: "windows", goarch: "arm64",
+			want: "https://go.dev/dl/go1.22.0.windows-arm64.zip",
+		},
+		{
+			name: "node", tool: "node", version: "20.11.1", goos: "darwin", goarch: "arm64",
+			want: "https://nodejs.org/dist/v20.11.1/node-v20.11.1-darwin-arm64.tar.gz",
+		},
+		{
+			name: "node on linux amd64", tool: "node", version: "v20.11.1", goos: "linux", goarch: "amd64",
+			want: "https://nodejs.org/dist/v20.11.1/node-v20.11.1-linux-x64.tar.gz",
+		},
+		{
+			name: "python", tool: "python", version: "3.12.3+20240415", goos: "linux", goarch: "arm64",
+			want: "https://github.com/astral-sh/python-build-standalone/releases/download/20240415/cpython-3.12.3+20240415-aarch64-unknown-linux-gnu-install_only.tar.gz",
+		},
+		{name: "python without release", tool: "python", version: "3.12.3", goos: "linux", goarch: "amd64", wantErr: true},
+		{name: "unknown tool", tool: "ruby", version: "3.3.0", goos: "linux", goarch: "amd64", wantErr: true},
+		{name: "no version", tool: "go", goos: "linux", goarch: "amd64", wantErr: true},
+		{name: "unsupported arch", tool: "node", version: "20.11.1", goos: "linux", goarch: "riscv64", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InferSource(tt.tool, tt.version, tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InferSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("InferSource() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+)
+
+// MetadataFile is the install record written into each dependency's directory
+const MetadataFile = ".dev-manager.json"
+
+// ManifestFile lists the sha256 of every installed file, in the format of
+// sha256sum, so Verify can tell when files were changed or removed
+const ManifestFile = ".dev-manager.sha256"
+
+// Metadata records what was installed for a dependency
+type Metadata struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Source      string    `json:"source"`
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+// Status describes how an installed dependency compares to its configuration
+type Status string
+
+const (
+	// StatusOK means the installed dependency matches its configuration
+	StatusOK Status = "OK"
+	// StatusMissing means the dependency is not installed
+	StatusMissing Status = "MISSING"
+	// StatusDrifted means the installation differs from the configuration
+	StatusDrifted Status = "DRIFTED"
+	// StatusIncomplete means files of the installation have been removed
+	StatusIncomplete Status = "INCOMPLETE"
+	// StatusModified means files of the installation were changed since it
+	// was installed. Files added since, e.g. packages installed into it, are
+	// expected and don't count.
+	StatusModified Status = "MODIFIED"
+)
+
+// Verification is the result of checking an installed dependency
+type Verification struct {
+	Status Status
+	// Detail explains a non-OK status. For an OK one it may mention files
+	// added since the install, for information.
+	Detail string
+}
+
+// ReadMetadata returns the install record of a dependency
+func (m *Manager) ReadMetadata(dep config.Dependency) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(m.InstallDir, dep.Name, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse install record: %w", err)
+	}
+	return &meta, nil
+}
+
+// writeMetadata records a completed install, and the files it consists of, in
+// the dependency's directory
+func writeMetadata(depPath string, dep config.Dependency) error {
+	if err := writeManifest(depPath); err != nil {
+		return err
+	}
+	return saveMetadata(depPath, Metadata{
+		Name:        dep.Name,
+		Version:     dep.Version,
+		Source:      dep.Source,
+		InstalledAt: time.Now(),
+	})
+}
+
+// writeManifest records the hash of every file under depPath
+func writeManifest(depPath string) error {
+	sums, err := hashFiles(depPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash installed files: %w", err)
+	}
+
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	return os.WriteFile(filepath.Join(depPath, ManifestFile), []byte(b.String()), 0644)
+}
+
+// readManifest returns the file hashes recorded by writeManifest, keyed by
+// path relative to depPath
+func readManifest(depPath string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(depPath, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("malformed line in %s: %q", ManifestFile, line)
+		}
+		sums[name] = sum
+	}
+	return sums, nil
+}
+
+// hashFiles returns the sha256 of every file under depPath, following
+// symlinks like sha256sum does, keyed by slash-separated relative path. The
+// install record and manifest themselves are left out, as are symlinks to
+// directories or to nothing.
+func hashFiles(depPath string) (map[string]string, error) {
+	names, err := installedFiles(depPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]string, len(names))
+	for _, name := range names {
+		sum, err := hashFile(filepath.Join(depPath, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		sums[name] = sum
+	}
+	return sums, nil
+}
+
+// installedFiles lists the files hashFiles hashes, by slash-separated path
+// relative to depPath
+func installedFiles(depPath string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(depPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(depPath, path)
+		if err != nil {
+			return err
+		}
+		if rel == MetadataFile || rel == ManifestFile {
+			return nil
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return nil
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+// hashFile returns the hex-encoded sha256 of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkFiles compares the files under depPath with its manifest: recorded
+// files that are gone or whose content changed fail the check, while files
+// added since the install are only noted. Installs recorded before manifests
+// were written have none and pass.
+func checkFiles(depPath string) (Verification, error) {
+	want, err := readManifest(depPath)
+	if os.IsNotExist(err) {
+		return Verification{Status: StatusOK}, nil
+	}
+	if err != nil {
+		return Verification{Status: StatusModified, Detail: err.Error()}, nil
+	}
+
+	var missing, changed []string
+	for name, sum := range want {
+		path := filepath.Join(depPath, filepath.FromSlash(name))
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			missing = append(missing, name)
+			continue
+		}
+		actual, err := hashFile(path)
+		if err != nil {
+			return Verification{}, err
+		}
+		if actual != sum {
+			changed = append(changed, name)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		return Verification{Status: StatusIncomplete, Detail: describeFiles(missing, "missing")}, nil
+	case len(changed) > 0:
+		return Verification{Status: StatusModified, Detail: describeFiles(changed, "changed")}, nil
+	}
+
+	names, err := installedFiles(depPath)
+	if err != nil {
+		return Verification{}, err
+	}
+	var added []string
+	for _, name := range names {
+		if _, ok := want[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	if len(added) > 0 {
+		return Verification{Status: StatusOK, Detail: describeFiles(added, "added since the install")}, nil
+	}
+	return Verification{Status: StatusOK}, nil
+}
+
+// describeFiles summarizes a list of files, naming the first few
+func describeFiles(names []string, what string) string {
+	slices.Sort(names)
+	const shown = 3
+	if len(names) > shown {
+		return fmt.Sprintf("%d files %s: %s, ...", len(names), what, strings.Join(names[:shown], ", "))
+	}
+	if len(names) == 1 {
+		return fmt.Sprintf("1 file %s: %s", what, names[0])
+	}
+	return fmt.Sprintf("%d files %s: %s", len(names), what, strings.Join(names, ", "))
+}
+
+// saveMetadata writes an install record into a dependency's directory
+func saveMetadata(depPath string, meta Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(depPath, MetadataFile), data, 0644)
+}
+
+// Invalidate deletes a dependency's install record, so the installation no
+// longer counts as up to date and the next sync reinstalls it
+func (m *Manager) Invalidate(dep config.Dependency) error {
+	depPath, err := m.installPath(dep.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(depPath, MetadataFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove install record of %s: %w", dep.Name, err)
+	}
+	return nil
+}
+
+// NeedsReinstall reports whether a dependency is installed but has no install
+// record, e.g. after Invalidate
+func (m *Manager) NeedsReinstall(dep config.Dependency) bool {
+	if !m.IsInstalled(dep) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(m.InstallDir, dep.Name, MetadataFile))
+	return os.IsNotExist(err)
+}
+
+// Verify compares an installed dependency with its configuration, and its
+// files with those recorded when it was installed
+func (m *Manager) Verify(dep config.Dependency) (Verification, error) {
+	depPath := filepath.Join(m.InstallDir, dep.Name)
+	if _, err := os.Stat(depPath); err != nil {
+		if os.IsNotExist(err) {
+			return Verification{Status: StatusMissing, Detail: "not installed"}, nil
+		}
+		return Verification{}, err
+	}
+
+	meta, err := m.ReadMetadata(dep)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Verification{Status: StatusDrifted, Detail: "no install record"}, nil
+		}
+		return Verification{Status: StatusDrifted, Detail: err.Error()}, nil
+	}
+
+	switch {
+	case meta.Version != dep.Version:
+		return Verification{
+			Status: StatusDrifted,
+			Detail: fmt.Sprintf("version %s installed, %s configured", meta.Version, dep.Version),
+		}, nil
+	case meta.Source != dep.Source:
+		return Verification{
+			Status: StatusDrifted,
+			Detail: fmt.Sprintf("installed from %s, %s configured", meta.Source, dep.Source),
+		}, nil
+	}
+
+	return checkFiles(depPath)
+}
+
+// Repair reinstalls a dependency that is missing or has drifted from its
+// configuration, leaving healthy installs untouched. It returns the
+// verification observed before any repair. The reinstall gives up once ctx
+// is done.
+func (m *Manager) Repair(ctx context.Context, dep config.Dependency) (Verification, error) {
+	v, err := m.Verify(dep)
+	if err != nil || v.Status == StatusOK {
+		return v, err
+	}
+
+	if err := m.InstallContext(ctx, dep, true); err != nil {
+		return v, fmt.Errorf("failed to repair %s: %w", dep.Name, err)
+	}
+	return v, nil
+}
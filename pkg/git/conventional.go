@@ -0,0 +1,104 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// CommitTypes are the standard conventional commit types
+var CommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// CommitPrefix returns the conventional commit prefix for a type and optional
+// scope, e.g. "feat(api): "
+func CommitPrefix(commitType, scope string) (string, error) {
+	if !slices.Contains(CommitTypes, commitType) {
+		return "", fmt.Errorf("unknown commit type %q (supported: %s)", commitType, strings.Join(CommitTypes, ", "))
+	}
+	if scope == "" {
+		return commitType + ": ", nil
+	}
+	if strings.ContainsAny(scope, "() :") {
+		return "", fmt.Errorf("invalid commit scope %q", scope)
+	}
+	return fmt.Sprintf("%s(%s): ", commitType, scope), nil
+}
+
+// MessageArgs splits a commit message into paragraphs and returns them as
+// git commit -m arguments, so a subject, body and footer such as
+// "BREAKING CHANGE: ..." end up separated by blank lines
+func MessageArgs(msg string) []string {
+	var args []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			args = append(args, "-m", paragraph)
+		}
+	}
+	return args
+}
+
+// OfflineCommitMessage builds a conventional commit subject from the names of
+// the changed files alone, e.g. "chore: update 3 files in pkg/config", for
+// when no LLM is available. The type is guessed from the kind of files
+// changed unless prefix, as returned by CommitPrefix, fixes it.
+func OfflineCommitMessage(files []string, prefix string) string {
+	if prefix == "" {
+		prefix = guessCommitType(files) + ": "
+	}
+	if len(files) == 1 {
+		return prefix + "update " + files[0]
+	}
+
+	subject := fmt.Sprintf("%supdate %d files", prefix, len(files))
+	if dir := commonDir(files); dir != "." {
+		subject += " in " + dir
+	}
+	return subject
+}
+
+// guessCommitType picks the conventional commit type shared by every file, or
+// chore when they differ
+func guessCommitType(files []string) string {
+	commitType := ""
+	for _, file := range files {
+		t := fileCommitType(file)
+		if commitType != "" && t != commitType {
+			return "chore"
+		}
+		commitType = t
+	}
+	if commitType == "" {
+		return "chore"
+	}
+	return commitType
+}
+
+// fileCommitType returns the conventional commit type a change to file alone
+// suggests
+func fileCommitType(file string) string {
+	base := path.Base(file)
+	switch {
+	case strings.HasPrefix(file, ".github/workflows/"), base == ".gitlab-ci.yml":
+		return "ci"
+	case strings.HasSuffix(base, "_test.go"), strings.HasPrefix(file, "testdata/"), strings.Contains(file, "/testdata/"):
+		return "test"
+	case strings.HasSuffix(base, ".md"), strings.HasPrefix(file, "docs/"):
+		return "docs"
+	case base == "go.mod", base == "go.sum", base == "Makefile", base == "Dockerfile":
+		return "build"
+	}
+	return "chore"
+}
+
+// commonDir returns the deepest directory containing every file, "." when
+// they only share the repository root
+func commonDir(files []string) string {
+	dir := path.Dir(files[0])
+	for _, file := range files[1:] {
+		for dir != "." && !strings.HasPrefix(file, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
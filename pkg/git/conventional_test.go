@@ -0,0 +1,83 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommitPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		commitType string
+		scope      string
+		want       string
+		wantErr    bool
+	}{
+		{name: "type only", commitType: "fix", want: "fix: "},
+		{name: "type and scope", commitType: "feat", scope: "api", want: "feat(api): "},
+		{name: "unknown type", commitType: "feature", wantErr: true},
+		{name: "scope with parenthesis", commitType: "feat", scope: "a)b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CommitPrefix(tt.commitType, tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CommitPrefix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CommitPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want []string
+	}{
+		{name: "subject only", msg: "fix: typo", want: []string{"-m", "fix: typo"}},
+		{
+			name: "subject, body and footer",
+			msg:  "feat: new api\n\nReplaces the old endpoint.\nClients must migrate.\n\n\nBREAKING CHANGE: /v1 is gone\n",
+			want: []string{"-m", "feat: new api", "-m", "Replaces the old endpoint.\nClients must migrate.", "-m", "BREAKING CHANGE: /v1 is gone"},
+		},
+		{name: "empty", msg: "  \n", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessageArgs(tt.msg); !slices.Equal(got, tt.want) {
+				t.Errorf("MessageArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfflineCommitMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		prefix string
+		want   string
+	}{
+		{name: "single file", files: []string{"pkg/config/types.go"}, want: "chore: update pkg/config/types.go"},
+		{name: "files in one directory", files: []string{"pkg/config/a.go", "pkg/config/b.go", "pkg/config/sub/c.go"}, want: "chore: update 3 files in pkg/config"},
+		{name: "files across the repository", files: []string{"cmd/main.go", "pkg/config/a.go"}, want: "chore: update 2 files"},
+		{name: "shared directory name prefix", files: []string{"pkg/app/a.go", "pkg/apps/b.go"}, want: "chore: update 2 files in pkg"},
+		{name: "docs only", files: []string{"README.md", "docs/usage.md"}, want: "docs: update 2 files"},
+		{name: "tests only", files: []string{"pkg/git/repo_test.go", "pkg/git/url_test.go"}, want: "test: update 2 files in pkg/git"},
+		{name: "mixed kinds", files: []string{"README.md", "pkg/git/repo_test.go"}, want: "chore: update 2 files"},
+		{name: "given prefix", files: []string{"pkg/deps/cache.go"}, prefix: "fix(deps): ", want: "fix(deps): update pkg/deps/cache.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OfflineCommitMessage(tt.files, tt.prefix); got != tt.want {
+				t.Errorf("OfflineCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
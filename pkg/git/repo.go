@@ -1,17 +1,92 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrBranchNotFound is matched by the error CheckRemoteBranch returns when the
+// remote has no such branch
+var ErrBranchNotFound = errors.New("branch not found")
+
+// ErrDirtyWorktree is returned by Checkout when the working tree has
+// uncommitted changes that switching branches would carry along
+var ErrDirtyWorktree = errors.New("working tree has uncommitted changes")
+
+// ErrDetachedHead is returned by Update when HEAD isn't on a branch, so there
+// is nothing to rebase onto the remote branch
+var ErrDetachedHead = errors.New("HEAD is detached")
+
+// ErrRebaseConflict is returned by Update and SyncUpstream when rebasing onto
+// the fetched branch conflicts. The rebase is aborted first, leaving the
+// branch as it was before.
+var ErrRebaseConflict = errors.New("rebase conflict")
+
+// BranchNotFoundError reports a branch missing from a remote repository
+type BranchNotFoundError struct {
+	Branch string
+	URL    string
+	// Available lists some of the branches the remote does have
+	Available []string
+}
+
+func (e *BranchNotFoundError) Error() string {
+	msg := fmt.Sprintf("branch %q not found in %s", e.Branch, e.URL)
+	if len(e.Available) > 0 {
+		msg += fmt.Sprintf(" (available: %s)", strings.Join(e.Available, ", "))
+	}
+	return msg
+}
+
+func (e *BranchNotFoundError) Unwrap() error { return ErrBranchNotFound }
+
+// maxListedBranches caps how many branches a BranchNotFoundError suggests
+const maxListedBranches = 5
+
+// DefaultRemote is the remote repositories are cloned from and synced with
+// unless Remote says otherwise
+const DefaultRemote = "origin"
+
+// UpstreamRemote is the name of the remote pointing at the repository a fork tracks
+const UpstreamRemote = "upstream"
+
 // Repository handles git operations for a single repository
 type Repository struct {
 	Path   string
 	URL    string
 	Branch string
+	// Remote is the name of the remote URL is cloned as and updates are
+	// fetched from; DefaultRemote when empty
+	Remote string
+	// UpstreamURL is the repository URL is a fork of, tracked as the upstream remote
+	UpstreamURL string
+	// Ref pins the checkout to a tag or commit instead of the tip of Branch.
+	// Pinned repositories are checked out detached and never rebased.
+	Ref string
+	// URLScheme rewrites URL to https or ssh when cloning, see ConvertURL
+	URLScheme string
+	// Recurse clones and updates the repository's submodules along with it
+	Recurse bool
+	// Prune makes Update delete remote-tracking refs whose branches were
+	// deleted from Remote
+	Prune bool
+	// DryRun makes Update and SyncUpstream return without running git;
+	// UpdatePlan describes what they would have done
+	DryRun bool
+}
+
+// gitCommand builds a git invocation, logging it at debug level
+func gitCommand(ctx context.Context, args ...string) *exec.Cmd {
+	slog.Debug("running git", "args", args)
+	return exec.CommandContext(ctx, "git", args...)
 }
 
 // New creates a new Repository instance
@@ -23,11 +98,37 @@ func New(path, url, branch string) *Repository {
 		Path:   path,
 		URL:    url,
 		Branch: branch,
+		Remote: DefaultRemote,
 	}
 }
 
+// ValidateRemote checks that Remote is usable as a remote name and doesn't
+// clash with the upstream remote of a fork
+func (r *Repository) ValidateRemote() error {
+	if strings.ContainsAny(r.Remote, " /:") || strings.HasPrefix(r.Remote, "-") {
+		return fmt.Errorf("invalid remote name %q", r.Remote)
+	}
+	if r.UpstreamURL != "" && r.remote() == UpstreamRemote {
+		return fmt.Errorf("remote %q is reserved for the repository the fork tracks", UpstreamRemote)
+	}
+	return nil
+}
+
+// remote returns the name of the remote to sync with
+func (r *Repository) remote() string {
+	if r.Remote == "" {
+		return DefaultRemote
+	}
+	return r.Remote
+}
+
 // Clone clones the repository if it doesn't exist
 func (r *Repository) Clone() error {
+	return r.CloneContext(context.Background())
+}
+
+// CloneContext clones the repository, aborting the clone when ctx is done
+func (r *Repository) CloneContext(ctx context.Context) error {
 	if _, err := os.Stat(r.Path); !os.IsNotExist(err) {
 		return fmt.Errorf("path already exists: %s", r.Path)
 	}
@@ -36,41 +137,200 @@ func (r *Repository) Clone() error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "clone", "-b", r.Branch, r.URL, r.Path)
+	url := ConvertURL(r.URL, r.URLScheme)
+	args := []string{"clone", "-b", r.Branch, url, r.Path}
+	if r.Ref != "" {
+		// The ref may be a commit, which clone -b can't take; check it out below
+		args = []string{"clone", "--no-checkout", url, r.Path}
+	} else if r.Recurse {
+		args = append(args, "--recurse-submodules")
+	}
+	if r.remote() != DefaultRemote {
+		args = append(args, "--origin", r.remote())
+	}
+
+	slog.Info("cloning repository", "url", url, "path", r.Path)
+	cmd := gitCommand(ctx, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to clone repository: %w", err)
 	}
 
+	if r.Ref != "" {
+		if err := r.checkoutRef(ctx); err != nil {
+			return err
+		}
+		// Submodules can only be initialized once the ref is checked out
+		if r.Recurse {
+			if err := r.updateSubmodules(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.UpstreamURL != "" {
+		if err := r.AddRemote(ctx, UpstreamRemote, r.UpstreamURL); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Update fetches and rebases the repository
 func (r *Repository) Update() error {
+	return r.UpdateContext(context.Background())
+}
+
+// UpdateContext fetches Branch from Remote and rebases onto it, aborting when
+// ctx is done.
+// Repositories pinned to a Ref are fetched and checked out at the ref instead.
+func (r *Repository) UpdateContext(ctx context.Context) error {
+	if r.DryRun {
+		return nil
+	}
+
 	// Check if directory exists
 	if _, err := os.Stat(r.Path); os.IsNotExist(err) {
-		return r.Clone()
+		return r.CloneContext(ctx)
+	}
+
+	slog.Info("updating repository", "path", r.Path)
+	if r.Ref != "" {
+		args := []string{"-C", r.Path, "fetch", "--tags"}
+		if r.Prune {
+			args = append(args, "--prune")
+		}
+		fetchCmd := gitCommand(ctx, append(args, r.remote())...)
+		if output, err := fetchCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to fetch updates: %s, %w", string(output), err)
+		}
+		if err := r.checkoutRef(ctx); err != nil {
+			return err
+		}
+		if r.Recurse {
+			return r.updateSubmodules(ctx)
+		}
+		return nil
+	}
+
+	if err := r.checkAttached(ctx); err != nil {
+		return err
 	}
 
-	// Fetch updates
-	fetchCmd := exec.Command("git", "-C", r.Path, "fetch", "origin", r.Branch)
+	// Fetch updates. Pruning needs the whole remote fetched, as git only
+	// prunes refs matching the refspecs it fetches.
+	args := []string{"-C", r.Path, "fetch", r.remote(), r.Branch}
+	if r.Prune {
+		args = []string{"-C", r.Path, "fetch", "--prune", r.remote()}
+	}
+	fetchCmd := gitCommand(ctx, args...)
 	if output, err := fetchCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to fetch updates: %s, %w", string(output), err)
 	}
 
 	// Rebase
-	rebaseCmd := exec.Command("git", "-C", r.Path, "rebase", fmt.Sprintf("origin/%s", r.Branch))
-	if output, err := rebaseCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to rebase: %s, %w", string(output), err)
+	if err := r.rebase(ctx, fmt.Sprintf("%s/%s", r.remote(), r.Branch)); err != nil {
+		return err
+	}
+
+	if r.Recurse {
+		return r.updateSubmodules(ctx)
 	}
 
 	return nil
 }
 
+// UpdatePlan describes what Update, followed by SyncUpstream for forks,
+// would do to the repository in its current state
+func (r *Repository) UpdatePlan() string {
+	if _, err := os.Stat(r.Path); os.IsNotExist(err) {
+		if r.Ref != "" {
+			return fmt.Sprintf("clone %s into %s at %s", r.URL, r.Path, r.Ref)
+		}
+		return fmt.Sprintf("clone %s (%s) into %s", r.URL, r.Branch, r.Path)
+	}
+	if r.Ref != "" {
+		return fmt.Sprintf("fetch %s and check out %s", r.remote(), r.Ref)
+	}
+
+	plan := fmt.Sprintf("fetch %s/%s and rebase onto it", r.remote(), r.Branch)
+	if r.Prune {
+		plan = fmt.Sprintf("fetch and prune %s, then rebase onto %s/%s", r.remote(), r.remote(), r.Branch)
+	}
+	if r.UpstreamURL != "" {
+		plan += fmt.Sprintf(", then rebase onto %s/%s", UpstreamRemote, r.Branch)
+	}
+	return plan
+}
+
+// checkAttached returns ErrDetachedHead if HEAD isn't on a branch
+func (r *Repository) checkAttached(ctx context.Context) error {
+	cmd := gitCommand(ctx, "-C", r.Path, "symbolic-ref", "--short", "-q", "HEAD")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	// symbolic-ref -q exits 1, printing nothing, only when HEAD is detached
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return fmt.Errorf("%s: %w; check out %s (git -C %s checkout %s) before syncing", r.Path, ErrDetachedHead, r.Branch, r.Path, r.Branch)
+	}
+	return fmt.Errorf("failed to get current branch: %s, %w", string(output), err)
+}
+
+// rebaseAbortTimeout bounds the "git rebase --abort" run after a failed
+// rebase, which may have to outlive the rebase's own context
+const rebaseAbortTimeout = 30 * time.Second
+
+// rebase rebases the current branch onto upstream. A rebase that stops on a
+// conflict is aborted and reported as ErrRebaseConflict.
+func (r *Repository) rebase(ctx context.Context, upstream string) error {
+	rebaseCmd := gitCommand(ctx, "-C", r.Path, "rebase", upstream)
+	output, err := rebaseCmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	// Only a rebase that got under way can be aborted; one refused up front,
+	// e.g. over uncommitted changes, changed nothing. A rebase killed because
+	// ctx is done must be aborted all the same, so the abort gets a context
+	// of its own.
+	abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rebaseAbortTimeout)
+	defer cancel()
+	abortCmd := gitCommand(abortCtx, "-C", r.Path, "rebase", "--abort")
+	if abortCmd.Run() != nil {
+		return fmt.Errorf("failed to rebase onto %s: %s, %w", upstream, string(output), err)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("rebase onto %s was interrupted and aborted: %w", upstream, ctxErr)
+	}
+	return fmt.Errorf("%s has diverged from %s (%w); the rebase was aborted, resolve it by hand", r.Path, upstream, ErrRebaseConflict)
+}
+
+// checkoutRef checks out the pinned Ref as a detached HEAD
+func (r *Repository) checkoutRef(ctx context.Context) error {
+	cmd := gitCommand(ctx, "-C", r.Path, "checkout", "--detach", r.Ref)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to check out %s: %s, %w", r.Ref, string(output), err)
+	}
+	return nil
+}
+
+// updateSubmodules initializes and updates submodules to the commits recorded
+// in the checked out tree
+func (r *Repository) updateSubmodules(ctx context.Context) error {
+	cmd := gitCommand(ctx, "-C", r.Path, "submodule", "update", "--init", "--recursive")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update submodules: %s, %w", string(output), err)
+	}
+	return nil
+}
+
 // IsClean checks if the repository has any uncommitted changes
 func (r *Repository) IsClean() (bool, error) {
-	cmd := exec.Command("git", "-C", r.Path, "status", "--porcelain")
+	cmd := gitCommand(context.Background(), "-C", r.Path, "status", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return false, fmt.Errorf("failed to check repository status: %w", err)
@@ -78,3 +338,209 @@ func (r *Repository) IsClean() (bool, error) {
 
 	return len(output) == 0, nil
 }
+
+// CurrentBranch returns the name of the checked out branch, or "HEAD" when detached
+func (r *Repository) CurrentBranch() (string, error) {
+	cmd := gitCommand(context.Background(), "-C", r.Path, "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// AheadBehind returns how many commits the current branch is ahead of and
+// behind its upstream tracking branch
+func (r *Repository) AheadBehind() (ahead, behind int, err error) {
+	cmd := gitCommand(context.Background(), "-C", r.Path, "rev-list", "--left-right", "--count", "@{u}...HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare with upstream: %w", err)
+	}
+
+	// Output format: <commits only in upstream>\t<commits only in HEAD>
+	counts := strings.Fields(string(output))
+	if len(counts) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", string(output))
+	}
+
+	behind, err = strconv.Atoi(counts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", string(output))
+	}
+	ahead, err = strconv.Atoi(counts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", string(output))
+	}
+
+	return ahead, behind, nil
+}
+
+// BranchExists reports whether a local branch with the given name exists
+func (r *Repository) BranchExists(name string) (bool, error) {
+	cmd := gitCommand(context.Background(), "-C", r.Path, "rev-parse", "--verify", "--quiet", "refs/heads/"+name)
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check branch %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// CheckRemoteBranch verifies that the remote repository has Branch, so a
+// typo fails before a clone is attempted. It returns a *BranchNotFoundError
+// when the branch is missing.
+func (r *Repository) CheckRemoteBranch(ctx context.Context) error {
+	url := ConvertURL(r.URL, r.URLScheme)
+	output, err := gitCommand(ctx, "ls-remote", "--heads", url).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list branches of %s: %w", url, err)
+	}
+
+	var available []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		branch := strings.TrimPrefix(fields[1], "refs/heads/")
+		if branch == r.Branch {
+			return nil
+		}
+		available = append(available, branch)
+	}
+
+	if len(available) > maxListedBranches {
+		available = available[:maxListedBranches]
+	}
+	return &BranchNotFoundError{Branch: r.Branch, URL: url, Available: available}
+}
+
+// DefaultBranch asks the remote repository which branch its HEAD points
+// to, i.e. the branch a plain clone checks out
+func (r *Repository) DefaultBranch(ctx context.Context) (string, error) {
+	url := ConvertURL(r.URL, r.URLScheme)
+	output, err := gitCommand(ctx, "ls-remote", "--symref", url, "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to query the default branch of %s: %w", url, err)
+	}
+
+	// The symref is listed as "ref: refs/heads/<branch>\tHEAD"
+	for _, line := range strings.Split(string(output), "\n") {
+		target, ok := strings.CutPrefix(line, "ref: ")
+		if !ok {
+			continue
+		}
+		ref, name, _ := strings.Cut(target, "\t")
+		if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok && name == "HEAD" {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("%s did not report a default branch", url)
+}
+
+// CreateBranch creates a branch starting at from (HEAD when empty) and switches to it.
+// Uncommitted and staged changes are carried over to the new branch.
+func (r *Repository) CreateBranch(name, from string) error {
+	args := []string{"-C", r.Path, "checkout", "-b", name}
+	if from != "" {
+		args = append(args, from)
+	}
+
+	cmd := gitCommand(context.Background(), args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create branch %s: %s, %w", name, string(output), err)
+	}
+	return nil
+}
+
+// SwitchBranch switches to an existing local branch
+func (r *Repository) SwitchBranch(name string) error {
+	cmd := gitCommand(context.Background(), "-C", r.Path, "checkout", name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to switch to branch %s: %s, %w", name, string(output), err)
+	}
+	return nil
+}
+
+// Checkout switches the clone to branch and makes it the tracked Branch. A
+// branch that only exists on Remote is fetched and created tracking it. The
+// working tree must be clean; otherwise ErrDirtyWorktree is returned.
+func (r *Repository) Checkout(ctx context.Context, branch string) error {
+	clean, err := r.IsClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("%w in %s; commit or stash them first", ErrDirtyWorktree, r.Path)
+	}
+
+	exists, err := r.BranchExists(branch)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		tracking := r.remote() + "/" + branch
+		refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", branch, tracking)
+		output, err := gitCommand(ctx, "-C", r.Path, "fetch", r.remote(), refspec).CombinedOutput()
+		if err != nil {
+			if strings.Contains(string(output), "couldn't find remote ref") {
+				return &BranchNotFoundError{Branch: branch, URL: r.remote()}
+			}
+			return fmt.Errorf("failed to fetch %s: %s, %w", tracking, string(output), err)
+		}
+		cmd := gitCommand(ctx, "-C", r.Path, "checkout", "-b", branch, "--track", tracking)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to check out %s: %s, %w", branch, string(output), err)
+		}
+	} else if err := r.SwitchBranch(branch); err != nil {
+		return err
+	}
+
+	r.Branch = branch
+	return nil
+}
+
+// AddRemote adds a named remote to the repository
+func (r *Repository) AddRemote(ctx context.Context, name, url string) error {
+	cmd := gitCommand(ctx, "-C", r.Path, "remote", "add", name, url)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to add remote %s: %s, %w", name, string(output), err)
+	}
+	return nil
+}
+
+// SyncUpstream fetches the upstream remote and rebases onto its branch
+func (r *Repository) SyncUpstream() error {
+	return r.SyncUpstreamContext(context.Background())
+}
+
+// SyncUpstreamContext fetches the upstream remote and rebases onto its branch,
+// adding the remote first if the clone predates it
+func (r *Repository) SyncUpstreamContext(ctx context.Context) error {
+	if r.UpstreamURL == "" {
+		return fmt.Errorf("no upstream configured for %s", r.Path)
+	}
+	if r.Ref != "" {
+		return fmt.Errorf("%s is pinned to %s; not rebasing onto upstream", r.Path, r.Ref)
+	}
+	if r.DryRun {
+		return nil
+	}
+
+	getURLCmd := gitCommand(ctx, "-C", r.Path, "remote", "get-url", UpstreamRemote)
+	if err := getURLCmd.Run(); err != nil {
+		if err := r.AddRemote(ctx, UpstreamRemote, r.UpstreamURL); err != nil {
+			return err
+		}
+	}
+
+	fetchCmd := gitCommand(ctx, "-C", r.Path, "fetch", UpstreamRemote, r.Branch)
+	if output, err := fetchCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch upstream: %s, %w", string(output), err)
+	}
+
+	return r.rebase(ctx, fmt.Sprintf("%s/%s", UpstreamRemote, r.Branch))
+}
@@ -1,9 +1,13 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"dev-manager/internal/testutil/mockgit"
 )
@@ -33,7 +37,7 @@ func TestRepository_Clone(t *testing.T) {
 		},
 		{
 			name: "git command fails",
-			repo: New(filepath.Join(tempDir, "repo"), "https://github.com/test/repo", "main"),
+			repo: New(filepath.Join(tempDir, "missing"), "https://github.com/test/repo", "main"),
 			config: mockgit.Config{
 				ExitCode: 1,
 				Error:    "fatal: repository 'https://github.com/test/repo' not found\n",
@@ -46,6 +50,7 @@ func TestRepository_Clone(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Configure mock git behavior
 			mock.Configure(t, tt.config)
+			mock.Reset(t)
 
 			// Run the test
 			err := tt.repo.Clone()
@@ -55,6 +60,11 @@ func TestRepository_Clone(t *testing.T) {
 				t.Errorf("Repository.Clone() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
+			want := [][]string{{"clone", "-b", "main", tt.repo.URL, tt.repo.Path}}
+			if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+				t.Errorf("git invocations = %v, want %v", got, want)
+			}
+
 			// Check if directory exists for successful clone
 			if !tt.wantErr {
 				if _, err := os.Stat(tt.repo.Path); os.IsNotExist(err) {
@@ -64,3 +74,708 @@ func TestRepository_Clone(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_CloneContextCanceled(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo := New(filepath.Join(t.TempDir(), "repo"), "https://github.com/test/repo", "main")
+	if err := repo.CloneContext(ctx); err == nil {
+		t.Error("Repository.CloneContext() with canceled context succeeded, want error")
+	}
+}
+
+func TestRepository_AddRemote(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name    string
+		config  mockgit.Config
+		wantErr bool
+	}{
+		{
+			name:    "remote added",
+			config:  mockgit.Config{ExitCode: 0},
+			wantErr: false,
+		},
+		{
+			name: "remote already exists",
+			config: mockgit.Config{
+				ExitCode: 3,
+				Error:    "error: remote upstream already exists.\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := New(t.TempDir(), "https://github.com/me/repo", "main")
+			err := repo.AddRemote(context.Background(), UpstreamRemote, "https://github.com/org/repo")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.AddRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_CloneFork(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	repo := New(filepath.Join(t.TempDir(), "fork"), "https://github.com/me/repo", "main")
+	repo.UpstreamURL = "https://github.com/org/repo"
+
+	if err := repo.Clone(); err != nil {
+		t.Fatalf("Repository.Clone() error = %v", err)
+	}
+
+	want := [][]string{
+		{"clone", "-b", "main", repo.URL, repo.Path},
+		{"-C", repo.Path, "remote", "add", UpstreamRemote, repo.UpstreamURL},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("git invocations = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_AheadBehind(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name       string
+		config     mockgit.Config
+		wantAhead  int
+		wantBehind int
+		wantErr    bool
+	}{
+		{
+			name:       "ahead and behind",
+			config:     mockgit.Config{Output: "2\t5\n"},
+			wantAhead:  5,
+			wantBehind: 2,
+		},
+		{
+			name:   "up to date",
+			config: mockgit.Config{Output: "0\t0\n"},
+		},
+		{
+			name: "no upstream",
+			config: mockgit.Config{
+				ExitCode: 128,
+				Error:    "fatal: no upstream configured for branch 'main'\n",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected output",
+			config:  mockgit.Config{Output: "garbage\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := New(t.TempDir(), "https://github.com/test/repo", "main")
+			ahead, behind, err := repo.AheadBehind()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repository.AheadBehind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ahead != tt.wantAhead || behind != tt.wantBehind {
+				t.Errorf("Repository.AheadBehind() = (%d, %d), want (%d, %d)", ahead, behind, tt.wantAhead, tt.wantBehind)
+			}
+		})
+	}
+}
+
+func TestRepository_BranchExists(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name    string
+		config  mockgit.Config
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "branch exists",
+			config: mockgit.Config{Output: "3f2a1b\n"},
+			want:   true,
+		},
+		{
+			name:   "branch missing",
+			config: mockgit.Config{ExitCode: 1},
+			want:   false,
+		},
+		{
+			name:    "not a repository",
+			config:  mockgit.Config{ExitCode: 128, Error: "fatal: not a git repository\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := &Repository{Path: t.TempDir()}
+			got, err := repo.BranchExists("feature")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repository.BranchExists() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Repository.BranchExists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_CheckRemoteBranch(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	heads := "3f2a1b\trefs/heads/main\n9c8d7e\trefs/heads/develop\n"
+	tests := []struct {
+		name          string
+		branch        string
+		config        mockgit.Config
+		wantNotFound  bool
+		wantAvailable []string
+		wantErr       bool
+	}{
+		{name: "branch exists", branch: "develop", config: mockgit.Config{Output: heads}},
+		{
+			name:          "branch missing",
+			branch:        "mian",
+			config:        mockgit.Config{Output: heads},
+			wantNotFound:  true,
+			wantAvailable: []string{"main", "develop"},
+			wantErr:       true,
+		},
+		{
+			name:    "remote unreachable",
+			branch:  "main",
+			config:  mockgit.Config{ExitCode: 128, Error: "fatal: repository not found\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := New(t.TempDir(), "https://github.com/test/repo", tt.branch)
+			err := repo.CheckRemoteBranch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repository.CheckRemoteBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrBranchNotFound) != tt.wantNotFound {
+				t.Fatalf("Repository.CheckRemoteBranch() error = %v, want ErrBranchNotFound %v", err, tt.wantNotFound)
+			}
+			var notFound *BranchNotFoundError
+			if errors.As(err, &notFound) && !reflect.DeepEqual(notFound.Available, tt.wantAvailable) {
+				t.Errorf("available branches = %v, want %v", notFound.Available, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+func TestRepository_DefaultBranch(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name    string
+		config  mockgit.Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "symref reported",
+			config: mockgit.Config{Output: "ref: refs/heads/master\tHEAD\n3f2a1b\tHEAD\n"},
+			want:   "master",
+		},
+		{
+			name:    "no symref",
+			config:  mockgit.Config{Output: "3f2a1b\tHEAD\n"},
+			wantErr: true,
+		},
+		{
+			name:    "remote unreachable",
+			config:  mockgit.Config{ExitCode: 128, Error: "fatal: repository not found\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := New(t.TempDir(), "https://github.com/test/repo", "")
+			got, err := repo.DefaultBranch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Repository.DefaultBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Repository.DefaultBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	mock.Configure(t, mockgit.Config{Output: "ref: refs/heads/main\tHEAD\n"})
+	mock.Reset(t)
+	repo := New(t.TempDir(), "https://github.com/test/repo", "")
+	if _, err := repo.DefaultBranch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ls-remote", "--symref", "https://github.com/test/repo", "HEAD"}
+	if got := mock.Invocations(t); len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("git invocations = %v, want [%v]", got, want)
+	}
+}
+
+func TestRepository_Checkout(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name        string
+		commands    map[string]mockgit.Config
+		wantErr     error
+		wantLastCmd []string // the last git invocation, after -C <path>
+	}{
+		{
+			name:     "dirty worktree",
+			commands: map[string]mockgit.Config{"status": {Output: " M main.go\n"}},
+			wantErr:  ErrDirtyWorktree,
+		},
+		{
+			name:        "local branch",
+			commands:    map[string]mockgit.Config{"rev-parse": {Output: "3f2a1b\n"}},
+			wantLastCmd: []string{"checkout", "feature"},
+		},
+		{
+			name:        "remote-only branch",
+			commands:    map[string]mockgit.Config{"rev-parse": {ExitCode: 1}},
+			wantLastCmd: []string{"checkout", "-b", "feature", "--track", "origin/feature"},
+		},
+		{
+			name: "missing branch",
+			commands: map[string]mockgit.Config{
+				"rev-parse": {ExitCode: 1},
+				"fetch":     {ExitCode: 128, Error: "fatal: couldn't find remote ref feature\n"},
+			},
+			wantErr: ErrBranchNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, mockgit.Config{Commands: tt.commands})
+			mock.Reset(t)
+
+			repo := New(t.TempDir(), "https://github.com/test/repo", "main")
+			err := repo.Checkout(context.Background(), "feature")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Repository.Checkout() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if repo.Branch != "main" {
+					t.Errorf("Branch = %q after a failed checkout, want main", repo.Branch)
+				}
+				return
+			}
+
+			if repo.Branch != "feature" {
+				t.Errorf("Branch = %q, want feature", repo.Branch)
+			}
+			invocations := mock.Invocations(t)
+			last := invocations[len(invocations)-1]
+			if len(last) < 2 || !reflect.DeepEqual(last[2:], tt.wantLastCmd) {
+				t.Errorf("last git invocation = %v, want -C <path> %v", last, tt.wantLastCmd)
+			}
+		})
+	}
+}
+
+func TestRepository_CreateBranch(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name    string
+		from    string
+		config  mockgit.Config
+		wantErr bool
+	}{
+		{
+			name:   "from HEAD",
+			config: mockgit.Config{Error: "Switched to a new branch 'feature'\n"},
+		},
+		{
+			name:   "from ref",
+			from:   "origin/main",
+			config: mockgit.Config{Error: "Switched to a new branch 'feature'\n"},
+		},
+		{
+			name:    "invalid start point",
+			from:    "nope",
+			config:  mockgit.Config{ExitCode: 128, Error: "fatal: 'nope' is not a commit\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+
+			repo := &Repository{Path: t.TempDir()}
+			if err := repo.CreateBranch("feature", tt.from); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.CreateBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_Update(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+
+	tests := []struct {
+		name      string
+		config    mockgit.Config
+		wantCalls []string // subcommands expected to run, in order
+		wantErr   bool
+		wantIs    error
+	}{
+		{
+			name:      "fetch and rebase succeed",
+			config:    mockgit.Config{ExitCode: 0},
+			wantCalls: []string{"symbolic-ref", "fetch", "rebase"},
+		},
+		{
+			name: "detached HEAD",
+			config: mockgit.Config{
+				Commands: map[string]mockgit.Config{
+					"symbolic-ref": {ExitCode: 1},
+				},
+			},
+			wantCalls: []string{"symbolic-ref"},
+			wantErr:   true,
+			wantIs:    ErrDetachedHead,
+		},
+		{
+			name: "fetch fails",
+			config: mockgit.Config{
+				Commands: map[string]mockgit.Config{
+					"fetch": {ExitCode: 128, Error: "fatal: couldn't find remote ref main\n"},
+				},
+			},
+			wantCalls: []string{"symbolic-ref", "fetch"},
+			wantErr:   true,
+		},
+		{
+			name: "rebase conflicts and is aborted",
+			config: mockgit.Config{
+				Commands: map[string]mockgit.Config{
+					"rebase":         {ExitCode: 1, Error: "CONFLICT (content): Merge conflict in main.go\n"},
+					"rebase --abort": {ExitCode: 0},
+				},
+			},
+			wantCalls: []string{"symbolic-ref", "fetch", "rebase", "abort"},
+			wantErr:   true,
+			wantIs:    ErrRebaseConflict,
+		},
+		{
+			name: "rebase refused",
+			config: mockgit.Config{
+				Commands: map[string]mockgit.Config{
+					"rebase": {ExitCode: 128, Error: "error: cannot rebase: You have unstaged changes.\n"},
+				},
+			},
+			wantCalls: []string{"symbolic-ref", "fetch", "rebase", "abort"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Configure(t, tt.config)
+			mock.Reset(t)
+
+			repo := New(t.TempDir(), "https://github.com/test/repo", "main")
+			err := repo.Update()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("Repository.Update() error = %v, want %v", err, tt.wantIs)
+			}
+			if tt.wantIs == nil && (errors.Is(err, ErrRebaseConflict) || errors.Is(err, ErrDetachedHead)) {
+				t.Errorf("Repository.Update() error = %v, want a plain failure", err)
+			}
+
+			wantArgs := map[string][]string{
+				"symbolic-ref": {"-C", repo.Path, "symbolic-ref", "--short", "-q", "HEAD"},
+				"fetch":        {"-C", repo.Path, "fetch", "origin", "main"},
+				"rebase":       {"-C", repo.Path, "rebase", "origin/main"},
+				"abort":        {"-C", repo.Path, "rebase", "--abort"},
+			}
+			var want [][]string
+			for _, call := range tt.wantCalls {
+				want = append(want, wantArgs[call])
+			}
+			if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+				t.Errorf("git invocations = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRepository_UpdateCancelledRebase(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{
+		Commands: map[string]mockgit.Config{
+			"rebase":         {Delay: 10 * time.Second},
+			"rebase --abort": {ExitCode: 0},
+		},
+	})
+
+	// The rebase is killed when the context expires, and must still be aborted
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	repo := New(t.TempDir(), "https://github.com/test/repo", "main")
+	err := repo.UpdateContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Repository.UpdateContext() error = %v, want the context's", err)
+	}
+
+	invocations := mock.Invocations(t)
+	if last := invocations[len(invocations)-1]; !reflect.DeepEqual(last, []string{"-C", repo.Path, "rebase", "--abort"}) {
+		t.Errorf("last git invocation = %v, want the rebase aborted", last)
+	}
+}
+
+func TestRepository_UpdatePrune(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	repo := New(t.TempDir(), "https://github.com/test/repo", "main")
+	repo.Prune = true
+	if err := repo.Update(); err != nil {
+		t.Fatalf("Repository.Update() error = %v", err)
+	}
+	want := [][]string{
+		{"-C", repo.Path, "symbolic-ref", "--short", "-q", "HEAD"},
+		{"-C", repo.Path, "fetch", "--prune", "origin"},
+		{"-C", repo.Path, "rebase", "origin/main"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("update invocations = %v, want %v", got, want)
+	}
+
+	mock.Reset(t)
+	repo.Ref = "v1.4.0"
+	if err := repo.Update(); err != nil {
+		t.Fatalf("Repository.Update() of a pinned ref error = %v", err)
+	}
+	want = [][]string{
+		{"-C", repo.Path, "fetch", "--tags", "--prune", "origin"},
+		{"-C", repo.Path, "checkout", "--detach", "v1.4.0"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("pinned update invocations = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_PinnedRef(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	repo := New(filepath.Join(t.TempDir(), "pinned"), "https://github.com/test/repo", "main")
+	repo.Ref = "v1.4.0"
+
+	if err := repo.Clone(); err != nil {
+		t.Fatalf("Repository.Clone() error = %v", err)
+	}
+	want := [][]string{
+		{"clone", "--no-checkout", repo.URL, repo.Path},
+		{"-C", repo.Path, "checkout", "--detach", "v1.4.0"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("clone invocations = %v, want %v", got, want)
+	}
+
+	// The mock doesn't create the clone, so stand in for it before updating
+	if err := os.MkdirAll(repo.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mock.Reset(t)
+
+	if err := repo.Update(); err != nil {
+		t.Fatalf("Repository.Update() error = %v", err)
+	}
+	want = [][]string{
+		{"-C", repo.Path, "fetch", "--tags", "origin"},
+		{"-C", repo.Path, "checkout", "--detach", "v1.4.0"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("update invocations = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_Submodules(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	repo := New(filepath.Join(t.TempDir(), "repo"), "https://github.com/test/repo", "main")
+	repo.Recurse = true
+
+	if err := repo.Clone(); err != nil {
+		t.Fatalf("Repository.Clone() error = %v", err)
+	}
+	want := [][]string{{"clone", "-b", "main", repo.URL, repo.Path, "--recurse-submodules"}}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("clone invocations = %v, want %v", got, want)
+	}
+
+	if err := os.MkdirAll(repo.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mock.Reset(t)
+
+	if err := repo.Update(); err != nil {
+		t.Fatalf("Repository.Update() error = %v", err)
+	}
+	want = [][]string{
+		{"-C", repo.Path, "symbolic-ref", "--short", "-q", "HEAD"},
+		{"-C", repo.Path, "fetch", "origin", "main"},
+		{"-C", repo.Path, "rebase", "origin/main"},
+		{"-C", repo.Path, "submodule", "update", "--init", "--recursive"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("update invocations = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_DryRun(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	existing := New(t.TempDir(), "https://github.com/me/repo", "main")
+	existing.UpstreamURL = "https://github.com/org/repo"
+	missing := New(filepath.Join(t.TempDir(), "missing"), "https://github.com/test/repo", "main")
+	pinned := New(t.TempDir(), "https://github.com/test/repo", "main")
+	pinned.Ref = "v1.4.0"
+
+	tests := []struct {
+		name     string
+		repo     *Repository
+		wantPlan string
+	}{
+		{name: "missing clone", repo: missing, wantPlan: "clone https://github.com/test/repo (main) into " + missing.Path},
+		{name: "fork", repo: existing, wantPlan: "fetch origin/main and rebase onto it, then rebase onto upstream/main"},
+		{name: "pinned", repo: pinned, wantPlan: "fetch origin and check out v1.4.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Reset(t)
+			tt.repo.DryRun = true
+
+			if got := tt.repo.UpdatePlan(); got != tt.wantPlan {
+				t.Errorf("Repository.UpdatePlan() = %q, want %q", got, tt.wantPlan)
+			}
+			if err := tt.repo.Update(); err != nil {
+				t.Fatalf("Repository.Update() error = %v", err)
+			}
+			if got := mock.Invocations(t); len(got) != 0 {
+				t.Errorf("dry run invoked git: %v", got)
+			}
+			if _, err := os.Stat(missing.Path); !os.IsNotExist(err) {
+				t.Errorf("dry run created %s", missing.Path)
+			}
+		})
+	}
+}
+
+func TestRepository_Remote(t *testing.T) {
+	mock := mockgit.New(t)
+	defer mock.Cleanup()
+	mock.Configure(t, mockgit.Config{ExitCode: 0})
+
+	repo := New(filepath.Join(t.TempDir(), "repo"), "https://github.com/test/repo", "main")
+	repo.Remote = "upstream"
+
+	if err := repo.Clone(); err != nil {
+		t.Fatalf("Repository.Clone() error = %v", err)
+	}
+	want := [][]string{{"clone", "-b", "main", repo.URL, repo.Path, "--origin", "upstream"}}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("clone invocations = %v, want %v", got, want)
+	}
+
+	if err := os.MkdirAll(repo.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mock.Reset(t)
+
+	if err := repo.Update(); err != nil {
+		t.Fatalf("Repository.Update() error = %v", err)
+	}
+	want = [][]string{
+		{"-C", repo.Path, "symbolic-ref", "--short", "-q", "HEAD"},
+		{"-C", repo.Path, "fetch", "upstream", "main"},
+		{"-C", repo.Path, "rebase", "upstream/main"},
+	}
+	if got := mock.Invocations(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("update invocations = %v, want %v", got, want)
+	}
+	if plan, want := repo.UpdatePlan(), "fetch upstream/main and rebase onto it"; plan != want {
+		t.Errorf("Repository.UpdatePlan() = %q, want %q", plan, want)
+	}
+}
+
+func TestRepository_ValidateRemote(t *testing.T) {
+	tests := []struct {
+		name     string
+		remote   string
+		upstream string
+		wantErr  bool
+	}{
+		{name: "default", remote: ""},
+		{name: "custom", remote: "upstream"},
+		{name: "fork with custom remote", remote: "mine", upstream: "https://github.com/org/repo"},
+		{name: "clashes with fork upstream", remote: "upstream", upstream: "https://github.com/org/repo", wantErr: true},
+		{name: "contains slash", remote: "my/remote", wantErr: true},
+		{name: "looks like a flag", remote: "--mirror", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := New("/tmp/repo", "https://github.com/me/repo", "main")
+			repo.Remote = tt.remote
+			repo.UpstreamURL = tt.upstream
+			if err := repo.ValidateRemote(); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ValidateRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URL schemes a repository can be cloned with
+const (
+	SchemeAsIs  = "as-is"
+	SchemeHTTPS = "https"
+	SchemeSSH   = "ssh"
+)
+
+// rewritableHosts are the hosts whose HTTPS and SSH URLs map onto each other
+var rewritableHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// ValidateScheme checks that scheme is empty or one of the known URL schemes
+func ValidateScheme(scheme string) error {
+	switch scheme {
+	case "", SchemeAsIs, SchemeHTTPS, SchemeSSH:
+		return nil
+	}
+	return fmt.Errorf("unknown URL scheme %q (want %s, %s or %s)", scheme, SchemeHTTPS, SchemeSSH, SchemeAsIs)
+}
+
+// ConvertURL rewrites url to use scheme, turning
+// https://github.com/u/r.git into git@github.com:u/r.git or the reverse.
+// URLs on hosts other than github.com, gitlab.com and bitbucket.org, and
+// any URL when scheme is empty or as-is, are returned unchanged.
+func ConvertURL(url, scheme string) string {
+	for _, host := range rewritableHosts {
+		var repoPath string
+		if p, ok := strings.CutPrefix(url, "https://"+host+"/"); ok {
+			repoPath = p
+		} else if p, ok := strings.CutPrefix(url, "git@"+host+":"); ok {
+			repoPath = p
+		} else if p, ok := strings.CutPrefix(url, "ssh://git@"+host+"/"); ok {
+			repoPath = p
+		} else {
+			continue
+		}
+
+		switch scheme {
+		case SchemeHTTPS:
+			return "https://" + host + "/" + repoPath
+		case SchemeSSH:
+			return "git@" + host + ":" + repoPath
+		}
+		return url
+	}
+	return url
+}
+
+// SameRemote reports whether url and other name the same repository, however
+// each is written: https or ssh on a known host, with or without ".git" or a
+// trailing slash
+func SameRemote(url, other string) bool {
+	normalize := func(u string) string {
+		u = strings.TrimSuffix(ConvertURL(u, SchemeSSH), "/")
+		return strings.TrimSuffix(u, ".git")
+	}
+	return normalize(url) == normalize(other)
+}
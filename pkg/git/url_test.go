@@ -0,0 +1,53 @@
+package git
+
+import "testing"
+
+func TestConvertURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		scheme string
+		want   string
+	}{
+		{name: "https to ssh", url: "https://github.com/u/r.git", scheme: SchemeSSH, want: "git@github.com:u/r.git"},
+		{name: "ssh to https", url: "git@gitlab.com:group/sub/r.git", scheme: SchemeHTTPS, want: "https://gitlab.com/group/sub/r.git"},
+		{name: "ssh url to scp form", url: "ssh://git@bitbucket.org/u/r.git", scheme: SchemeSSH, want: "git@bitbucket.org:u/r.git"},
+		{name: "already ssh", url: "git@github.com:u/r.git", scheme: SchemeSSH, want: "git@github.com:u/r.git"},
+		{name: "as-is", url: "https://github.com/u/r.git", scheme: SchemeAsIs, want: "https://github.com/u/r.git"},
+		{name: "no scheme", url: "https://github.com/u/r.git", want: "https://github.com/u/r.git"},
+		{name: "unknown host", url: "https://git.example.com/u/r.git", scheme: SchemeSSH, want: "https://git.example.com/u/r.git"},
+		{name: "host prefix only", url: "https://github.com.evil.io/u/r.git", scheme: SchemeSSH, want: "https://github.com.evil.io/u/r.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertURL(tt.url, tt.scheme); got != tt.want {
+				t.Errorf("ConvertURL(%q, %q) = %q, want %q", tt.url, tt.scheme, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameRemote(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		other string
+		want  bool
+	}{
+		{name: "identical", url: "git@github.com:u/r.git", other: "git@github.com:u/r.git", want: true},
+		{name: "https and ssh", url: "https://github.com/u/r.git", other: "git@github.com:u/r.git", want: true},
+		{name: "without .git", url: "https://github.com/u/r", other: "git@github.com:u/r.git", want: true},
+		{name: "trailing slash", url: "https://github.com/u/r/", other: "https://github.com/u/r", want: true},
+		{name: "different repository", url: "git@github.com:u/r.git", other: "git@github.com:u/other.git", want: false},
+		{name: "different host", url: "https://gitlab.com/u/r.git", other: "https://github.com/u/r.git", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameRemote(tt.url, tt.other); got != tt.want {
+				t.Errorf("SameRemote(%q, %q) = %v, want %v", tt.url, tt.other, got, tt.want)
+			}
+		})
+	}
+}
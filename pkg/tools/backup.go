@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"dev-manager/pkg/config"
+)
+
+// backupTimeFormat is appended to BackupPath to name each snapshot
+const backupTimeFormat = "20060102-150405"
+
+// Backup is a timestamped snapshot of a tool's configuration
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// CreateBackup copies a tool's current configuration to a snapshot named
+// after its BackupPath and the current time, e.g. ~/.zshrc.bak.20240101-120000.
+// Configs that are symlinks are snapshotted by content.
+func CreateBackup(tool config.ToolConfig) (Backup, error) {
+	if tool.BackupPath == "" {
+		return Backup{}, fmt.Errorf("tool %s has no backupPath", tool.Name)
+	}
+
+	p, err := resolvePaths(tool)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	src, err := filepath.EvalSymlinks(p.config)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to read config for %s: %w", tool.Name, err)
+	}
+
+	now := time.Now()
+	backup := Backup{Path: p.backup + "." + now.Format(backupTimeFormat), Time: now}
+	if _, err := os.Lstat(backup.Path); err == nil {
+		return Backup{}, fmt.Errorf("backup %s already exists", backup.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backup.Path), 0755); err != nil {
+		return Backup{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := copyPath(src, backup.Path); err != nil {
+		os.RemoveAll(backup.Path)
+		return Backup{}, fmt.Errorf("failed to back up %s: %w", tool.Name, err)
+	}
+	return backup, nil
+}
+
+// ListBackups returns the snapshots of a tool's configuration, newest first
+func ListBackups(tool config.ToolConfig) ([]Backup, error) {
+	if tool.BackupPath == "" {
+		return nil, nil
+	}
+
+	p, err := resolvePaths(tool)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(p.backup + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(match, p.backup+".")
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: match, Time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// Restore replaces a tool's configuration with a copy of the given backup.
+// The copy is made next to the config first and only then swapped in, so a
+// failed restore leaves the current configuration untouched.
+func Restore(tool config.ToolConfig, backup Backup) error {
+	p, err := resolvePaths(tool)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(backup.Path); err != nil {
+		return fmt.Errorf("backup not found: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.config), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(p.config), "."+filepath.Base(p.config)+".restore-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	restored := filepath.Join(tmpDir, "restored")
+	if err := copyPath(backup.Path, restored); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", tool.Name, err)
+	}
+
+	// Move the current config aside rather than deleting it, so it can be
+	// put back if the swap fails; a directory can't be renamed over anyway
+	previous := filepath.Join(tmpDir, "previous")
+	hadConfig := true
+	if err := os.Rename(p.config, previous); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move current config aside: %w", err)
+		}
+		hadConfig = false
+	}
+	if err := os.Rename(restored, p.config); err != nil {
+		if hadConfig {
+			os.Rename(previous, p.config)
+		}
+		return fmt.Errorf("failed to restore %s: %w", tool.Name, err)
+	}
+	return nil
+}
+
+// copyPath copies a file or directory tree from src to dst, preserving
+// permissions. Symlinks inside directories are recreated as symlinks.
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+}
+
+// copyFile copies a single regular file
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/pkg/config"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // relative to ConfigPath; "" is ConfigPath itself
+	}{
+		{
+			name:  "single file",
+			files: map[string]string{"": "export EDITOR=nvim\n"},
+		},
+		{
+			name: "directory",
+			files: map[string]string{
+				"init.lua":            "require('plugins')\n",
+				"lua/plugins/git.lua": "return {}\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tool := config.ToolConfig{
+				Name:       "tool",
+				ConfigPath: filepath.Join(dir, "config"),
+				BackupPath: filepath.Join(dir, "backups", "config.bak"),
+			}
+			writeFiles(t, tool.ConfigPath, tt.files)
+
+			backup, err := CreateBackup(tool)
+			if err != nil {
+				t.Fatalf("CreateBackup() error = %v", err)
+			}
+
+			backups, err := ListBackups(tool)
+			if err != nil {
+				t.Fatalf("ListBackups() error = %v", err)
+			}
+			if len(backups) != 1 || backups[0].Path != backup.Path {
+				t.Fatalf("ListBackups() = %v, want [%s]", backups, backup.Path)
+			}
+
+			// Clobber the config, then restore it
+			os.RemoveAll(tool.ConfigPath)
+			writeFiles(t, tool.ConfigPath, map[string]string{"": "broken\n"})
+
+			if err := Restore(tool, backups[0]); err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			for rel, want := range tt.files {
+				data, err := os.ReadFile(filepath.Join(tool.ConfigPath, rel))
+				if err != nil || string(data) != want {
+					t.Errorf("restored %q = %q, %v; want %q", rel, data, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreFailureKeepsConfig(t *testing.T) {
+	dir := t.TempDir()
+	tool := config.ToolConfig{
+		Name:       "tool",
+		ConfigPath: filepath.Join(dir, "config"),
+		BackupPath: filepath.Join(dir, "config.bak"),
+	}
+	writeFiles(t, tool.ConfigPath, map[string]string{"init.lua": "current\n"})
+
+	// A socket can't be copied, so restoring this backup fails midway
+	backup := Backup{Path: filepath.Join(dir, "config.bak.20240101-120000")}
+	writeFiles(t, backup.Path, map[string]string{"a.lua": "backup\n"})
+	l, err := net.Listen("unix", filepath.Join(backup.Path, "sock"))
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer l.Close()
+
+	if err := Restore(tool, backup); err == nil {
+		t.Fatal("Restore() error = nil, want a copy failure")
+	}
+	data, err := os.ReadFile(filepath.Join(tool.ConfigPath, "init.lua"))
+	if err != nil || string(data) != "current\n" {
+		t.Errorf("config after failed restore = %q, %v; want it untouched", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("failed restore left files behind: %v", entries)
+	}
+}
+
+// writeFiles creates files under root; the "" key writes root itself
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dev-manager/internal/textdiff"
+	"dev-manager/pkg/config"
+)
+
+// DiffResult describes how a tool's deployed config differs from its source
+type DiffResult struct {
+	// Linked is set when ConfigPath is a symlink to Source, so they can't drift
+	Linked bool
+	// Missing is set when nothing is deployed at ConfigPath
+	Missing bool
+	// Diff is a unified diff from Source to ConfigPath, so added lines are
+	// local edits; it is empty when the two are identical
+	Diff string
+}
+
+// Diff compares the config deployed at a tool's ConfigPath, following
+// symlinks, with its managed Source. Directories are compared file by file.
+func Diff(tool config.ToolConfig) (DiffResult, error) {
+	if tool.Source == "" {
+		return DiffResult{}, fmt.Errorf("tool %s has no source configured", tool.Name)
+	}
+
+	p, err := resolvePaths(tool)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	source, err := filepath.EvalSymlinks(p.source)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("managed config for %s not found: %w", tool.Name, err)
+	}
+	if isLinkTo(p.config, p.source) {
+		return DiffResult{Linked: true}, nil
+	}
+	deployed, err := filepath.EvalSymlinks(p.config)
+	if os.IsNotExist(err) {
+		return DiffResult{Missing: true}, nil
+	}
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	sourceFiles, err := readTree(source)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("failed to read %s: %w", p.source, err)
+	}
+	deployedFiles, err := readTree(deployed)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("failed to read %s: %w", p.config, err)
+	}
+
+	names := make([]string, 0, len(sourceFiles)+len(deployedFiles))
+	for name := range sourceFiles {
+		names = append(names, name)
+	}
+	for name := range deployedFiles {
+		if _, ok := sourceFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var diff strings.Builder
+	for _, name := range names {
+		oldName, newName := filepath.Join(p.source, name), filepath.Join(p.config, name)
+		old, inSource := sourceFiles[name]
+		new, inDeployed := deployedFiles[name]
+		if !inSource {
+			oldName = "/dev/null"
+		}
+		if !inDeployed {
+			newName = "/dev/null"
+		}
+
+		if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(new, 0) >= 0 {
+			if !bytes.Equal(old, new) {
+				fmt.Fprintf(&diff, "Binary files %s and %s differ\n", oldName, newName)
+			}
+			continue
+		}
+		diff.WriteString(textdiff.Unified(oldName, newName, string(old), string(new), 3))
+	}
+	return DiffResult{Diff: diff.String()}, nil
+}
+
+// readTree returns the contents of the files under root keyed by their path
+// relative to it, or of root itself under "" when it is a file. Symlinks
+// inside root are compared by their target, and .git directories are skipped.
+func readTree(root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = ""
+		}
+
+		switch {
+		case d.IsDir():
+			if d.Name() == ".git" && rel != "" {
+				return filepath.SkipDir
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = []byte("symlink to " + target + "\n")
+		default:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = data
+		}
+		return nil
+	})
+	return files, err
+}
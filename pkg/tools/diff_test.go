@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dev-manager/pkg/config"
+)
+
+func TestDiff_File(t *testing.T) {
+	dir := t.TempDir()
+	tool := config.ToolConfig{
+		Name:       "tmux",
+		Source:     filepath.Join(dir, "dotfiles", "tmux.conf"),
+		ConfigPath: filepath.Join(dir, "home", ".tmux.conf"),
+	}
+	writeFiles(t, dir, map[string]string{"dotfiles/tmux.conf": "set -g mouse on\n"})
+
+	result, err := Diff(tool)
+	if err != nil || !result.Missing {
+		t.Fatalf("Diff() before deploying = %+v, %v, want Missing", result, err)
+	}
+
+	writeFiles(t, dir, map[string]string{"home/.tmux.conf": "set -g mouse on\n"})
+	if result, err = Diff(tool); err != nil || result.Diff != "" {
+		t.Errorf("Diff() of identical copies = %+v, %v, want no diff", result, err)
+	}
+
+	writeFiles(t, dir, map[string]string{"home/.tmux.conf": "set -g mouse on\nset -g base-index 1\n"})
+	if result, err = Diff(tool); err != nil || !strings.Contains(result.Diff, "+set -g base-index 1") {
+		t.Errorf("Diff() after a local edit = %+v, %v, want the added line", result, err)
+	}
+
+	if _, err := Sync(config.ToolConfig{Name: "tmux", Source: tool.Source, ConfigPath: tool.ConfigPath, BackupPath: tool.ConfigPath + ".bak"}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = Diff(tool); err != nil || !result.Linked {
+		t.Errorf("Diff() of a linked config = %+v, %v, want Linked", result, err)
+	}
+
+	if _, err := Diff(config.ToolConfig{Name: "zsh", ConfigPath: tool.ConfigPath}); err == nil {
+		t.Error("Diff() without a source succeeded, want error")
+	}
+}
+
+func TestDiff_Directory(t *testing.T) {
+	dir := t.TempDir()
+	tool := config.ToolConfig{
+		Name:       "nvim",
+		Source:     filepath.Join(dir, "dotfiles", "nvim"),
+		ConfigPath: filepath.Join(dir, "home", ".config", "nvim"),
+	}
+	writeFiles(t, dir, map[string]string{
+		"dotfiles/nvim/init.lua":            "require('plugins')\n",
+		"dotfiles/nvim/lua/plugins.lua":     "return {}\n",
+		"dotfiles/nvim/.git/HEAD":           "ref: refs/heads/main\n",
+		"home/.config/nvim/init.lua":        "require('plugins')\n",
+		"home/.config/nvim/lua/plugins.lua": "return { 'tpope/vim-fugitive' }\n",
+		"home/.config/nvim/lua/local.lua":   "vim.o.number = true\n",
+	})
+
+	result, err := Diff(tool)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	for _, want := range []string{
+		"-return {}",
+		"+return { 'tpope/vim-fugitive' }",
+		"--- /dev/null",
+		"+vim.o.number = true",
+	} {
+		if !strings.Contains(result.Diff, want) {
+			t.Errorf("Diff() missing %q in:\n%s", want, result.Diff)
+		}
+	}
+	if strings.Contains(result.Diff, "init.lua") || strings.Contains(result.Diff, ".git") {
+		t.Errorf("Diff() reports unchanged or .git files:\n%s", result.Diff)
+	}
+}
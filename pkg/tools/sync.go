@@ -0,0 +1,111 @@
+// Package tools manages the configuration files of development tools
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dev-manager/pkg/config"
+)
+
+// SyncResult describes what Sync did for a tool
+type SyncResult struct {
+	// Changed is false when the config already linked to its source
+	Changed bool
+	// BackupPath is where an existing config was moved, if any
+	BackupPath string
+}
+
+// paths holds the expanded, absolute paths of a tool's configuration
+type paths struct {
+	source, config, backup string
+}
+
+// resolvePaths expands ~ and makes the paths of a tool absolute
+func resolvePaths(tool config.ToolConfig) (paths, error) {
+	var p paths
+	for _, f := range []struct {
+		in  string
+		out *string
+	}{
+		{tool.Source, &p.source},
+		{tool.ConfigPath, &p.config},
+		{tool.BackupPath, &p.backup},
+	} {
+		if f.in == "" {
+			continue
+		}
+		expanded, err := config.ExpandPath(f.in)
+		if err != nil {
+			return paths{}, err
+		}
+		if *f.out, err = filepath.Abs(expanded); err != nil {
+			return paths{}, err
+		}
+	}
+	return p, nil
+}
+
+// isLinkTo reports whether path is a symlink pointing at target
+func isLinkTo(path, target string) bool {
+	dest, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(path), dest)
+	}
+	return filepath.Clean(dest) == target
+}
+
+// Sync links a tool's ConfigPath to its managed Source. An existing config
+// is moved to BackupPath first; a config that already links to Source is
+// left alone, so Sync can be run repeatedly.
+func Sync(tool config.ToolConfig) (SyncResult, error) {
+	if tool.Source == "" {
+		return SyncResult{}, fmt.Errorf("tool %s has no source configured", tool.Name)
+	}
+
+	p, err := resolvePaths(tool)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	if _, err := os.Stat(p.source); err != nil {
+		return SyncResult{}, fmt.Errorf("managed config for %s not found: %w", tool.Name, err)
+	}
+
+	if isLinkTo(p.config, p.source) {
+		return SyncResult{}, nil
+	}
+
+	var result SyncResult
+	if _, err := os.Lstat(p.config); err == nil {
+		if p.backup == "" {
+			return SyncResult{}, fmt.Errorf("refusing to replace %s: tool %s has no backupPath", p.config, tool.Name)
+		}
+		if _, err := os.Lstat(p.backup); err == nil {
+			return SyncResult{}, fmt.Errorf("refusing to replace %s: backup %s already exists", p.config, p.backup)
+		}
+		if err := os.MkdirAll(filepath.Dir(p.backup), 0755); err != nil {
+			return SyncResult{}, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		if err := os.Rename(p.config, p.backup); err != nil {
+			return SyncResult{}, fmt.Errorf("failed to back up %s: %w", p.config, err)
+		}
+		result.BackupPath = p.backup
+	} else if !os.IsNotExist(err) {
+		return SyncResult{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.config), 0755); err != nil {
+		return SyncResult{}, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.Symlink(p.source, p.config); err != nil {
+		return SyncResult{}, fmt.Errorf("failed to link %s: %w", p.config, err)
+	}
+
+	result.Changed = true
+	return result, nil
+}
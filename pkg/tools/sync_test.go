@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dev-manager/pkg/config"
+)
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string // content of a config already in place, if any
+		wantBackup  bool
+		wantErr     bool
+		noBackupCfg bool
+	}{
+		{
+			name: "nothing in place",
+		},
+		{
+			name:       "existing config is backed up",
+			existing:   "set -g mouse off\n",
+			wantBackup: true,
+		},
+		{
+			name:        "existing config without backup path",
+			existing:    "set -g mouse off\n",
+			noBackupCfg: true,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tool := config.ToolConfig{
+				Name:       "tmux",
+				Source:     filepath.Join(dir, "dotfiles", "tmux.conf"),
+				ConfigPath: filepath.Join(dir, "home", ".tmux.conf"),
+				BackupPath: filepath.Join(dir, "home", ".tmux.conf.bak"),
+			}
+			if tt.noBackupCfg {
+				tool.BackupPath = ""
+			}
+
+			os.MkdirAll(filepath.Dir(tool.Source), 0755)
+			if err := os.WriteFile(tool.Source, []byte("set -g mouse on\n"), 0644); err != nil {
+				t.Fatalf("failed to write source: %v", err)
+			}
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(tool.ConfigPath), 0755)
+				if err := os.WriteFile(tool.ConfigPath, []byte(tt.existing), 0644); err != nil {
+					t.Fatalf("failed to write existing config: %v", err)
+				}
+			}
+
+			result, err := Sync(tool)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !result.Changed {
+				t.Error("first Sync() reported no change")
+			}
+			if !isLinkTo(tool.ConfigPath, tool.Source) {
+				t.Errorf("%s does not link to %s", tool.ConfigPath, tool.Source)
+			}
+			if tt.wantBackup {
+				data, err := os.ReadFile(tool.BackupPath)
+				if err != nil || string(data) != tt.existing {
+					t.Errorf("backup = %q, %v; want %q", data, err, tt.existing)
+				}
+			}
+
+			again, err := Sync(tool)
+			if err != nil {
+				t.Fatalf("second Sync() error = %v", err)
+			}
+			if again.Changed || again.BackupPath != "" {
+				t.Errorf("second Sync() = %+v, want no change", again)
+			}
+		})
+	}
+}